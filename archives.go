@@ -0,0 +1,337 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"notes-service/exports"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"notes-service/validators"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Maximum size of the chunks a group archive is streamed in.
+const archiveChunkSize = 64 << 10
+
+const archiveManifestVersion = 1
+
+// Limits of the images fetched for a group archive, the images beyond are
+// replaced by their url like the ones which can't be fetched. They are only
+// variables so that the tests can lower them.
+var (
+	maxArchiveImages     = 200
+	maxArchiveImagesSize = 100 << 20
+)
+
+var protobufFormatToExtension = map[notesv1.NoteExportFormat]string{
+	notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_MARKDOWN: ".md",
+	notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_PDF:      ".pdf",
+	notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_HTML:     ".html",
+	notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_JSON:     ".json",
+}
+
+var imageTypeToExtension = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/bmp":  ".bmp",
+}
+
+// archiveManifest describes the notes of a group archive, it is stored as
+// manifest.json at its root.
+type archiveManifest struct {
+	Version    int                    `json:"version"`
+	GroupID    string                 `json:"groupId"`
+	GroupName  string                 `json:"groupName"`
+	FolderID   string                 `json:"folderId,omitempty"`
+	Format     string                 `json:"format"`
+	ExportedAt time.Time              `json:"exportedAt"`
+	Notes      []*archiveManifestNote `json:"notes"`
+}
+
+type archiveManifestNote struct {
+	ID              string                    `json:"id"`
+	Title           string                    `json:"title"`
+	Path            string                    `json:"path"`
+	FolderID        string                    `json:"folderId,omitempty"`
+	AuthorAccountID string                    `json:"authorAccountId"`
+	Lang            string                    `json:"lang"`
+	CreatedAt       time.Time                 `json:"createdAt"`
+	ModifiedAt      *time.Time                `json:"modifiedAt,omitempty"`
+	Keywords        []*models.Keyword         `json:"keywords"`
+	Images          []*archiveManifestImage   `json:"images"`
+	Comments        []*archiveManifestComment `json:"comments"`
+}
+
+type archiveManifestImage struct {
+	URL  string `json:"url"`
+	Path string `json:"path"`
+}
+
+type archiveManifestComment struct {
+	ID              string `json:"id"`
+	BlockID         string `json:"blockId"`
+	AuthorAccountID string `json:"authorAccountId"`
+	Content         string `json:"content"`
+}
+
+func (srv *notesAPI) ExportGroup(req *notesv1.ExportGroupRequest, stream notesv1.NotesAPI_ExportGroupServer) error {
+	ctx := stream.Context()
+
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validators.ValidateExportGroupRequest(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if _, ok := protobufFormatToExtension[req.ExportFormat]; !ok {
+		return status.Errorf(codes.InvalidArgument, "format not recognized : %s", req.ExportFormat.String())
+	}
+
+	// Check user is part of the group.
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return statusFromModelError(err)
+	}
+
+	notes, err := srv.notes.ListAllNotesInternal(ctx, &models.ManyNotesFilter{GroupID: req.GroupId, FolderID: req.FolderId})
+	if err != nil {
+		return statusFromModelError(err)
+	}
+
+	chunks := bufio.NewWriterSize(&archiveChunkWriter{stream: stream}, archiveChunkSize)
+	archive := zip.NewWriter(chunks)
+
+	err = srv.writeGroupArchive(ctx, archive, group, notes, req)
+	if err == nil {
+		err = archive.Close()
+	}
+	if err == nil {
+		err = chunks.Flush()
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled {
+			return status.Error(codes.Canceled, "export canceled")
+		}
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		loggerFromContext(ctx, srv.logger).Error("failed to write group archive", zap.Error(err))
+		return status.Errorf(codes.Internal, "failed to export group : %s", req.GroupId)
+	}
+
+	return nil
+}
+
+// writeGroupArchive writes the notes in the archive, each in the directory of
+// its folder, followed by their images and the manifest.
+func (srv *notesAPI) writeGroupArchive(ctx context.Context, archive *zip.Writer, group *models.Group, notes []*models.Note, req *notesv1.ExportGroupRequest) error {
+	manifest := &archiveManifest{
+		Version:    archiveManifestVersion,
+		GroupID:    group.ID,
+		GroupName:  group.Name,
+		FolderID:   req.FolderId,
+		Format:     req.ExportFormat.String(),
+		ExportedAt: time.Now(),
+		Notes:      []*archiveManifestNote{},
+	}
+	paths := map[string]bool{}
+	// Paths of the images already in the archive, by URL.
+	imagePaths := map[string]string{}
+	fetchedImages, fetchedImagesSize := 0, 0
+
+	for _, note := range notes {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		directory := ""
+		if note.FolderID != "" {
+			directory = archiveFileName(note.FolderID, "folder") + "/"
+		}
+		path := directory + archiveFileName(note.Title, "note") + protobufFormatToExtension[req.ExportFormat]
+		if paths[path] {
+			path = directory + archiveFileName(note.Title, "note") + " (" + note.ID + ")" + protobufFormatToExtension[req.ExportFormat]
+		}
+		paths[path] = true
+
+		manifestNote := &archiveManifestNote{
+			ID:              note.ID,
+			Title:           note.Title,
+			Path:            path,
+			FolderID:        note.FolderID,
+			AuthorAccountID: note.AuthorAccountID,
+			Lang:            note.Lang,
+			CreatedAt:       note.CreatedAt,
+			ModifiedAt:      note.ModifiedAt,
+			Keywords:        note.Keywords,
+			Images:          []*archiveManifestImage{},
+			Comments:        []*archiveManifestComment{},
+		}
+		if manifestNote.Keywords == nil {
+			manifestNote.Keywords = []*models.Keyword{}
+		}
+		manifest.Notes = append(manifest.Notes, manifestNote)
+
+		// Images are fetched once per note, and stored once per archive.
+		images := map[string][]byte{}
+		imageLinks := map[string]string{}
+		if note.Blocks != nil {
+			for _, block := range *note.Blocks {
+				if block.Thread != nil && req.IncludeComments {
+					for _, comment := range *block.Thread {
+						manifestNote.Comments = append(manifestNote.Comments, &archiveManifestComment{
+							ID:              comment.ID,
+							BlockID:         block.ID,
+							AuthorAccountID: comment.AuthorAccountID,
+							Content:         comment.Content,
+						})
+					}
+				}
+
+				if block.Image == nil || block.Image.Url == "" {
+					continue
+				}
+				url := block.Image.Url
+				if _, ok := images[url]; ok {
+					continue
+				}
+
+				if fetchedImages >= maxArchiveImages || fetchedImagesSize >= maxArchiveImagesSize {
+					images[url] = nil
+					continue
+				}
+				fetchedImages++
+
+				data, err := exports.FetchImage(ctx, url)
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return ctxErr
+				}
+				if err != nil {
+					loggerFromContext(ctx, srv.logger).Warn("could not fetch image", zap.String("note_id", note.ID), zap.Error(err))
+					images[url] = nil
+					continue
+				}
+				fetchedImagesSize += len(data)
+				if fetchedImagesSize > maxArchiveImagesSize {
+					images[url] = nil
+					continue
+				}
+				images[url] = data
+
+				if imagePaths[url] == "" {
+					imagePaths[url] = archiveImagePath(url, data)
+					err = writeArchiveFile(archive, imagePaths[url], data)
+					if err != nil {
+						return err
+					}
+				}
+				imageLinks[url] = strings.Repeat("../", strings.Count(path, "/")) + imagePaths[url]
+				manifestNote.Images = append(manifestNote.Images, &archiveManifestImage{URL: url, Path: imagePaths[url]})
+			}
+		}
+
+		fileBytes, err := srv.exportNote(ctx, note, group, &noteExportOptions{
+			format:          req.ExportFormat,
+			includeComments: req.IncludeComments,
+			images: func(ctx context.Context, url string) ([]byte, error) {
+				if images[url] == nil {
+					return nil, errors.New("image could not be fetched")
+				}
+				return images[url], nil
+			},
+			imageLinks: imageLinks,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = writeArchiveFile(archive, path, fileBytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeArchiveFile(archive, "manifest.json", manifestBytes)
+}
+
+func writeArchiveFile(archive *zip.Writer, path string, data []byte) error {
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     path,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	return err
+}
+
+// archiveImagePath names an image after its URL, so that an image used by
+// several notes is stored once.
+func archiveImagePath(url string, data []byte) string {
+	hash := sha256.Sum256([]byte(url))
+	return "images/" + hex.EncodeToString(hash[:8]) + imageTypeToExtension[http.DetectContentType(data)]
+}
+
+var archiveUnsafeCharacters = regexp.MustCompile(`[\x00-\x1f/\\:*?"<>|]+`)
+
+// archiveFileName returns a name usable as a file name on every system.
+func archiveFileName(name string, fallback string) string {
+	name = strings.Trim(archiveUnsafeCharacters.ReplaceAllString(name, "-"), " .")
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// archiveChunkWriter sends what is written to it as chunks of the archive.
+type archiveChunkWriter struct {
+	stream notesv1.NotesAPI_ExportGroupServer
+}
+
+func (w *archiveChunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		size := len(p) - written
+		if size > archiveChunkSize {
+			size = archiveChunkSize
+		}
+
+		// The stream may keep the message after Send returns.
+		chunk := make([]byte, size)
+		copy(chunk, p[written:written+size])
+		err := w.stream.Send(&notesv1.ExportGroupResponse{Chunk: chunk})
+		if err != nil {
+			return written, err
+		}
+		written += size
+	}
+	return written, nil
+}
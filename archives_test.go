@@ -0,0 +1,225 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type exportGroupServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks [][]byte
+	// (Optional) Called once the first chunk is sent.
+	onFirstChunk func()
+}
+
+func (s *exportGroupServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *exportGroupServerStream) Send(res *notesv1.ExportGroupResponse) error {
+	// Like grpc, nothing can be sent once the stream is done.
+	err := s.ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	s.chunks = append(s.chunks, res.Chunk)
+	if len(s.chunks) == 1 && s.onFirstChunk != nil {
+		s.onFirstChunk()
+	}
+	return nil
+}
+
+// archive opens the archive made of the chunks sent on the stream.
+func (s *exportGroupServerStream) archive(t *testing.T) map[string][]byte {
+	data := bytes.Join(s.chunks, nil)
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		content, err := file.Open()
+		require.NoError(t, err)
+		files[file.Name], err = io.ReadAll(content)
+		require.NoError(t, err)
+		content.Close()
+	}
+	return files
+}
+
+func TestArchivesSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	edouard := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, edouard)
+
+	png, err := os.ReadFile("exports/testdata/chat.png")
+	require.NoError(t, err)
+	jpg, err := os.ReadFile("exports/testdata/chat.jpg")
+	require.NoError(t, err)
+	imageURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+
+	imageBlock := func(data []byte, mediaType string) *notesv1.Block {
+		url := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+		return &notesv1.Block{Type: notesv1.Block_TYPE_IMAGE, Data: &notesv1.Block_Image_{Image: &notesv1.Block_Image{Url: url}}}
+	}
+
+	exportImages := func(t *testing.T, group *testGroup) (*archiveManifest, map[string][]byte) {
+		stream := &exportGroupServerStream{ctx: edouard.Context}
+		err := tu.notes.ExportGroup(&notesv1.ExportGroupRequest{
+			GroupId:      group.ID,
+			ExportFormat: notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_MARKDOWN,
+		}, stream)
+		require.NoError(t, err)
+
+		files := stream.archive(t)
+		manifest := &archiveManifest{}
+		require.NoError(t, json.Unmarshal(files["manifest.json"], manifest))
+		require.Len(t, manifest.Notes, 1)
+		return manifest, files
+	}
+
+	cats := newTestNote(t, tu, group, edouard, []*notesv1.Block{
+		{Type: notesv1.Block_TYPE_HEADING_1, Data: &notesv1.Block_Heading{Heading: "Les chats"}},
+		{Type: notesv1.Block_TYPE_IMAGE, Data: &notesv1.Block_Image_{Image: &notesv1.Block_Image{Url: imageURL, Caption: "Un chat"}}},
+	})
+	_, err = tu.notes.ImportNote(edouard.Context, &notesv1.ImportNoteRequest{
+		GroupId:      group.ID,
+		FolderId:     "animaux",
+		Title:        "Les chiens",
+		Lang:         "fr",
+		ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_TEXT,
+		File:         []byte("Les chiens aboient."),
+	})
+	require.NoError(t, err)
+
+	t.Run("member-can-export-group-as-markdown", func(t *testing.T) {
+		stream := &exportGroupServerStream{ctx: edouard.Context}
+		err := tu.notes.ExportGroup(&notesv1.ExportGroupRequest{
+			GroupId:      group.ID,
+			ExportFormat: notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_MARKDOWN,
+		}, stream)
+		require.NoError(t, err)
+
+		files := stream.archive(t)
+		require.Len(t, files, 4)
+		require.Equal(t, "Les chiens aboient.\n", string(files["animaux/Les chiens.md"]), "notes should be organised by folder")
+
+		manifest := &archiveManifest{}
+		require.NoError(t, json.Unmarshal(files["manifest.json"], manifest))
+		require.Equal(t, group.ID, manifest.GroupID)
+		require.Len(t, manifest.Notes, 2)
+
+		var catsManifest *archiveManifestNote
+		for _, note := range manifest.Notes {
+			if note.ID == cats.ID {
+				catsManifest = note
+			}
+		}
+		require.NotNil(t, catsManifest)
+		require.Len(t, catsManifest.Images, 1)
+		require.Equal(t, png, files[catsManifest.Images[0].Path], "images should be copied in the archive")
+		require.True(t, strings.HasSuffix(catsManifest.Images[0].Path, ".png"))
+		require.Contains(t, string(files[catsManifest.Path]), "](<"+catsManifest.Images[0].Path+">)", "image links should point to the copies")
+	})
+
+	t.Run("member-can-export-folder-as-json", func(t *testing.T) {
+		stream := &exportGroupServerStream{ctx: edouard.Context}
+		err := tu.notes.ExportGroup(&notesv1.ExportGroupRequest{
+			GroupId:      group.ID,
+			FolderId:     "animaux",
+			ExportFormat: notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_JSON,
+		}, stream)
+		require.NoError(t, err)
+
+		files := stream.archive(t)
+		require.Len(t, files, 2)
+		require.Contains(t, files, "animaux/Les chiens.json")
+	})
+
+	t.Run("archive-images-are-limited-in-number", func(t *testing.T) {
+		defer func(limit int) { maxArchiveImages = limit }(maxArchiveImages)
+		maxArchiveImages = 1
+
+		images := newTestGroup(t, tu, edouard)
+		newTestNote(t, tu, images, edouard, []*notesv1.Block{imageBlock(png, "image/png"), imageBlock(jpg, "image/jpeg")})
+
+		manifest, files := exportImages(t, images)
+		require.Len(t, manifest.Notes[0].Images, 1)
+		require.Equal(t, png, files[manifest.Notes[0].Images[0].Path])
+		require.Len(t, files, 3, "only the first image should be copied in the archive")
+	})
+
+	t.Run("archive-images-are-limited-in-size", func(t *testing.T) {
+		defer func(limit int) { maxArchiveImagesSize = limit }(maxArchiveImagesSize)
+		maxArchiveImagesSize = len(png) + len(jpg) - 1
+
+		images := newTestGroup(t, tu, edouard)
+		newTestNote(t, tu, images, edouard, []*notesv1.Block{imageBlock(png, "image/png"), imageBlock(jpg, "image/jpeg")})
+
+		manifest, files := exportImages(t, images)
+		require.Len(t, manifest.Notes[0].Images, 1)
+		require.Equal(t, png, files[manifest.Notes[0].Images[0].Path])
+		require.Len(t, files, 3, "the image over the size limit should not be copied in the archive")
+	})
+
+	t.Run("export-stops-when-request-is-canceled", func(t *testing.T) {
+		// Noise doesn't compress, each image fills more than a chunk.
+		random := rand.New(rand.NewSource(1))
+		noise := func() []byte {
+			data := make([]byte, 2*archiveChunkSize)
+			random.Read(data)
+			return data
+		}
+		large := newTestGroup(t, tu, edouard)
+		newTestNote(t, tu, large, edouard, []*notesv1.Block{imageBlock(noise(), "image/png"), imageBlock(noise(), "image/png")})
+		newTestNote(t, tu, large, edouard, []*notesv1.Block{imageBlock(noise(), "image/png")})
+
+		ctx, cancel := context.WithCancel(edouard.Context)
+		defer cancel()
+		stream := &exportGroupServerStream{ctx: ctx, onFirstChunk: cancel}
+		err := tu.notes.ExportGroup(&notesv1.ExportGroupRequest{
+			GroupId:      large.ID,
+			ExportFormat: notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_MARKDOWN,
+		}, stream)
+		requireErrorHasGRPCCode(t, codes.Canceled, err)
+		require.Len(t, stream.chunks, 1, "nothing should be sent once the request is canceled")
+	})
+
+	t.Run("cannot-export-group-without-format", func(t *testing.T) {
+		stream := &exportGroupServerStream{ctx: edouard.Context}
+		err := tu.notes.ExportGroup(&notesv1.ExportGroupRequest{GroupId: group.ID}, stream)
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+		require.Empty(t, stream.chunks)
+	})
+
+	t.Run("stranger-cannot-export-group", func(t *testing.T) {
+		stream := &exportGroupServerStream{ctx: stranger.Context}
+		err := tu.notes.ExportGroup(&notesv1.ExportGroupRequest{
+			GroupId:      group.ID,
+			ExportFormat: notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_HTML,
+		}, stream)
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Empty(t, stream.chunks)
+	})
+}
+
+func Test_archiveFileName(t *testing.T) {
+	require.Equal(t, "Cours - TD n°1", archiveFileName("Cours / TD n°1", "note"))
+	require.Equal(t, "note", archiveFileName(" ... ", "note"))
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 
+	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
@@ -15,9 +16,58 @@ func ForwardAuthMetadatathUnaryInterceptor(ctx context.Context, req interface{},
 	return handler(IncomingToOutgoingContext(ctx), req)
 }
 
+// ForwardAuthMetadataStreamInterceptor is the streaming equivalent of
+// ForwardAuthMetadatathUnaryInterceptor, the stream given to the handler
+// returns the outgoing context.
+func ForwardAuthMetadataStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, NewContextServerStream(stream, IncomingToOutgoingContext(stream.Context())))
+}
+
+// ContextServerStream overrides the context of the stream it wraps, it lets
+// the stream interceptors hand a derived context to the handler.
+type ContextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func NewContextServerStream(stream grpc.ServerStream, ctx context.Context) *ContextServerStream {
+	return &ContextServerStream{ServerStream: stream, ctx: ctx}
+}
+
+func (s *ContextServerStream) Context() context.Context {
+	return s.ctx
+}
+
 // IncomingToOutgoingContext creates a new outgoing context with the metadata
-// stored inside the incoming context.
+// stored inside the incoming context. The trace context of the caller is
+// replaced by the one of the current span so that calls to other services
+// appear as its children.
 func IncomingToOutgoingContext(parent context.Context) context.Context {
 	md, _ := metadata.FromIncomingContext(parent)
+	md = md.Copy()
+	otel.GetTextMapPropagator().Inject(parent, metadataCarrier(md))
 	return metadata.NewOutgoingContext(parent, md)
 }
+
+// metadataCarrier lets the otel propagators read and write grpc metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -6,6 +6,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -21,3 +25,57 @@ func TestIncomingToOutgoingContext(t *testing.T) {
 	require.Equal(t, len(md), 1)
 	require.Equal(t, md.Get(auth.AuthorizationHeaderKey)[0], "token")
 }
+
+func TestIncomingToOutgoingContextPropagatesTraceContext(t *testing.T) {
+	// Given
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	inCtx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs(
+		auth.AuthorizationHeaderKey, "token",
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-1111111111111111-01",
+	))
+	inCtx = trace.ContextWithSpanContext(inCtx, spanContext)
+
+	// When
+	outCtx := auth.IncomingToOutgoingContext(inCtx)
+
+	// Then
+	md, _ := metadata.FromOutgoingContext(outCtx)
+	require.Equal(t, md.Get(auth.AuthorizationHeaderKey)[0], "token")
+	require.Equal(t, md.Get("traceparent"), []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"})
+
+	inMd, _ := metadata.FromIncomingContext(inCtx)
+	require.Equal(t, inMd.Get("traceparent"), []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-1111111111111111-01"})
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestForwardAuthMetadataStreamInterceptor(t *testing.T) {
+	// Given
+	inCtx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs(auth.AuthorizationHeaderKey, "token"))
+	stream := &fakeServerStream{ctx: inCtx}
+
+	// When
+	var md metadata.MD
+	err := auth.ForwardAuthMetadataStreamInterceptor(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		md, _ = metadata.FromOutgoingContext(stream.Context())
+		return nil
+	})
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, md.Get(auth.AuthorizationHeaderKey)[0], "token")
+}
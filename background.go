@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"notes-service/metrics"
+	"sync"
+	"time"
+
+	background "github.com/noted-eip/noted/background-service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Pending processes due within this delay are run when the service stops
+// instead of being dropped, e.g. the keywords of a note modified right before
+// the shutdown.
+const maxFlushDelay = time.Minute
+
+var (
+	backgroundPendingProcesses = promauto.With(metrics.Registry).NewGauge(prometheus.GaugeOpts{
+		Name: "notes_background_pending_processes",
+		Help: "Background processes waiting for their callback to be called.",
+	})
+	backgroundProcessRuns = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Name: "notes_background_process_runs_total",
+		Help: "Callbacks of background processes called.",
+	})
+	backgroundProcessFailures = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Name: "notes_background_process_failures_total",
+		Help: "Callbacks of background processes which returned an error.",
+	})
+)
+
+// stoppableBackgroundService sits in front of a background.Service and keeps
+// track of the pending processes so that they can be flushed or dropped when
+// the server shuts down. Dropped processes such as the quiz expirations are
+// scheduled again at startup.
+type stoppableBackgroundService struct {
+	service background.Service
+	logger  *zap.Logger
+
+	mu      sync.Mutex
+	pending map[*background.Process]*pendingProcess
+	running sync.WaitGroup
+	stopped bool
+}
+
+type pendingProcess struct {
+	identifier  interface{}
+	callBackFct func() error
+	repeat      bool
+	dueAt       time.Time
+}
+
+func newStoppableBackgroundService(service background.Service, logger *zap.Logger) *stoppableBackgroundService {
+	return &stoppableBackgroundService{
+		service: service,
+		logger:  logger.Named("background"),
+		pending: make(map[*background.Process]*pendingProcess),
+	}
+}
+
+func (s *stoppableBackgroundService) AddProcess(process *background.Process) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return errors.New("background service is stopped")
+	}
+	if process.CancelProcessOnSameIdentifier {
+		s.forget(process.Identifier)
+	}
+
+	tracked := *process
+	pending := &pendingProcess{
+		identifier:  process.Identifier,
+		callBackFct: process.CallBackFct,
+		repeat:      process.RepeatProcess,
+		dueAt:       time.Now().Add(time.Duration(process.SecondsToDebounce) * time.Second),
+	}
+	tracked.CallBackFct = func() error {
+		s.mu.Lock()
+		if s.stopped {
+			// The process was flushed or dropped by Stop.
+			s.mu.Unlock()
+			return nil
+		}
+		if pending.repeat {
+			pending.dueAt = time.Now().Add(time.Duration(tracked.SecondsToDebounce) * time.Second)
+		} else {
+			delete(s.pending, &tracked)
+		}
+		backgroundPendingProcesses.Set(float64(len(s.pending)))
+		s.running.Add(1)
+		s.mu.Unlock()
+
+		defer s.running.Done()
+		return runBackgroundProcess(pending.callBackFct)
+	}
+	s.pending[&tracked] = pending
+	backgroundPendingProcesses.Set(float64(len(s.pending)))
+	s.mu.Unlock()
+
+	return s.service.AddProcess(&tracked)
+}
+
+func (s *stoppableBackgroundService) CancelProcess(process *background.Process) error {
+	s.mu.Lock()
+	s.forget(process.Identifier)
+	s.mu.Unlock()
+
+	return s.service.CancelProcess(process)
+}
+
+// forget stops tracking the processes with the identifier, s.mu must be held.
+func (s *stoppableBackgroundService) forget(identifier interface{}) {
+	if identifier == nil {
+		return
+	}
+	for key, pending := range s.pending {
+		if pending.identifier == identifier {
+			delete(s.pending, key)
+		}
+	}
+	backgroundPendingProcesses.Set(float64(len(s.pending)))
+}
+
+// runBackgroundProcess calls the callback and counts its outcome.
+func runBackgroundProcess(callBackFct func() error) error {
+	backgroundProcessRuns.Inc()
+	err := callBackFct()
+	if err != nil {
+		backgroundProcessFailures.Inc()
+	}
+	return err
+}
+
+// Stop runs the pending processes due soon, drops the other ones and waits for
+// the running ones to return, or for ctx to be done.
+func (s *stoppableBackgroundService) Stop(ctx context.Context) {
+	s.mu.Lock()
+	s.stopped = true
+	flushed := []*pendingProcess{}
+	for _, pending := range s.pending {
+		if !pending.repeat && time.Until(pending.dueAt) < maxFlushDelay {
+			flushed = append(flushed, pending)
+		}
+	}
+	dropped := len(s.pending) - len(flushed)
+	s.pending = nil
+	backgroundPendingProcesses.Set(0)
+	s.mu.Unlock()
+
+	s.logger.Info("stopping background service", zap.Int("flushed", len(flushed)), zap.Int("dropped", dropped))
+
+	for _, pending := range flushed {
+		if ctx.Err() != nil {
+			s.logger.Warn("background service stop deadline exceeded before every process was flushed")
+			return
+		}
+		err := runBackgroundProcess(pending.callBackFct)
+		if err != nil {
+			s.logger.Error("failed to flush background process", zap.Any("identifier", pending.identifier), zap.Error(err))
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger.Warn("background service stop deadline exceeded before every process returned")
+	}
+}
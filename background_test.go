@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	background "github.com/noted-eip/noted/background-service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStoppableBackgroundService(t *testing.T) {
+	logger := zap.NewNop()
+
+	t.Run("stop-flushes-processes-due-soon", func(t *testing.T) {
+		service := newStoppableBackgroundService(background.NewService(logger), logger)
+		keywords, expiration := 0, 0
+
+		require.NoError(t, service.AddProcess(&background.Process{
+			Identifier:        "keywords",
+			CallBackFct:       func() error { keywords++; return nil },
+			SecondsToDebounce: 5,
+		}))
+		require.NoError(t, service.AddProcess(&background.Process{
+			Identifier:        "quiz-expiration",
+			CallBackFct:       func() error { expiration++; return nil },
+			SecondsToDebounce: uint32((7 * 24 * time.Hour) / time.Second),
+		}))
+
+		service.Stop(context.Background())
+		require.Equal(t, 1, keywords)
+		require.Equal(t, 0, expiration)
+	})
+
+	t.Run("cancelled-processes-are-not-flushed", func(t *testing.T) {
+		service := newStoppableBackgroundService(background.NewService(logger), logger)
+		calls := 0
+
+		process := &background.Process{
+			Identifier:        "keywords",
+			CallBackFct:       func() error { calls++; return nil },
+			SecondsToDebounce: 5,
+		}
+		require.NoError(t, service.AddProcess(process))
+		require.NoError(t, service.CancelProcess(process))
+
+		service.Stop(context.Background())
+		require.Equal(t, 0, calls)
+	})
+
+	t.Run("cannot-add-process-once-stopped", func(t *testing.T) {
+		service := newStoppableBackgroundService(background.NewService(logger), logger)
+		service.Stop(context.Background())
+
+		err := service.AddProcess(&background.Process{
+			Identifier:        "keywords",
+			CallBackFct:       func() error { return nil },
+			SecondsToDebounce: 5,
+		})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,525 @@
+// Package backups implements the JSON serialisation of notes used to back
+// them up and to move them between instances. Its schema is versioned and
+// independent of the storage of the notes.
+package backups
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"notes-service/models"
+	notespb "notes-service/protorepo/noted/notes/v1"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Version of the schema written by NoteToJSON. Documents of older versions
+// are still read.
+const SchemaVersion = 1
+
+const schemaName = "noted.note"
+
+type Document struct {
+	Schema     string    `json:"schema"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Note       *Note     `json:"note"`
+}
+
+type Note struct {
+	ID                          string     `json:"id"`
+	Title                       string     `json:"title"`
+	AuthorAccountID             string     `json:"authorAccountId"`
+	FolderID                    string     `json:"folderId,omitempty"`
+	Lang                        string     `json:"lang"`
+	CreatedAt                   time.Time  `json:"createdAt"`
+	ModifiedAt                  *time.Time `json:"modifiedAt,omitempty"`
+	AnalyzedAt                  *time.Time `json:"analyzedAt,omitempty"`
+	SourceNoteID                string     `json:"sourceNoteId,omitempty"`
+	AccountsWithEditPermissions []string   `json:"accountsWithEditPermissions"`
+	Keywords                    []*Keyword `json:"keywords"`
+	Blocks                      []*Block   `json:"blocks"`
+	Quizzes                     []*Quiz    `json:"quizzes"`
+	Summary                     *Summary   `json:"summary,omitempty"`
+}
+
+type Keyword struct {
+	Keyword  string `json:"keyword"`
+	Type     string `json:"type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Summary  string `json:"summary,omitempty"`
+	ImageURL string `json:"imageUrl,omitempty"`
+}
+
+type Block struct {
+	ID            string         `json:"id"`
+	Type          string         `json:"type"`
+	Heading       *string        `json:"heading,omitempty"`
+	Paragraph     *string        `json:"paragraph,omitempty"`
+	NumberPoint   *string        `json:"numberPoint,omitempty"`
+	BulletPoint   *string        `json:"bulletPoint,omitempty"`
+	Math          *string        `json:"math,omitempty"`
+	Summary       *string        `json:"summary,omitempty"`
+	Image         *Image         `json:"image,omitempty"`
+	Code          *Code          `json:"code,omitempty"`
+	ChecklistItem *ChecklistItem `json:"checklistItem,omitempty"`
+	Table         *Table         `json:"table,omitempty"`
+	Callout       *Callout       `json:"callout,omitempty"`
+	Embed         *Embed         `json:"embed,omitempty"`
+	Styles        []*TextStyle   `json:"styles"`
+	Thread        []*Comment     `json:"thread"`
+}
+
+type Image struct {
+	URL     string `json:"url"`
+	Caption string `json:"caption"`
+}
+
+type Code struct {
+	Snippet string `json:"snippet"`
+	Lang    string `json:"lang"`
+}
+
+type ChecklistItem struct {
+	Text              string `json:"text"`
+	Checked           bool   `json:"checked"`
+	AssigneeAccountID string `json:"assigneeAccountId,omitempty"`
+}
+
+type Table struct {
+	HasHeaderRow bool           `json:"hasHeaderRow"`
+	Rows         [][]*TableCell `json:"rows"`
+}
+
+type TableCell struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type Callout struct {
+	Tone string `json:"tone"`
+	Text string `json:"text"`
+}
+
+type Embed struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+}
+
+type TextStyle struct {
+	Style  string `json:"style,omitempty"`
+	Start  int64  `json:"start"`
+	Length int64  `json:"length"`
+	Color  *Color `json:"color,omitempty"`
+}
+
+type Color struct {
+	R int32 `json:"r"`
+	G int32 `json:"g"`
+	B int32 `json:"b"`
+}
+
+type Comment struct {
+	ID              string `json:"id"`
+	AuthorAccountID string `json:"authorAccountId"`
+	Content         string `json:"content"`
+}
+
+type Quiz struct {
+	ID        string          `json:"id"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Questions []*QuizQuestion `json:"questions"`
+}
+
+type QuizQuestion struct {
+	Question  string   `json:"question"`
+	Answers   []string `json:"answers"`
+	Solutions []string `json:"solutions"`
+}
+
+type Summary struct {
+	Content     string    `json:"content"`
+	ContentHash string    `json:"contentHash,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// NoteToJSON writes every data of the note, except its embedding which is
+// computed again, as a document of the current schema version.
+func NoteToJSON(note *models.Note) ([]byte, error) {
+	document := &Document{
+		Schema:     schemaName,
+		Version:    SchemaVersion,
+		ExportedAt: time.Now().UTC(),
+		Note:       noteFromModel(note),
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// NoteFromJSON reads and validates a document written by NoteToJSON. The note
+// keeps the IDs and authors it had when exported.
+func NoteFromJSON(data []byte) (*models.Note, error) {
+	document := &Document{}
+	err := json.Unmarshal(data, document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	if document.Schema != schemaName {
+		return nil, errors.New("not a note document")
+	}
+	if document.Version < 1 || document.Version > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version: %d", document.Version)
+	}
+	if document.Note == nil {
+		return nil, errors.New("note: cannot be blank")
+	}
+
+	err = document.Note.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("note: %w", err)
+	}
+
+	return document.Note.toModel(), nil
+}
+
+func (n Note) Validate() error {
+	return validation.ValidateStruct(&n,
+		validation.Field(&n.Title, validation.Required, validation.Length(1, 64)),
+		validation.Field(&n.AuthorAccountID, validation.Required),
+		validation.Field(&n.Keywords, validation.Each(validation.NotNil)),
+		validation.Field(&n.Blocks, validation.Each(validation.NotNil)),
+		validation.Field(&n.Quizzes, validation.Each(validation.NotNil)),
+	)
+}
+
+func (k Keyword) Validate() error {
+	return validation.ValidateStruct(&k,
+		validation.Field(&k.Keyword, validation.Required),
+	)
+}
+
+// Fields holding the content of each type of block.
+func (b Block) content() map[string]bool {
+	return map[string]bool{
+		"TYPE_HEADING_1":      b.Heading != nil,
+		"TYPE_HEADING_2":      b.Heading != nil,
+		"TYPE_HEADING_3":      b.Heading != nil,
+		"TYPE_PARAGRAPH":      b.Paragraph != nil,
+		"TYPE_NUMBER_POINT":   b.NumberPoint != nil,
+		"TYPE_BULLET_POINT":   b.BulletPoint != nil,
+		"TYPE_MATH":           b.Math != nil,
+		"TYPE_CODE":           b.Code != nil,
+		"TYPE_IMAGE":          b.Image != nil,
+		"TYPE_SUMMARY":        b.Summary != nil,
+		"TYPE_CHECKLIST_ITEM": b.ChecklistItem != nil,
+		"TYPE_TABLE":          b.Table != nil,
+		"TYPE_CALLOUT":        b.Callout != nil,
+		"TYPE_DIVIDER":        true,
+		"TYPE_EMBED":          b.Embed != nil,
+	}
+}
+
+func (b Block) Validate() error {
+	return validation.ValidateStruct(&b,
+		validation.Field(&b.Type, validation.Required, validation.By(func(interface{}) error {
+			hasContent, known := b.content()[b.Type]
+			if !known {
+				return errors.New("unknown block type")
+			}
+			if !hasContent {
+				return errors.New("block has no content")
+			}
+			return nil
+		})),
+		validation.Field(&b.Table),
+		validation.Field(&b.Styles, validation.Each(validation.NotNil)),
+		validation.Field(&b.Thread, validation.Each(validation.NotNil)),
+	)
+}
+
+func (t Table) Validate() error {
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.Rows, validation.Each(validation.Each(validation.NotNil))),
+	)
+}
+
+func (s TextStyle) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Style,
+			validation.When(s.Color == nil, validation.Required),
+			validation.When(s.Style != "", validation.In(textStyles()...)),
+		),
+		validation.Field(&s.Start, validation.Min(0)),
+		validation.Field(&s.Length, validation.Min(0)),
+	)
+}
+
+func textStyles() []interface{} {
+	styles := []interface{}{}
+	for name, value := range notespb.Block_TextStyle_Style_value {
+		if value != 0 {
+			styles = append(styles, name)
+		}
+	}
+	return styles
+}
+
+func (c Comment) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.AuthorAccountID, validation.Required),
+		validation.Field(&c.Content, validation.Required),
+	)
+}
+
+func (q Quiz) Validate() error {
+	return validation.ValidateStruct(&q,
+		validation.Field(&q.Questions, validation.Each(validation.NotNil)),
+	)
+}
+
+func (q QuizQuestion) Validate() error {
+	return validation.ValidateStruct(&q,
+		validation.Field(&q.Question, validation.Required),
+	)
+}
+
+func noteFromModel(note *models.Note) *Note {
+	n := &Note{
+		ID:                          note.ID,
+		Title:                       note.Title,
+		AuthorAccountID:             note.AuthorAccountID,
+		FolderID:                    note.FolderID,
+		Lang:                        note.Lang,
+		CreatedAt:                   note.CreatedAt,
+		ModifiedAt:                  note.ModifiedAt,
+		AnalyzedAt:                  note.AnalyzedAt,
+		SourceNoteID:                note.SourceNoteID,
+		AccountsWithEditPermissions: append([]string{}, note.AccountsWithEditPermissions...),
+		Keywords:                    []*Keyword{},
+		Blocks:                      []*Block{},
+		Quizzes:                     []*Quiz{},
+	}
+
+	for _, keyword := range note.Keywords {
+		n.Keywords = append(n.Keywords, &Keyword{
+			Keyword:  keyword.Keyword,
+			Type:     keyword.Type,
+			URL:      keyword.URL,
+			Summary:  keyword.Summary,
+			ImageURL: keyword.ImageURL,
+		})
+	}
+
+	if note.Blocks != nil {
+		for _, block := range *note.Blocks {
+			n.Blocks = append(n.Blocks, blockFromModel(&block))
+		}
+	}
+
+	if note.Quizs != nil {
+		for _, quiz := range *note.Quizs {
+			q := &Quiz{ID: quiz.ID, CreatedAt: quiz.CreatedAt, Questions: []*QuizQuestion{}}
+			for _, question := range quiz.QuizQuestions {
+				q.Questions = append(q.Questions, &QuizQuestion{
+					Question:  question.Question,
+					Answers:   append([]string{}, question.Answers...),
+					Solutions: append([]string{}, question.Solutions...),
+				})
+			}
+			n.Quizzes = append(n.Quizzes, q)
+		}
+	}
+
+	if note.Summary != nil {
+		n.Summary = &Summary{
+			Content:     note.Summary.Content,
+			ContentHash: note.Summary.ContentHash,
+			CreatedAt:   note.Summary.CreatedAt,
+		}
+	}
+
+	return n
+}
+
+func blockFromModel(block *models.NoteBlock) *Block {
+	b := &Block{
+		ID:          block.ID,
+		Type:        block.Type,
+		Heading:     block.Heading,
+		Paragraph:   block.Paragraph,
+		NumberPoint: block.NumberPoint,
+		BulletPoint: block.BulletPoint,
+		Math:        block.Math,
+		Summary:     block.Summary,
+		Styles:      []*TextStyle{},
+		Thread:      []*Comment{},
+	}
+
+	if block.Image != nil {
+		b.Image = &Image{URL: block.Image.Url, Caption: block.Image.Caption}
+	}
+	if block.Code != nil {
+		b.Code = &Code{Snippet: block.Code.Snippet, Lang: block.Code.Lang}
+	}
+	if block.ChecklistItem != nil {
+		b.ChecklistItem = &ChecklistItem{
+			Text:              block.ChecklistItem.Text,
+			Checked:           block.ChecklistItem.Checked,
+			AssigneeAccountID: block.ChecklistItem.AssigneeAccountID,
+		}
+	}
+	if block.Table != nil {
+		b.Table = &Table{HasHeaderRow: block.Table.HasHeaderRow, Rows: [][]*TableCell{}}
+		for _, row := range block.Table.Rows {
+			cells := []*TableCell{}
+			for _, cell := range row.Cells {
+				cells = append(cells, &TableCell{Type: cell.Type, Value: cell.Value})
+			}
+			b.Table.Rows = append(b.Table.Rows, cells)
+		}
+	}
+	if block.Callout != nil {
+		b.Callout = &Callout{Tone: block.Callout.Tone, Text: block.Callout.Text}
+	}
+	if block.Embed != nil {
+		b.Embed = &Embed{URL: block.Embed.Url, Title: block.Embed.Title}
+	}
+	if block.Styles != nil {
+		for _, style := range *block.Styles {
+			s := &TextStyle{Style: style.Style, Start: style.Position.Start, Length: style.Position.Length}
+			if style.Color != nil {
+				s.Color = &Color{R: style.Color.R, G: style.Color.G, B: style.Color.B}
+			}
+			b.Styles = append(b.Styles, s)
+		}
+	}
+	if block.Thread != nil {
+		for _, comment := range *block.Thread {
+			b.Thread = append(b.Thread, &Comment{
+				ID:              comment.ID,
+				AuthorAccountID: comment.AuthorAccountID,
+				Content:         comment.Content,
+			})
+		}
+	}
+
+	return b
+}
+
+func (n *Note) toModel() *models.Note {
+	note := &models.Note{
+		ID:                          n.ID,
+		Title:                       n.Title,
+		AuthorAccountID:             n.AuthorAccountID,
+		FolderID:                    n.FolderID,
+		Lang:                        n.Lang,
+		CreatedAt:                   n.CreatedAt,
+		ModifiedAt:                  n.ModifiedAt,
+		AnalyzedAt:                  n.AnalyzedAt,
+		SourceNoteID:                n.SourceNoteID,
+		AccountsWithEditPermissions: append([]string{}, n.AccountsWithEditPermissions...),
+		Keywords:                    []*models.Keyword{},
+		Blocks:                      &[]models.NoteBlock{},
+		Quizs:                       &[]models.Quiz{},
+	}
+
+	for _, keyword := range n.Keywords {
+		note.Keywords = append(note.Keywords, &models.Keyword{
+			Keyword:  keyword.Keyword,
+			Type:     keyword.Type,
+			URL:      keyword.URL,
+			Summary:  keyword.Summary,
+			ImageURL: keyword.ImageURL,
+		})
+	}
+
+	for _, block := range n.Blocks {
+		*note.Blocks = append(*note.Blocks, *block.toModel())
+	}
+
+	for _, quiz := range n.Quizzes {
+		q := models.Quiz{ID: quiz.ID, CreatedAt: quiz.CreatedAt, QuizQuestions: []models.QuizQuestion{}}
+		for _, question := range quiz.Questions {
+			q.QuizQuestions = append(q.QuizQuestions, models.QuizQuestion{
+				Question:  question.Question,
+				Answers:   append([]string{}, question.Answers...),
+				Solutions: append([]string{}, question.Solutions...),
+			})
+		}
+		*note.Quizs = append(*note.Quizs, q)
+	}
+
+	if n.Summary != nil {
+		note.Summary = &models.Summary{
+			Content:     n.Summary.Content,
+			ContentHash: n.Summary.ContentHash,
+			CreatedAt:   n.Summary.CreatedAt,
+		}
+	}
+
+	return note
+}
+
+func (b *Block) toModel() *models.NoteBlock {
+	block := &models.NoteBlock{
+		ID:          b.ID,
+		Type:        b.Type,
+		Styles:      &[]models.TextStyle{},
+		Thread:      &[]models.BlockComment{},
+		Heading:     b.Heading,
+		Paragraph:   b.Paragraph,
+		NumberPoint: b.NumberPoint,
+		BulletPoint: b.BulletPoint,
+		Math:        b.Math,
+		Summary:     b.Summary,
+	}
+
+	if b.Image != nil {
+		block.Image = &models.NoteBlockImage{Url: b.Image.URL, Caption: b.Image.Caption}
+	}
+	if b.Code != nil {
+		block.Code = &models.NoteBlockCode{Snippet: b.Code.Snippet, Lang: b.Code.Lang}
+	}
+	if b.ChecklistItem != nil {
+		block.ChecklistItem = &models.NoteBlockChecklistItem{
+			Text:              b.ChecklistItem.Text,
+			Checked:           b.ChecklistItem.Checked,
+			AssigneeAccountID: b.ChecklistItem.AssigneeAccountID,
+		}
+	}
+	if b.Table != nil {
+		block.Table = &models.NoteBlockTable{HasHeaderRow: b.Table.HasHeaderRow, Rows: []models.NoteBlockTableRow{}}
+		for _, cells := range b.Table.Rows {
+			row := models.NoteBlockTableRow{Cells: []models.NoteBlockTableCell{}}
+			for _, cell := range cells {
+				row.Cells = append(row.Cells, models.NoteBlockTableCell{Type: cell.Type, Value: cell.Value})
+			}
+			block.Table.Rows = append(block.Table.Rows, row)
+		}
+	}
+	if b.Callout != nil {
+		block.Callout = &models.NoteBlockCallout{Tone: b.Callout.Tone, Text: b.Callout.Text}
+	}
+	if b.Embed != nil {
+		block.Embed = &models.NoteBlockEmbed{Url: b.Embed.URL, Title: b.Embed.Title}
+	}
+	for _, style := range b.Styles {
+		s := models.TextStyle{Style: style.Style, Position: models.Position{Start: style.Start, Length: style.Length}}
+		if style.Color != nil {
+			s.Color = &models.Color{R: style.Color.R, G: style.Color.G, B: style.Color.B}
+		}
+		*block.Styles = append(*block.Styles, s)
+	}
+	for _, comment := range b.Thread {
+		*block.Thread = append(*block.Thread, models.BlockComment{
+			ID:              comment.ID,
+			AuthorAccountID: comment.AuthorAccountID,
+			Content:         comment.Content,
+		})
+	}
+
+	return block
+}
@@ -0,0 +1,134 @@
+package backups_test
+
+import (
+	"notes-service/backups"
+	"notes-service/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func Test_NoteToJSON(t *testing.T) {
+	// Given
+	createdAt := time.Date(2023, time.March, 14, 10, 0, 0, 0, time.UTC)
+	modifiedAt := createdAt.Add(time.Hour)
+	note := &models.Note{
+		ID:                          "note",
+		Title:                       "Les chats",
+		AuthorAccountID:             "edouard",
+		GroupID:                     "group",
+		FolderID:                    "animaux",
+		Lang:                        "fr",
+		CreatedAt:                   createdAt,
+		ModifiedAt:                  &modifiedAt,
+		AccountsWithEditPermissions: []string{"edouard", "maxime"},
+		Keywords:                    []*models.Keyword{{Keyword: "chat", Type: "ANIMAL", URL: "https://fr.wikipedia.org/wiki/Chat"}},
+		Blocks: &[]models.NoteBlock{
+			{
+				ID:        "heading",
+				Type:      "TYPE_HEADING_1",
+				Heading:   stringPtr("Définition"),
+				Styles:    &[]models.TextStyle{},
+				Thread:    &[]models.BlockComment{},
+				Paragraph: nil,
+			},
+			{
+				ID:        "paragraph",
+				Type:      "TYPE_PARAGRAPH",
+				Paragraph: stringPtr("Le chat est noir."),
+				Styles: &[]models.TextStyle{
+					{Style: "STYLE_BOLD", Position: models.Position{Start: 3, Length: 4}},
+					{Position: models.Position{Start: 12, Length: 4}, Color: &models.Color{R: 255}},
+				},
+				Thread: &[]models.BlockComment{{ID: "comment", AuthorAccountID: "maxime", Content: "Pas toujours"}},
+			},
+			{
+				ID:     "code",
+				Type:   "TYPE_CODE",
+				Code:   &models.NoteBlockCode{Snippet: "fmt.Println(\"miaou\")", Lang: "go"},
+				Styles: &[]models.TextStyle{},
+				Thread: &[]models.BlockComment{},
+			},
+			{
+				ID:   "table",
+				Type: "TYPE_TABLE",
+				Table: &models.NoteBlockTable{HasHeaderRow: true, Rows: []models.NoteBlockTableRow{
+					{Cells: []models.NoteBlockTableCell{{Type: "TYPE_TEXT", Value: "Race"}, {Type: "TYPE_NUMBER", Value: "Poids"}}},
+					{Cells: []models.NoteBlockTableCell{{Type: "TYPE_TEXT", Value: "Siamois"}, {Type: "TYPE_NUMBER", Value: "4.5"}}},
+				}},
+				Styles: &[]models.TextStyle{},
+				Thread: &[]models.BlockComment{},
+			},
+			{
+				ID:            "checklist",
+				Type:          "TYPE_CHECKLIST_ITEM",
+				ChecklistItem: &models.NoteBlockChecklistItem{Text: "Nourrir le chat", Checked: true, AssigneeAccountID: "maxime"},
+				Styles:        &[]models.TextStyle{},
+				Thread:        &[]models.BlockComment{},
+			},
+			{
+				ID:     "divider",
+				Type:   "TYPE_DIVIDER",
+				Styles: &[]models.TextStyle{},
+				Thread: &[]models.BlockComment{},
+			},
+		},
+		Quizs: &[]models.Quiz{{
+			ID:            "quiz",
+			CreatedAt:     createdAt,
+			QuizQuestions: []models.QuizQuestion{{Question: "Le chat est ?", Answers: []string{"noir", "blanc"}, Solutions: []string{"noir"}}},
+		}},
+		Summary:   &models.Summary{Content: "Les chats sont noirs.", ContentHash: "hash", CreatedAt: createdAt},
+		Embedding: &models.NoteEmbedding{Model: "hashing", Vector: []float32{1}},
+	}
+
+	// When
+	data, err := backups.NoteToJSON(note)
+	require.NoError(t, err)
+	imported, err := backups.NoteFromJSON(data)
+	require.NoError(t, err)
+
+	// Then
+	require.Contains(t, string(data), `"version": 1`)
+	expected := *note
+	// The group is chosen when importing and the embedding is computed again.
+	expected.GroupID = ""
+	expected.Embedding = nil
+	require.Equal(t, &expected, imported)
+}
+
+func Test_NoteFromJSON(t *testing.T) {
+	valid := `{"schema": "noted.note", "version": 1, "note": {"title": "Les chats", "authorAccountId": "edouard", "blocks": [%s]}}`
+
+	_, err := backups.NoteFromJSON([]byte(strings.Replace(valid, "%s", `{"type": "TYPE_PARAGRAPH", "paragraph": "Miaou"}`, 1)))
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"invalid json":       `{"schema": `,
+		"not a note":         `{"schema": "noted.group", "version": 1, "note": {}}`,
+		"unsupported":        `{"schema": "noted.note", "version": 2, "note": {}}`,
+		"missing note":       `{"schema": "noted.note", "version": 1}`,
+		"missing title":      `{"schema": "noted.note", "version": 1, "note": {"authorAccountId": "edouard"}}`,
+		"unknown block type": strings.Replace(valid, "%s", `{"type": "TYPE_VIDEO", "paragraph": "Miaou"}`, 1),
+		"block content":      strings.Replace(valid, "%s", `{"type": "TYPE_HEADING_1", "paragraph": "Miaou"}`, 1),
+		"null block":         strings.Replace(valid, "%s", `null`, 1),
+		"unknown style":      strings.Replace(valid, "%s", `{"type": "TYPE_PARAGRAPH", "paragraph": "Miaou", "styles": [{"style": "STYLE_BLINK"}]}`, 1),
+		"negative position":  strings.Replace(valid, "%s", `{"type": "TYPE_PARAGRAPH", "paragraph": "Miaou", "styles": [{"style": "STYLE_BOLD", "start": -1}]}`, 1),
+		"null table cell":    strings.Replace(valid, "%s", `{"type": "TYPE_TABLE", "table": {"rows": [[null]]}}`, 1),
+		"empty comment":      strings.Replace(valid, "%s", `{"type": "TYPE_PARAGRAPH", "paragraph": "Miaou", "thread": [{"authorAccountId": "edouard"}]}`, 1),
+	}
+
+	for name, document := range tests {
+		t.Run(name, func(t *testing.T) {
+			note, err := backups.NoteFromJSON([]byte(document))
+			require.Error(t, err)
+			require.Nil(t, note)
+		})
+	}
+}
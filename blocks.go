@@ -24,17 +24,25 @@ func (srv *notesAPI) InsertBlock(ctx context.Context, req *notesv1.InsertBlockRe
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	err = srv.checkGroupChecklistAssignees(ctx, req.GroupId, token.AccountID, []*notesv1.Block{req.Block})
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId, ExpectedRevision: req.ExpectedRevision}
 	block, err := srv.notes.InsertBlock(ctx,
-		&models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId},
+		filter,
 		&models.InsertNoteBlockPayload{
 			Index: uint(req.Index),
 			Block: *protobufBlockToModelsBlock(req.Block),
 		},
 		token.AccountID)
 	if err != nil {
-		return nil, statusFromModelError(err)
+		return nil, srv.noteWriteError(ctx, filter, token.AccountID, err)
 	}
 
+	srv.reindexNoteLinks(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+
 	// Launch process to generate keywords in 15minutes after the last modification
 	srv.background.AddProcess(&background.Process{
 		Identifier: models.NoteIdentifier{Metadata: req.NoteId, ActionType: models.NoteUpdateKeyword},
@@ -61,30 +69,19 @@ func (srv *notesAPI) UpdateBlockIndex(ctx context.Context, req *notesv1.UpdateBl
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	block, err := srv.notes.GetBlock(ctx,
-		&models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId},
-		token.AccountID)
-	if err != nil {
-		return nil, statusFromModelError(err)
-	}
-	err = srv.notes.DeleteBlock(ctx,
-		&models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId},
-		token.AccountID)
-	if err != nil {
-		return nil, statusFromModelError(err)
-	}
-	block, err = srv.notes.InsertBlock(ctx,
-		&models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId},
-		&models.InsertNoteBlockPayload{
+	// The block is moved only if it did not change since the expected revision.
+	filter := &models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId, ExpectedRevision: req.ExpectedRevision}
+	note, err := srv.notes.MoveBlock(ctx, filter,
+		&models.MoveBlockPayload{
 			Index: uint(req.Index),
-			Block: *block,
+			Count: uint(req.Count),
 		},
 		token.AccountID)
 	if err != nil {
-		return nil, statusFromModelError(err)
+		return nil, srv.blockWriteError(ctx, filter, token.AccountID, err)
 	}
 
-	return &notesv1.UpdateBlockIndexResponse{Block: modelsBlockToProtobufBlock(block)}, nil
+	return &notesv1.UpdateBlockIndexResponse{Block: modelsBlockToProtobufBlock(note.FindBlock(req.BlockId))}, nil
 }
 
 func (srv *notesAPI) UpdateBlock(ctx context.Context, req *notesv1.UpdateBlockRequest) (*notesv1.UpdateBlockResponse, error) {
@@ -98,16 +95,24 @@ func (srv *notesAPI) UpdateBlock(ctx context.Context, req *notesv1.UpdateBlockRe
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	err = srv.checkGroupChecklistAssignees(ctx, req.GroupId, token.AccountID, []*notesv1.Block{req.Block})
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId, ExpectedRevision: req.ExpectedRevision}
 	block, err := srv.notes.UpdateBlock(ctx,
-		&models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId},
+		filter,
 		&models.UpdateBlockPayload{
 			Block: *protobufBlockToModelsBlock(req.Block),
 		},
 		token.AccountID)
 	if err != nil {
-		return nil, statusFromModelError(err)
+		return nil, srv.blockWriteError(ctx, filter, token.AccountID, err)
 	}
 
+	srv.reindexNoteLinks(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+
 	// Launch process to generate keywords in 15minutes after the last modification
 	srv.background.AddProcess(&background.Process{
 		Identifier: models.NoteIdentifier{Metadata: req.NoteId, ActionType: models.NoteUpdateKeyword},
@@ -134,13 +139,15 @@ func (srv *notesAPI) DeleteBlock(ctx context.Context, req *notesv1.DeleteBlockRe
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	err = srv.notes.DeleteBlock(ctx,
-		&models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId},
-		token.AccountID)
+	filter := &models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId, ExpectedRevision: req.ExpectedRevision}
+	err = srv.notes.DeleteBlock(ctx, filter, token.AccountID)
 	if err != nil {
-		return nil, statusFromModelError(err)
+		return nil, srv.blockWriteError(ctx, filter, token.AccountID, err)
 	}
 
+	srv.reindexNoteLinks(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+	breakLinksToBlocks(ctx, loggerFromContext(ctx, srv.logger), srv.links, req.NoteId, []string{req.BlockId})
+
 	// Launch process to generate keywords in 15minutes after the last modification
 	srv.background.AddProcess(&background.Process{
 		Identifier: models.NoteIdentifier{Metadata: req.NoteId, ActionType: models.NoteUpdateKeyword},
@@ -155,3 +162,102 @@ func (srv *notesAPI) DeleteBlock(ctx context.Context, req *notesv1.DeleteBlockRe
 
 	return &notesv1.DeleteBlockResponse{}, nil
 }
+
+func (srv *notesAPI) BatchUpdateBlocks(ctx context.Context, req *notesv1.BatchUpdateBlocksRequest) (*notesv1.BatchUpdateBlocksResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateBatchUpdateBlocksRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	blocks := []*notesv1.Block{}
+	for _, operation := range req.Operations {
+		if block := operation.GetInsert().GetBlock(); block != nil {
+			blocks = append(blocks, block)
+		}
+		if block := operation.GetUpdate().GetBlock(); block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+	err = srv.checkGroupChecklistAssignees(ctx, req.GroupId, token.AccountID, blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	operations, deletedBlockIDs := protobufBlockOperationsToModelsBlockOperations(req.Operations)
+
+	filter := &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId, ExpectedRevision: req.ExpectedRevision}
+	note, err := srv.notes.BatchUpdateBlocks(ctx, filter, operations, token.AccountID)
+	if err != nil {
+		return nil, srv.noteWriteError(ctx, filter, token.AccountID, err)
+	}
+
+	indexNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.notes, srv.links, note)
+	breakLinksToBlocks(ctx, loggerFromContext(ctx, srv.logger), srv.links, note.ID, deletedBlockIDs)
+
+	// Launch process to generate keywords in 15minutes after the last modification
+	srv.background.AddProcess(&background.Process{
+		Identifier: models.NoteIdentifier{Metadata: req.NoteId, ActionType: models.NoteUpdateKeyword},
+		CallBackFct: func() error {
+			err := srv.UpdateKeywordsByNoteId(req.NoteId, req.GroupId, token.AccountID)
+			return err
+		},
+		SecondsToDebounce:             5,
+		CancelProcessOnSameIdentifier: true,
+		RepeatProcess:                 false,
+	})
+
+	protobufNote := modelsNoteToProtobufNote(note)
+
+	return &notesv1.BatchUpdateBlocksResponse{Blocks: protobufNote.Blocks, Revision: note.Revision}, nil
+}
+
+// protobufBlockOperationsToModelsBlockOperations also returns the IDs of the
+// deleted blocks.
+func protobufBlockOperationsToModelsBlockOperations(protobufOperations []*notesv1.BlockOperation) ([]models.BlockOperation, []string) {
+	operations := make([]models.BlockOperation, len(protobufOperations))
+	deletedBlockIDs := []string{}
+
+	for i, operation := range protobufOperations {
+		switch {
+		case operation.GetInsert() != nil:
+			insert := operation.GetInsert()
+			operations[i] = models.BlockOperation{
+				Type:  models.BlockOperationInsert,
+				Block: *protobufBlockToModelsBlock(insert.Block),
+				Index: uint(insert.Index),
+			}
+		case operation.GetUpdate() != nil:
+			update := operation.GetUpdate()
+			operations[i] = models.BlockOperation{
+				Type:             models.BlockOperationUpdate,
+				BlockID:          update.BlockId,
+				Block:            *protobufBlockToModelsBlock(update.Block),
+				ExpectedRevision: update.ExpectedRevision,
+			}
+		case operation.GetDelete() != nil:
+			deletion := operation.GetDelete()
+			operations[i] = models.BlockOperation{
+				Type:             models.BlockOperationDelete,
+				BlockID:          deletion.BlockId,
+				ExpectedRevision: deletion.ExpectedRevision,
+			}
+			deletedBlockIDs = append(deletedBlockIDs, deletion.BlockId)
+		case operation.GetMove() != nil:
+			move := operation.GetMove()
+			operations[i] = models.BlockOperation{
+				Type:             models.BlockOperationMove,
+				BlockID:          move.BlockId,
+				Index:            uint(move.Index),
+				Count:            uint(move.Count),
+				ExpectedRevision: move.ExpectedRevision,
+			}
+		}
+	}
+
+	return operations, deletedBlockIDs
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"notes-service/models"
 	notesv1 "notes-service/protorepo/noted/notes/v1"
 
@@ -239,4 +240,236 @@ func TestBlocksSuite(t *testing.T) {
 		require.NoError(t, err)
 		require.Nil(t, note.FindBlock(someBlock.ID))
 	})
+
+	t.Run("batch-update-blocks", func(t *testing.T) {
+		note := newTestNote(t, tu, edouardGroup, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_HEADING_1, Data: &notesv1.Block_Heading{Heading: "Cellule"}},
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Brouillon"}},
+		})
+		stored, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: edouardGroup.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		heading, draft := stored.Note.Blocks[0], stored.Note.Blocks[1]
+
+		res, err := tu.notes.BatchUpdateBlocks(maxime.Context, &notesv1.BatchUpdateBlocksRequest{
+			GroupId:          edouardGroup.ID,
+			NoteId:           note.ID,
+			ExpectedRevision: stored.Note.Revision,
+			Operations: []*notesv1.BlockOperation{
+				{Op: &notesv1.BlockOperation_Insert{Insert: &notesv1.BlockOperation_InsertBlock{
+					Index: 2,
+					Block: &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Noyau"}},
+				}}},
+				{Op: &notesv1.BlockOperation_Update{Update: &notesv1.BlockOperation_UpdateBlock{
+					BlockId:          heading.Id,
+					Block:            &notesv1.Block{Type: notesv1.Block_TYPE_HEADING_1, Data: &notesv1.Block_Heading{Heading: "La cellule"}},
+					ExpectedRevision: heading.Revision,
+				}}},
+				{Op: &notesv1.BlockOperation_Delete{Delete: &notesv1.BlockOperation_DeleteBlock{BlockId: draft.Id}}},
+				{Op: &notesv1.BlockOperation_Move{Move: &notesv1.BlockOperation_MoveBlock{BlockId: heading.Id, Index: 1}}},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, stored.Note.Revision+1, res.Revision)
+		require.Len(t, res.Blocks, 2)
+		require.Equal(t, "Noyau", res.Blocks[0].GetParagraph())
+		require.Equal(t, heading.Id, res.Blocks[1].Id)
+		require.Equal(t, "La cellule", res.Blocks[1].GetHeading())
+		require.Equal(t, heading.Revision+1, res.Blocks[1].Revision)
+	})
+
+	t.Run("batch-update-blocks-is-atomic", func(t *testing.T) {
+		note := newTestNote(t, tu, edouardGroup, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Mitochondrie"}},
+		})
+
+		_, err := tu.notes.BatchUpdateBlocks(maxime.Context, &notesv1.BatchUpdateBlocksRequest{
+			GroupId: edouardGroup.ID,
+			NoteId:  note.ID,
+			Operations: []*notesv1.BlockOperation{
+				{Op: &notesv1.BlockOperation_Insert{Insert: &notesv1.BlockOperation_InsertBlock{
+					Block: &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Lysosome"}},
+				}}},
+				{Op: &notesv1.BlockOperation_Delete{Delete: &notesv1.BlockOperation_DeleteBlock{BlockId: "does-not-exist-in-the-note"}}},
+			},
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+
+		stored, err := tu.notesRepository.GetNote(context.TODO(), &models.OneNoteFilter{GroupID: edouardGroup.ID, NoteID: note.ID}, maxime.ID)
+		require.NoError(t, err)
+		require.Len(t, *stored.Blocks, 1)
+	})
+
+	t.Run("batch-update-blocks-keeps-concurrent-comments", func(t *testing.T) {
+		note := newTestNote(t, tu, edouardGroup, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Mitochondrie"}},
+		})
+		stored, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: edouardGroup.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		blockID := stored.Note.Blocks[0].Id
+
+		// The comments are created while the block is rewritten by batch updates.
+		const comments = 10
+		errs := make(chan error, comments)
+		go func() {
+			defer close(errs)
+			for i := 0; i < comments; i++ {
+				_, err := tu.notes.CreateBlockComment(edouard.Context, &notesv1.CreateBlockCommentRequest{
+					GroupId: edouardGroup.ID,
+					NoteId:  note.ID,
+					BlockId: blockID,
+					Comment: &notesv1.Block_Comment{Content: fmt.Sprint("Commentaire ", i)},
+				})
+				errs <- err
+			}
+		}()
+
+		created := 0
+		for created < comments {
+			select {
+			case err := <-errs:
+				require.NoError(t, err)
+				created++
+			default:
+			}
+
+			_, err := tu.notes.BatchUpdateBlocks(maxime.Context, &notesv1.BatchUpdateBlocksRequest{
+				GroupId: edouardGroup.ID,
+				NoteId:  note.ID,
+				Operations: []*notesv1.BlockOperation{
+					{Op: &notesv1.BlockOperation_Update{Update: &notesv1.BlockOperation_UpdateBlock{
+						BlockId: blockID,
+						Block:   &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Mitochondrie"}},
+					}}},
+				},
+			})
+			// Batch updates retried too many times give up.
+			if err != nil {
+				requireErrorHasGRPCCode(t, codes.Aborted, err)
+			}
+		}
+
+		stored, err = tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: edouardGroup.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		require.Len(t, stored.Note.Blocks[0].Thread, comments)
+	})
+
+	t.Run("batch-update-blocks-member-cannot-update", func(t *testing.T) {
+		_, err := tu.notes.BatchUpdateBlocks(edouard.Context, &notesv1.BatchUpdateBlocksRequest{
+			GroupId: maximeNote.Group.ID,
+			NoteId:  maximeNote.ID,
+			Operations: []*notesv1.BlockOperation{
+				{Op: &notesv1.BlockOperation_Insert{Insert: &notesv1.BlockOperation_InsertBlock{
+					Block: &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Vacuole"}},
+				}}},
+			},
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+	})
+
+	t.Run("update-block-index-keeps-the-block", func(t *testing.T) {
+		note := newTestNote(t, tu, edouardGroup, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Prophase"}},
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Métaphase"}},
+		})
+		stored, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: edouardGroup.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		prophase := stored.Note.Blocks[0]
+
+		res, err := tu.notes.UpdateBlockIndex(maxime.Context, &notesv1.UpdateBlockIndexRequest{
+			GroupId: edouardGroup.ID,
+			NoteId:  note.ID,
+			BlockId: prophase.Id,
+			Index:   1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, prophase.Id, res.Block.Id)
+		require.Equal(t, prophase.Revision, res.Block.Revision)
+
+		moved, err := tu.notesRepository.GetNote(context.TODO(), &models.OneNoteFilter{GroupID: edouardGroup.ID, NoteID: note.ID}, maxime.ID)
+		require.NoError(t, err)
+		require.Len(t, *moved.Blocks, 2)
+		require.Equal(t, prophase.Id, (*moved.Blocks)[1].ID)
+	})
+
+	t.Run("update-block-index-to-the-top-of-the-note", func(t *testing.T) {
+		note := newTestNote(t, tu, edouardGroup, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Anaphase"}},
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Télophase"}},
+		})
+		stored, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: edouardGroup.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		telophase := stored.Note.Blocks[1]
+
+		_, err = tu.notes.UpdateBlockIndex(maxime.Context, &notesv1.UpdateBlockIndexRequest{
+			GroupId: edouardGroup.ID,
+			NoteId:  note.ID,
+			BlockId: telophase.Id,
+			Index:   0,
+		})
+		require.NoError(t, err)
+
+		moved, err := tu.notesRepository.GetNote(context.TODO(), &models.OneNoteFilter{GroupID: edouardGroup.ID, NoteID: note.ID}, maxime.ID)
+		require.NoError(t, err)
+		require.Equal(t, telophase.Id, (*moved.Blocks)[0].ID)
+	})
+
+	t.Run("update-block-index-of-commented-block-at-stale-revision-is-aborted", func(t *testing.T) {
+		note := newTestNote(t, tu, edouardGroup, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Interphase"}},
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Cytocinèse"}},
+		})
+		stored, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: edouardGroup.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		interphase := stored.Note.Blocks[0]
+
+		_, err = tu.notes.CreateBlockComment(edouard.Context, &notesv1.CreateBlockCommentRequest{
+			GroupId: edouardGroup.ID,
+			NoteId:  note.ID,
+			BlockId: interphase.Id,
+			Comment: &notesv1.Block_Comment{Content: "Et la phase G1 ?"},
+		})
+		require.NoError(t, err)
+
+		_, err = tu.notes.UpdateBlockIndex(maxime.Context, &notesv1.UpdateBlockIndexRequest{
+			GroupId:          edouardGroup.ID,
+			NoteId:           note.ID,
+			BlockId:          interphase.Id,
+			Index:            1,
+			ExpectedRevision: interphase.Revision,
+		})
+		requireErrorHasGRPCCode(t, codes.Aborted, err)
+
+		unmoved, err := tu.notesRepository.GetNote(context.TODO(), &models.OneNoteFilter{GroupID: edouardGroup.ID, NoteID: note.ID}, maxime.ID)
+		require.NoError(t, err)
+		require.Equal(t, interphase.Id, (*unmoved.Blocks)[0].ID)
+		require.Len(t, *(*unmoved.Blocks)[0].Thread, 1)
+	})
+
+	t.Run("update-block-index-moves-a-heading-with-its-blocks", func(t *testing.T) {
+		note := newTestNote(t, tu, edouardGroup, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_HEADING_1, Data: &notesv1.Block_Heading{Heading: "Mitose"}},
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Division cellulaire"}},
+			{Type: notesv1.Block_TYPE_HEADING_1, Data: &notesv1.Block_Heading{Heading: "Méiose"}},
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Cellules sexuelles"}},
+		})
+		stored, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: edouardGroup.ID, NoteId: note.ID})
+		require.NoError(t, err)
+
+		_, err = tu.notes.UpdateBlockIndex(maxime.Context, &notesv1.UpdateBlockIndexRequest{
+			GroupId: edouardGroup.ID,
+			NoteId:  note.ID,
+			BlockId: stored.Note.Blocks[2].Id,
+			Count:   2,
+			Index:   0,
+		})
+		require.NoError(t, err)
+
+		moved, err := tu.notesRepository.GetNote(context.TODO(), &models.OneNoteFilter{GroupID: edouardGroup.ID, NoteID: note.ID}, maxime.ID)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, block := range *moved.Blocks {
+			ids = append(ids, block.ID)
+		}
+		require.Equal(t, []string{stored.Note.Blocks[2].Id, stored.Note.Blocks[3].Id, stored.Note.Blocks[0].Id, stored.Note.Blocks[1].Id}, ids)
+	})
 }
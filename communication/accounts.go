@@ -3,6 +3,7 @@ package communication
 import (
 	accountsv1 "notes-service/protorepo/noted/accounts/v1"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -24,7 +25,10 @@ func NewAccountsServiceClient(address string) (*AccountsServiceClient, error) {
 }
 
 func (c *AccountsServiceClient) Init(address string) error {
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,343 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// The Anki collection format is documented at
+// https://github.com/ankitects/anki/blob/main/rslib/src/storage/schema11.sql
+
+// ID of the note type of the exported cards, it never changes so that Anki
+// reuses it across imports.
+const ankiModelID = 1617349921
+
+const ankiCollectionSchema = `
+CREATE TABLE col (
+	id integer PRIMARY KEY, crt integer NOT NULL, mod integer NOT NULL,
+	scm integer NOT NULL, ver integer NOT NULL, dty integer NOT NULL,
+	usn integer NOT NULL, ls integer NOT NULL, conf text NOT NULL,
+	models text NOT NULL, decks text NOT NULL, dconf text NOT NULL,
+	tags text NOT NULL
+);
+CREATE TABLE notes (
+	id integer PRIMARY KEY, guid text NOT NULL, mid integer NOT NULL,
+	mod integer NOT NULL, usn integer NOT NULL, tags text NOT NULL,
+	flds text NOT NULL, sfld integer NOT NULL, csum integer NOT NULL,
+	flags integer NOT NULL, data text NOT NULL
+);
+CREATE TABLE cards (
+	id integer PRIMARY KEY, nid integer NOT NULL, did integer NOT NULL,
+	ord integer NOT NULL, mod integer NOT NULL, usn integer NOT NULL,
+	type integer NOT NULL, queue integer NOT NULL, due integer NOT NULL,
+	ivl integer NOT NULL, factor integer NOT NULL, reps integer NOT NULL,
+	lapses integer NOT NULL, left integer NOT NULL, odue integer NOT NULL,
+	odid integer NOT NULL, flags integer NOT NULL, data text NOT NULL
+);
+CREATE TABLE revlog (
+	id integer PRIMARY KEY, cid integer NOT NULL, usn integer NOT NULL,
+	ease integer NOT NULL, ivl integer NOT NULL, lastIvl integer NOT NULL,
+	factor integer NOT NULL, time integer NOT NULL, type integer NOT NULL
+);
+CREATE TABLE graves (usn integer NOT NULL, oid integer NOT NULL, type integer NOT NULL);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+const ankiCardCSS = `.card {
+	font-family: arial;
+	font-size: 20px;
+	text-align: center;
+	color: black;
+	background-color: white;
+}
+.answers {
+	display: inline-block;
+	text-align: left;
+}
+`
+
+// QuizDeckToAnki writes the deck as an Anki package (.apkg), each question
+// being a card showing the answers on its front and the solutions on its back.
+func QuizDeckToAnki(deck *QuizDeck) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "anki")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	collectionPath := filepath.Join(dir, "collection.anki2")
+	err = writeAnkiCollection(collectionPath, deck, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := os.ReadFile(collectionPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"collection.anki2", collection},
+		// The cards don't embed any media.
+		{"media", []byte("{}")},
+	}
+	for _, file := range files {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = writer.Write(file.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeAnkiCollection(path string, deck *QuizDeck, now time.Time) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(ankiCollectionSchema)
+	if err != nil {
+		return err
+	}
+
+	nowMillis := now.UnixMilli()
+	deckID := ankiDeckID(deck.NoteID)
+
+	models, err := json.Marshal(map[string]interface{}{
+		strconv.Itoa(ankiModelID): ankiModel(deckID, now),
+	})
+	if err != nil {
+		return err
+	}
+	decks, err := json.Marshal(map[string]interface{}{
+		"1":                           ankiDeck(1, "Default", now),
+		strconv.FormatInt(deckID, 10): ankiDeck(deckID, deck.deckName(), now),
+	})
+	if err != nil {
+		return err
+	}
+	conf, err := json.Marshal(ankiCollectionConf(deckID))
+	if err != nil {
+		return err
+	}
+	dconf, err := json.Marshal(map[string]interface{}{"1": ankiDeckConf()})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		now.Truncate(24*time.Hour).Unix(), nowMillis, nowMillis, string(conf), string(models), string(decks), string(dconf))
+	if err != nil {
+		return err
+	}
+
+	tags := ""
+	if cardTags := deck.cardTags(); len(cardTags) > 0 {
+		tags = " " + strings.Join(cardTags, " ") + " "
+	}
+
+	// Notes and cards are identified by their creation time in milliseconds.
+	position := int64(0)
+	for _, quiz := range deck.Quizzes {
+		for i, question := range quiz.Questions {
+			position++
+			id := nowMillis + position
+
+			fields := []string{
+				html.EscapeString(question.Question),
+				ankiList(question.Answers),
+				ankiList(question.Solutions),
+			}
+			_, err = tx.Exec(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+				id, ankiGUID(deck.NoteID, quiz.Id, i), ankiModelID, now.Unix(), tags,
+				strings.Join(fields, "\x1f"), question.Question, ankiChecksum(question.Question))
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+				id, id, deckID, now.Unix(), position)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ankiList renders the items as an HTML list.
+func ankiList(items []string) string {
+	var result strings.Builder
+	result.WriteString("<ul>")
+	for _, item := range items {
+		result.WriteString("<li>" + html.EscapeString(item) + "</li>")
+	}
+	result.WriteString("</ul>")
+	return result.String()
+}
+
+// ankiDeckID derives the ID of the deck from the note so that exporting the
+// same note twice fills the same deck.
+func ankiDeckID(noteID string) int64 {
+	sum := sha256.Sum256([]byte("deck/" + noteID))
+	return 1<<30 + int64(binary.BigEndian.Uint32(sum[:4])>>2)
+}
+
+// ankiGUID identifies a question across exports, Anki updates the cards it
+// already knows instead of duplicating them.
+func ankiGUID(noteID string, quizID string, index int) string {
+	sum := sha256.Sum256([]byte(noteID + "/" + quizID + "/" + strconv.Itoa(index)))
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// ankiChecksum is the number made of the first 8 hex digits of the SHA-1 of
+// the sort field, used by Anki to find duplicates.
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+func ankiModel(deckID int64, now time.Time) map[string]interface{} {
+	field := func(name string, ord int) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name, "ord": ord, "font": "Arial", "size": 20,
+			"media": []string{}, "rtl": false, "sticky": false,
+		}
+	}
+
+	return map[string]interface{}{
+		"id":    ankiModelID,
+		"name":  "Noted Quiz",
+		"type":  0,
+		"mod":   now.Unix(),
+		"usn":   -1,
+		"did":   deckID,
+		"sortf": 0,
+		"flds": []interface{}{
+			field("Question", 0),
+			field("Answers", 1),
+			field("Solutions", 2),
+		},
+		"tmpls": []interface{}{
+			map[string]interface{}{
+				"name":  "Card 1",
+				"ord":   0,
+				"qfmt":  `{{Question}}<div class="answers">{{Answers}}</div>`,
+				"afmt":  `{{FrontSide}}<hr id=answer><div class="answers">{{Solutions}}</div>`,
+				"bqfmt": "",
+				"bafmt": "",
+				"did":   nil,
+			},
+		},
+		"css":       ankiCardCSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"req":       []interface{}{[]interface{}{0, "any", []int{0}}},
+		"tags":      []string{},
+		"vers":      []interface{}{},
+	}
+}
+
+func ankiDeck(id int64, name string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        id,
+		"name":      name,
+		"desc":      "",
+		"mod":       now.Unix(),
+		"usn":       -1,
+		"conf":      1,
+		"dyn":       0,
+		"collapsed": false,
+		"extendNew": 0,
+		"extendRev": 50,
+		"newToday":  []int{0, 0},
+		"revToday":  []int{0, 0},
+		"lrnToday":  []int{0, 0},
+		"timeToday": []int{0, 0},
+	}
+}
+
+func ankiCollectionConf(deckID int64) map[string]interface{} {
+	return map[string]interface{}{
+		"activeDecks":   []int64{deckID},
+		"curDeck":       deckID,
+		"curModel":      strconv.Itoa(ankiModelID),
+		"newSpread":     0,
+		"collapseTime":  1200,
+		"timeLim":       0,
+		"estTimes":      true,
+		"dueCounts":     true,
+		"nextPos":       1,
+		"sortType":      "noteFld",
+		"sortBackwards": false,
+		"addToCur":      true,
+	}
+}
+
+func ankiDeckConf() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       1,
+		"name":     "Default",
+		"mod":      0,
+		"usn":      0,
+		"maxTaken": 60,
+		"autoplay": true,
+		"replayq":  true,
+		"timer":    0,
+		"new": map[string]interface{}{
+			"bury": true, "delays": []int{1, 10}, "initialFactor": 2500,
+			"ints": []int{1, 4, 7}, "order": 1, "perDay": 20, "separate": true,
+		},
+		"rev": map[string]interface{}{
+			"bury": true, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1,
+			"maxIvl": 36500, "minSpace": 1, "perDay": 100,
+		},
+		"lapse": map[string]interface{}{
+			"delays": []int{10}, "leechAction": 0, "leechFails": 8,
+			"minInt": 1, "mult": 0,
+		},
+	}
+}
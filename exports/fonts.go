@@ -0,0 +1,37 @@
+package exports
+
+import (
+	_ "embed"
+
+	"github.com/phpdave11/gofpdf"
+)
+
+// The standard PDF fonts only cover latin alphabets, the DejaVu fonts are
+// embedded so that the notes written in any script are readable.
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	dejaVuSans []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	dejaVuSansBold []byte
+	//go:embed fonts/DejaVuSansCondensed-Oblique.ttf
+	dejaVuSansOblique []byte
+	//go:embed fonts/DejaVuSansCondensed-BoldOblique.ttf
+	dejaVuSansBoldOblique []byte
+	//go:embed fonts/DejaVuSansMono.ttf
+	dejaVuSansMono []byte
+	//go:embed fonts/DejaVuSansMono-Bold.ttf
+	dejaVuSansMonoBold []byte
+)
+
+// addPDFFonts registers the fonts used by the PDF exports. There is no oblique
+// monospace font, the italic code is written straight.
+func addPDFFonts(pdf *gofpdf.Fpdf) {
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "", dejaVuSans)
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "B", dejaVuSansBold)
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "I", dejaVuSansOblique)
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "BI", dejaVuSansBoldOblique)
+	pdf.AddUTF8FontFromBytes(pdfCodeFontFamily, "", dejaVuSansMono)
+	pdf.AddUTF8FontFromBytes(pdfCodeFontFamily, "B", dejaVuSansMonoBold)
+	pdf.AddUTF8FontFromBytes(pdfCodeFontFamily, "I", dejaVuSansMono)
+	pdf.AddUTF8FontFromBytes(pdfCodeFontFamily, "BI", dejaVuSansMonoBold)
+}
@@ -0,0 +1,303 @@
+package exports
+
+import (
+	"fmt"
+	"html"
+	notespb "notes-service/protorepo/noted/notes/v1"
+	"strconv"
+	"strings"
+)
+
+const htmlHead = `<!DOCTYPE html>
+<html lang="%s">
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/katex@0.16.9/dist/katex.min.css">
+<script defer src="https://cdn.jsdelivr.net/npm/katex@0.16.9/dist/katex.min.js"></script>
+<script defer src="https://cdn.jsdelivr.net/npm/katex@0.16.9/dist/contrib/auto-render.min.js" onload="renderMathInElement(document.body)"></script>
+</head>
+<body>
+<article class="note">
+<h1 class="note-title">%s</h1>
+`
+
+const htmlFoot = `</article>
+</body>
+</html>
+`
+
+// htmlFootnotes numbers the comment threads of the blocks as they are rendered.
+type htmlFootnotes struct {
+	comments []*notespb.Block_Comment
+}
+
+// references returns the footnote references of the thread of the block.
+func (f *htmlFootnotes) references(b *notespb.Block) string {
+	result := ""
+	for _, comment := range b.Thread {
+		f.comments = append(f.comments, comment)
+		n := strconv.Itoa(len(f.comments))
+		result += `<sup class="footnote-ref"><a href="#fn-` + n + `" id="fnref-` + n + `">` + n + `</a></sup>`
+	}
+	return result
+}
+
+func (f *htmlFootnotes) section() string {
+	if len(f.comments) == 0 {
+		return ""
+	}
+	result := "<section class=\"footnotes\">\n<ol>\n"
+	for i, comment := range f.comments {
+		n := strconv.Itoa(i + 1)
+		result += `<li id="fn-` + n + `" data-author-id="` + html.EscapeString(comment.AuthorId) + `">` +
+			textToHTML(comment.Content) + ` <a href="#fnref-` + n + `" class="footnote-backref">↩</a></li>` + "\n"
+	}
+	return result + "</ol>\n</section>\n"
+}
+
+// textToHTML escapes the text and keeps its line breaks.
+func textToHTML(text string) string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// styledTextToHTML escapes the text and wraps the ranges covered by the styles
+// of the block in the matching tags. Each segment is wrapped on its own so that
+// tags never overlap.
+func styledTextToHTML(text string, styles []*notespb.Block_TextStyle) string {
+	result := ""
+	for _, segment := range styledSegments(text, styles) {
+		open, close := "", ""
+		for _, style := range segment.styles {
+			styleOpen, styleClose := styleTags(style)
+			open += styleOpen
+			close = styleClose + close
+		}
+		result += open + textToHTML(segment.text) + close
+	}
+	return result
+}
+
+// styleTags returns the opening and closing tags of the style, colors are
+// rendered as spans.
+func styleTags(style *notespb.Block_TextStyle) (string, string) {
+	open, close := "", ""
+	switch style.Style {
+	case notespb.Block_TextStyle_STYLE_BOLD:
+		open, close = "<strong>", "</strong>"
+	case notespb.Block_TextStyle_STYLE_ITALIC:
+		open, close = "<em>", "</em>"
+	case notespb.Block_TextStyle_STYLE_UNDERLINE:
+		open, close = "<u>", "</u>"
+	case notespb.Block_TextStyle_STYLE_STRIKETHROUGH:
+		open, close = "<s>", "</s>"
+	}
+	if style.Color != nil {
+		open = fmt.Sprintf(`<span style="color: rgb(%d, %d, %d)">`, style.Color.R, style.Color.G, style.Color.B) + open
+		close = close + "</span>"
+	}
+	return open, close
+}
+
+func headingBlockToHTML(b *notespb.Block, refs string) string {
+	// The title of the note is the only h1 of the document.
+	level := 2
+	switch b.Type {
+	case notespb.Block_TYPE_HEADING_2:
+		level = 3
+	case notespb.Block_TYPE_HEADING_3:
+		level = 4
+	}
+	tag := "h" + strconv.Itoa(level)
+	return "<" + tag + ">" + styledTextToHTML(b.GetHeading(), b.Styles) + refs + "</" + tag + ">\n"
+}
+
+func codeBlockToHTML(b *notespb.Block) string {
+	code := b.GetCode()
+	class := ""
+	if code.Lang != "" {
+		class = ` class="language-` + html.EscapeString(code.Lang) + `"`
+	}
+	return "<pre><code" + class + ">" + html.EscapeString(strings.ReplaceAll(code.Snippet, "\r\n", "\n")) + "</code></pre>\n"
+}
+
+func imageBlockToHTML(b *notespb.Block, refs string) string {
+	image := b.GetImage()
+	result := `<figure><img src="` + html.EscapeString(image.Url) + `" alt="` + html.EscapeString(image.Caption) + `">`
+	if image.Caption != "" || refs != "" {
+		result += "<figcaption>" + textToHTML(image.Caption) + refs + "</figcaption>"
+	}
+	return result + "</figure>\n"
+}
+
+func mathBlockToHTML(b *notespb.Block) string {
+	return `<div class="math math-display">\[` + html.EscapeString(strings.TrimSpace(b.GetMath())) + `\]</div>` + "\n"
+}
+
+func summaryBlockToHTML(b *notespb.Block, refs string) string {
+	return `<blockquote class="summary">` + textToHTML(b.GetSummary()) + refs + "</blockquote>\n"
+}
+
+func checklistItemBlockToHTML(b *notespb.Block, refs string) string {
+	item := b.GetChecklistItem()
+	attributes := ""
+	if item.AssigneeAccountId != "" {
+		attributes = ` data-assignee-id="` + html.EscapeString(item.AssigneeAccountId) + `"`
+	}
+	checked := ""
+	if item.Checked {
+		checked = " checked"
+	}
+	return "<li" + attributes + `><input type="checkbox" disabled` + checked + "> " + styledTextToHTML(item.Text, b.Styles) + refs + "</li>\n"
+}
+
+func tableRowToHTML(row *notespb.Block_Table_Row, header bool) string {
+	result := "<tr>"
+	for _, cell := range row.Cells {
+		tag, class := "td", ""
+		if header {
+			tag = "th"
+		} else if cell.Type == notespb.Block_Table_Cell_TYPE_NUMBER {
+			class = ` class="number"`
+		}
+		result += "<" + tag + class + ">" + textToHTML(cell.Value) + "</" + tag + ">"
+	}
+	return result + "</tr>\n"
+}
+
+func tableBlockToHTML(b *notespb.Block) string {
+	table := b.GetTable()
+	rows := table.Rows
+
+	result := "<table>\n"
+	if table.HasHeaderRow && len(rows) > 0 {
+		result += "<thead>\n" + tableRowToHTML(rows[0], true) + "</thead>\n"
+		rows = rows[1:]
+	}
+	if len(rows) > 0 {
+		result += "<tbody>\n"
+		for _, row := range rows {
+			result += tableRowToHTML(row, false)
+		}
+		result += "</tbody>\n"
+	}
+	return result + "</table>\n"
+}
+
+var calloutToneToHTMLClass = map[notespb.Block_Callout_Tone]string{
+	notespb.Block_Callout_TONE_INFO:    "callout-info",
+	notespb.Block_Callout_TONE_WARNING: "callout-warning",
+	notespb.Block_Callout_TONE_TIP:     "callout-tip",
+}
+
+func calloutBlockToHTML(b *notespb.Block, refs string) string {
+	callout := b.GetCallout()
+	class, ok := calloutToneToHTMLClass[callout.Tone]
+	if !ok {
+		class = calloutToneToHTMLClass[notespb.Block_Callout_TONE_INFO]
+	}
+	return `<aside class="callout ` + class + `">` + styledTextToHTML(callout.Text, b.Styles) + refs + "</aside>\n"
+}
+
+func embedBlockToHTML(b *notespb.Block, refs string) string {
+	embed := b.GetEmbed()
+	text := embed.Title
+	if text == "" {
+		text = embed.Url
+	}
+	return `<p class="embed"><a href="` + html.EscapeString(embed.Url) + `">` + textToHTML(text) + "</a>" + refs + "</p>\n"
+}
+
+// listTag returns the tag of the list the block belongs to, if any.
+func listTag(b *notespb.Block) string {
+	switch b.Data.(type) {
+	case *notespb.Block_BulletPoint:
+		return "ul"
+	case *notespb.Block_NumberPoint:
+		return "ol"
+	case *notespb.Block_ChecklistItem_:
+		return `ul class="checklist"`
+	}
+	return ""
+}
+
+// NoteToHTML renders the note as a standalone HTML document. The threads of
+// the blocks, when present, are rendered as footnotes.
+func NoteToHTML(n *notespb.Note) ([]byte, error) {
+	lang := n.Lang
+	if lang == "" {
+		lang = "fr"
+	}
+	title := html.EscapeString(n.Title)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf(htmlHead, html.EscapeString(lang), title, title))
+
+	footnotes := &htmlFootnotes{}
+	list := ""
+
+	for _, block := range n.Blocks {
+		// Consecutive points are grouped in the same list.
+		if tag := listTag(block); tag != list {
+			if list != "" {
+				result.WriteString("</" + strings.Fields(list)[0] + ">\n")
+			}
+			if tag != "" {
+				result.WriteString("<" + tag + ">\n")
+			}
+			list = tag
+		}
+
+		refs := footnotes.references(block)
+
+		switch op := block.Data.(type) {
+		case *notespb.Block_Heading:
+			result.WriteString(headingBlockToHTML(block, refs))
+		case *notespb.Block_Paragraph:
+			result.WriteString("<p>" + styledTextToHTML(op.Paragraph, block.Styles) + refs + "</p>\n")
+		case *notespb.Block_NumberPoint:
+			result.WriteString("<li>" + styledTextToHTML(op.NumberPoint, block.Styles) + refs + "</li>\n")
+		case *notespb.Block_BulletPoint:
+			result.WriteString("<li>" + styledTextToHTML(op.BulletPoint, block.Styles) + refs + "</li>\n")
+		case *notespb.Block_Math:
+			result.WriteString(mathBlockToHTML(block))
+			if refs != "" {
+				result.WriteString(`<p class="footnote-refs">` + refs + "</p>\n")
+			}
+		case *notespb.Block_Code_:
+			result.WriteString(codeBlockToHTML(block))
+			if refs != "" {
+				result.WriteString(`<p class="footnote-refs">` + refs + "</p>\n")
+			}
+		case *notespb.Block_Image_:
+			result.WriteString(imageBlockToHTML(block, refs))
+		case *notespb.Block_Summary:
+			result.WriteString(summaryBlockToHTML(block, refs))
+		case *notespb.Block_ChecklistItem_:
+			result.WriteString(checklistItemBlockToHTML(block, refs))
+		case *notespb.Block_Table_:
+			result.WriteString(tableBlockToHTML(block))
+			if refs != "" {
+				result.WriteString(`<p class="footnote-refs">` + refs + "</p>\n")
+			}
+		case *notespb.Block_Callout_:
+			result.WriteString(calloutBlockToHTML(block, refs))
+		case *notespb.Block_Embed_:
+			result.WriteString(embedBlockToHTML(block, refs))
+		default:
+			// Dividers are the only blocks without data.
+			if block.Type == notespb.Block_TYPE_DIVIDER {
+				result.WriteString("<hr>\n")
+			}
+		}
+	}
+	if list != "" {
+		result.WriteString("</" + strings.Fields(list)[0] + ">\n")
+	}
+
+	result.WriteString(footnotes.section())
+	result.WriteString(htmlFoot)
+
+	return []byte(result.String()), nil
+}
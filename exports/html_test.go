@@ -0,0 +1,84 @@
+package exports_test
+
+import (
+	"notes-service/exports"
+	notespb "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NoteToHTML(t *testing.T) {
+	// Given
+	note := &notespb.Note{
+		Title: "Les <chats>",
+		Lang:  "fr",
+		Blocks: []*notespb.Block{
+			{Type: notespb.Block_TYPE_HEADING_1, Data: &notespb.Block_Heading{Heading: "Définition"}},
+			{
+				Type: notespb.Block_TYPE_PARAGRAPH,
+				Data: &notespb.Block_Paragraph{Paragraph: "Le chat est noir"},
+				Styles: []*notespb.Block_TextStyle{
+					{Style: notespb.Block_TextStyle_STYLE_BOLD, Pos: &notespb.Block_TextStyle_Position{Start: 3, Length: 8}},
+					{Style: notespb.Block_TextStyle_STYLE_ITALIC, Pos: &notespb.Block_TextStyle_Position{Start: 8, Length: 8}},
+					{Pos: &notespb.Block_TextStyle_Position{Start: 12, Length: 4}, Color: &notespb.Block_TextStyle_Color{R: 255}},
+				},
+				Thread: []*notespb.Block_Comment{{Id: "c1", AuthorId: "edouard", Content: "Pas toujours"}},
+			},
+			{Type: notespb.Block_TYPE_BULLET_POINT, Data: &notespb.Block_BulletPoint{BulletPoint: "Siamois"}},
+			{Type: notespb.Block_TYPE_BULLET_POINT, Data: &notespb.Block_BulletPoint{BulletPoint: "Persan"}},
+			{Type: notespb.Block_TYPE_CODE, Data: &notespb.Block_Code_{Code: &notespb.Block_Code{Snippet: "a < b", Lang: "go"}}},
+			{Type: notespb.Block_TYPE_IMAGE, Data: &notespb.Block_Image_{Image: &notespb.Block_Image{Url: "https://noted.fr/chat.png", Caption: "Un chat"}}},
+			{Type: notespb.Block_TYPE_MATH, Data: &notespb.Block_Math{Math: "E = mc^2"}},
+		},
+	}
+
+	// When
+	result, err := exports.NoteToHTML(note)
+	require.NoError(t, err)
+	document := string(result)
+
+	// Then
+	require.Contains(t, document, `<h1 class="note-title">Les &lt;chats&gt;</h1>`)
+	require.Contains(t, document, "<h2>Définition</h2>")
+	require.Contains(t, document, `<p>Le <strong>chat </strong><strong><em>est</em></strong><em> </em><em><span style="color: rgb(255, 0, 0)">noir</span></em>`+
+		`<sup class="footnote-ref"><a href="#fn-1" id="fnref-1">1</a></sup></p>`, "overlapping styles should never overlap tags")
+	require.Contains(t, document, "<ul>\n<li>Siamois</li>\n<li>Persan</li>\n</ul>", "consecutive points should be in the same list")
+	require.Contains(t, document, `<pre><code class="language-go">a &lt; b</code></pre>`)
+	require.Contains(t, document, `<figure><img src="https://noted.fr/chat.png" alt="Un chat"><figcaption>Un chat</figcaption></figure>`)
+	require.Contains(t, document, `<div class="math math-display">\[E = mc^2\]</div>`)
+	require.Contains(t, document, `<li id="fn-1" data-author-id="edouard">Pas toujours`)
+}
+
+func Test_NoteToHTML_WithoutThreads(t *testing.T) {
+	// Given
+	note := &notespb.Note{
+		Title:  "Les chats",
+		Blocks: []*notespb.Block{{Type: notespb.Block_TYPE_PARAGRAPH, Data: &notespb.Block_Paragraph{Paragraph: "Le chat est noir"}}},
+	}
+
+	// When
+	result, err := exports.NoteToHTML(note)
+	require.NoError(t, err)
+
+	// Then
+	require.NotContains(t, string(result), "footnotes")
+}
+
+func Test_NoteToHTML_StructuredBlocks(t *testing.T) {
+	// When
+	result, err := exports.NoteToHTML(newTestStructuredBlocksNote())
+	require.NoError(t, err)
+	document := string(result)
+
+	// Then
+	require.Contains(t, document, "<ul class=\"checklist\">\n"+
+		`<li data-assignee-id="edouard"><input type="checkbox" disabled checked> Nourrir le chat</li>`+"\n"+
+		`<li><input type="checkbox" disabled> Brosser le chat</li>`+"\n"+
+		"</ul>\n", "consecutive checklist items should be in the same list")
+	require.Contains(t, document, "<table>\n<thead>\n<tr><th>Race</th><th>Poids</th></tr>\n</thead>\n"+
+		"<tbody>\n<tr><td>Siamois | Thaï</td><td class=\"number\">4.5</td></tr>\n</tbody>\n</table>\n")
+	require.Contains(t, document, `<aside class="callout callout-warning">Ne pas<br>mouiller</aside>`)
+	require.Contains(t, document, "<hr>\n")
+	require.Contains(t, document, `<p class="embed"><a href="https://fr.wikipedia.org/wiki/Chat">Chat</a></p>`)
+}
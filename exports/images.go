@@ -0,0 +1,129 @@
+package exports
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ImageFetcher returns the content of the image at url.
+type ImageFetcher func(ctx context.Context, url string) ([]byte, error)
+
+// Maximum size of an image embedded in a PDF.
+const maxImageSize = 10 << 20
+
+// Maximum number of redirects followed to fetch an image.
+const maxImageRedirects = 5
+
+var errForbiddenImageAddress = errors.New("images can't be fetched from a private address")
+
+// imageHTTPClient only reaches public addresses: the urls of the images are
+// given by the users, they must not be able to make the service request its
+// own network.
+var imageHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		// A proxy would resolve the address instead of the dialer.
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: checkImageAddress,
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxImageRedirects {
+			return errors.New("too many redirects")
+		}
+		return checkImageURL(req.URL)
+	},
+}
+
+// FetchImage downloads the image at url. Data urls are decoded without any
+// request.
+func FetchImage(ctx context.Context, rawURL string) ([]byte, error) {
+	if strings.HasPrefix(rawURL, "data:") {
+		header, data, ok := strings.Cut(strings.TrimPrefix(rawURL, "data:"), ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, errors.New("only base64 data urls are supported")
+		}
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	err = checkImageURL(u)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := imageHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch image: %s", res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, errors.New("image is too large")
+	}
+
+	return data, nil
+}
+
+// checkImageURL only accepts the http and https urls, it is called again on
+// every redirect.
+func checkImageURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("images can't be fetched with the %q scheme", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("image url has no host")
+	}
+	return nil
+}
+
+// checkImageAddress is called by the dialer once the host is resolved, so it
+// also rejects the public names pointing to a private address.
+func checkImageAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errForbiddenImageAddress
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return errForbiddenImageAddress
+	}
+
+	return nil
+}
@@ -1,13 +1,14 @@
 package exports
 
 import (
+	"fmt"
 	notespb "notes-service/protorepo/noted/notes/v1"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 func headingBlockToMarkdown(b *notespb.Block) (string, error) {
-	heading := b.GetHeading()
 	typeName := b.Type.String()
 	typeNameSplitted := strings.Split(typeName, "_")
 	importance, err := strconv.Atoi(typeNameSplitted[len(typeNameSplitted)-1])
@@ -16,7 +17,9 @@ func headingBlockToMarkdown(b *notespb.Block) (string, error) {
 		return "", err
 	}
 
-	result := strings.Repeat("#", importance) + " " + heading
+	// A heading can't span several lines.
+	heading := strings.ReplaceAll(strings.ReplaceAll(b.GetHeading(), "\r\n", " "), "\n", " ")
+	result := strings.Repeat("#", importance) + " " + styledTextToMarkdown(heading, b.Styles)
 
 	sanitizeNewLines(&result)
 
@@ -25,15 +28,124 @@ func headingBlockToMarkdown(b *notespb.Block) (string, error) {
 
 func codeBlockToMarkdown(b *notespb.Block) string {
 	codeData := b.GetCode()
-	result := "```" + codeData.Lang + "\n" + codeData.Snippet
+
+	// The fence must be longer than any backquotes run of the snippet.
+	fence := "```"
+	for strings.Contains(codeData.Snippet, fence) {
+		fence += "`"
+	}
+
+	result := fence + codeData.Lang + "\n" + codeData.Snippet
 	sanitizeNewLines(&result)
-	result = result + "```\n"
+	result = result + fence + "\n"
 	return result
 }
 
 func imageBlockToMarkdown(b *notespb.Block) string {
 	imageData := b.GetImage()
-	return "![](" + imageData.Url + " " + imageData.Caption + ")\n"
+	return "![" + escapeMarkdown(imageData.Caption) + "](<" + imageData.Url + ">)\n"
+}
+
+func mathBlockToMarkdown(b *notespb.Block) string {
+	math := strings.TrimSpace(b.GetMath())
+	sanitizeNewLines(&math)
+	return "$$\n" + math + "$$\n"
+}
+
+func summaryBlockToMarkdown(b *notespb.Block) string {
+	summary := b.GetSummary()
+	sanitizeNewLines(&summary)
+	lines := strings.Split(strings.TrimSuffix(summary, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = escapeMarkdown(line)
+	}
+	return "> " + strings.Join(lines, "\n> ") + "\n"
+}
+
+func checklistItemBlockToMarkdown(b *notespb.Block) string {
+	item := b.GetChecklistItem()
+	marker := "- [ ]"
+	if item.Checked {
+		marker = "- [x]"
+	}
+	return listItemToMarkdown(marker, b, item.Text)
+}
+
+// Markdown callouts are the blockquote alerts of GitHub.
+var calloutToneToMarkdownAlert = map[notespb.Block_Callout_Tone]string{
+	notespb.Block_Callout_TONE_INFO:    "NOTE",
+	notespb.Block_Callout_TONE_WARNING: "WARNING",
+	notespb.Block_Callout_TONE_TIP:     "TIP",
+}
+
+func calloutBlockToMarkdown(b *notespb.Block) string {
+	callout := b.GetCallout()
+	alert, ok := calloutToneToMarkdownAlert[callout.Tone]
+	if !ok {
+		alert = "NOTE"
+	}
+	text := strings.TrimSuffix(strings.ReplaceAll(callout.Text, "\r\n", "\n"), "\n")
+	lines := strings.Split(styledTextToMarkdown(text, b.Styles), "\n")
+	return "> [!" + alert + "]\n> " + strings.Join(lines, "\n> ") + "\n"
+}
+
+// tableCellToMarkdown escapes the value of the cell so that it stays on a
+// single line of its row.
+func tableCellToMarkdown(cell *notespb.Block_Table_Cell) string {
+	// Characters only meaningful at the start of a line are left as is.
+	value := markdownEscaper.Replace(strings.ReplaceAll(cell.Value, "\r\n", "\n"))
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
+
+// tableBlockToMarkdown writes the table as a GFM table. Markdown tables always
+// have a header row, an empty one is added when the table has none.
+func tableBlockToMarkdown(b *notespb.Block) string {
+	table := b.GetTable()
+	if len(table.Rows) == 0 {
+		return "\n"
+	}
+
+	columns := len(table.Rows[0].Cells)
+	row := func(cells []*notespb.Block_Table_Cell) string {
+		values := make([]string, columns)
+		for i := range values {
+			if i < len(cells) {
+				values[i] = tableCellToMarkdown(cells[i])
+			}
+		}
+		return "| " + strings.Join(values, " | ") + " |\n"
+	}
+
+	rows := table.Rows
+	result := row(nil)
+	if table.HasHeaderRow {
+		result = row(rows[0].Cells)
+		rows = rows[1:]
+	}
+
+	// Numbers are aligned on the right.
+	separators := make([]string, columns)
+	for i := range separators {
+		separators[i] = "---"
+		if len(rows) > 0 && i < len(rows[0].Cells) && rows[0].Cells[i].Type == notespb.Block_Table_Cell_TYPE_NUMBER {
+			separators[i] = "--:"
+		}
+	}
+	result += "| " + strings.Join(separators, " | ") + " |\n"
+
+	for _, r := range rows {
+		result += row(r.Cells)
+	}
+	return result
+}
+
+func embedBlockToMarkdown(b *notespb.Block) string {
+	embed := b.GetEmbed()
+	if embed.Title == "" {
+		return "<" + embed.Url + ">\n"
+	}
+	return "[" + escapeMarkdown(embed.Title) + "](<" + embed.Url + ">)\n"
 }
 
 // Replace every CLRF by a line feed and add a line feed at the end of the string
@@ -48,6 +160,102 @@ func sanitizeNewLines(str *string) {
 	}
 }
 
+var (
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, "~", `\~`, "&", `\&`,
+	)
+	// Characters which only have a meaning at the start of a line.
+	markdownLineStart = regexp.MustCompile(`(?m)^([ \t]*)([#>+=$-]|\d+[.)])`)
+)
+
+// escapeMarkdown escapes the characters of a plain text which Markdown would
+// interpret.
+func escapeMarkdown(text string) string {
+	text = markdownEscaper.Replace(text)
+	return markdownLineStart.ReplaceAllStringFunc(text, func(match string) string {
+		return match[:len(match)-1] + `\` + match[len(match)-1:]
+	})
+}
+
+// markdownStyleMarkers returns the markers opening and closing the styles.
+// Markdown has no underline nor color, they are written as inline HTML.
+func markdownStyleMarkers(styles []*notespb.Block_TextStyle) (string, string) {
+	open, close := "", ""
+	add := func(styleOpen string, styleClose string) {
+		open += styleOpen
+		close = styleClose + close
+	}
+
+	for _, style := range styles {
+		if style.Color != nil {
+			add(fmt.Sprintf(`<span style="color: rgb(%d, %d, %d)">`, style.Color.R, style.Color.G, style.Color.B), "</span>")
+		}
+	}
+	for _, style := range styles {
+		switch style.Style {
+		case notespb.Block_TextStyle_STYLE_UNDERLINE:
+			add("<u>", "</u>")
+		case notespb.Block_TextStyle_STYLE_STRIKETHROUGH:
+			add("~~", "~~")
+		}
+	}
+	for _, style := range styles {
+		switch style.Style {
+		case notespb.Block_TextStyle_STYLE_BOLD:
+			add("**", "**")
+		case notespb.Block_TextStyle_STYLE_ITALIC:
+			add("*", "*")
+		}
+	}
+
+	return open, close
+}
+
+// styledTextToMarkdown escapes the text and wraps the ranges covered by the
+// styles of the block in Markdown emphasis.
+func styledTextToMarkdown(text string, styles []*notespb.Block_TextStyle) string {
+	return applyMarkdownStyles(text, styles, escapeMarkdown)
+}
+
+// styledMarkdownToMarkdown wraps the ranges covered by the styles of the block
+// in Markdown emphasis, the text being already Markdown.
+func styledMarkdownToMarkdown(text string, styles []*notespb.Block_TextStyle) string {
+	return applyMarkdownStyles(text, styles, func(text string) string { return text })
+}
+
+func applyMarkdownStyles(text string, styles []*notespb.Block_TextStyle, escape func(string) string) string {
+	result := ""
+
+	for _, segment := range styledSegments(text, styles) {
+		open, close := markdownStyleMarkers(segment.styles)
+		trimmed := strings.TrimSpace(segment.text)
+		if open == "" || trimmed == "" {
+			result += escape(segment.text)
+			continue
+		}
+
+		// Emphasis can't start nor end with a whitespace.
+		leading := segment.text[:strings.Index(segment.text, trimmed)]
+		trailing := segment.text[len(leading)+len(trimmed):]
+		result += escape(leading) + open + escape(trimmed) + close + escape(trailing)
+	}
+
+	return result
+}
+
+// listItemToMarkdown writes a point, its next lines being indented to stay in
+// the item. Like paragraphs, the text of the points is already Markdown.
+func listItemToMarkdown(marker string, b *notespb.Block, text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	indent := strings.Repeat(" ", len(marker)+1)
+	result := marker + " " + strings.ReplaceAll(styledMarkdownToMarkdown(text, b.Styles), "\n", "\n"+indent)
+	sanitizeNewLines(&result)
+	return result
+}
+
+// NoteToMarkdown writes the blocks of the note as Markdown, separated by blank
+// lines. Paragraphs and points are already Markdown, the text of the other
+// blocks is escaped so that parsing the result gives back the same blocks.
 func NoteToMarkdown(n *notespb.Note) ([]byte, error) {
 	result := ""
 	var err error = nil
@@ -58,25 +266,41 @@ func NoteToMarkdown(n *notespb.Note) ([]byte, error) {
 		case *notespb.Block_Heading:
 			converted, err = headingBlockToMarkdown(block)
 		case *notespb.Block_Paragraph: // NOTE: Is already formatted as Markdown.
-			sanitizeNewLines(&op.Paragraph)
-			converted = op.Paragraph
-		case *notespb.Block_NumberPoint: // NOTE: Is already formatted as Markdown ?
-			sanitizeNewLines(&op.NumberPoint)
-			converted = op.NumberPoint + "\n"
-		case *notespb.Block_BulletPoint: // NOTE: Is already formatted as Markdown ?
-			sanitizeNewLines(&op.BulletPoint)
-			converted = op.BulletPoint + "\n"
+			converted = styledMarkdownToMarkdown(strings.ReplaceAll(op.Paragraph, "\r\n", "\n"), block.Styles)
+			sanitizeNewLines(&converted)
+		case *notespb.Block_NumberPoint:
+			converted = listItemToMarkdown("1.", block, op.NumberPoint)
+		case *notespb.Block_BulletPoint:
+			converted = listItemToMarkdown("-", block, op.BulletPoint)
 		case *notespb.Block_Math:
-			sanitizeNewLines(&op.Math)
-			converted = op.Math
+			converted = mathBlockToMarkdown(block)
 		case *notespb.Block_Code_:
 			converted = codeBlockToMarkdown(block)
 		case *notespb.Block_Image_:
 			converted = imageBlockToMarkdown(block)
+		case *notespb.Block_Summary:
+			converted = summaryBlockToMarkdown(block)
+		case *notespb.Block_ChecklistItem_:
+			converted = checklistItemBlockToMarkdown(block)
+		case *notespb.Block_Table_:
+			converted = tableBlockToMarkdown(block)
+		case *notespb.Block_Callout_:
+			converted = calloutBlockToMarkdown(block)
+		case *notespb.Block_Embed_:
+			converted = embedBlockToMarkdown(block)
+		default:
+			// Dividers are the only blocks without data.
+			if block.Type != notespb.Block_TYPE_DIVIDER {
+				continue
+			}
+			converted = "---\n"
 		}
 		if err != nil {
 			return nil, err
 		}
+		if result != "" {
+			result = result + "\n"
+		}
 		result = result + converted
 	}
 
@@ -0,0 +1,91 @@
+package exports_test
+
+import (
+	"notes-service/exports"
+	notespb "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStructuredBlocksNote() *notespb.Note {
+	return &notespb.Note{
+		Title: "Les chats",
+		Lang:  "fr",
+		Blocks: []*notespb.Block{
+			{Type: notespb.Block_TYPE_CHECKLIST_ITEM, Data: &notespb.Block_ChecklistItem_{ChecklistItem: &notespb.Block_ChecklistItem{Text: "Nourrir le chat", Checked: true, AssigneeAccountId: "edouard"}}},
+			{Type: notespb.Block_TYPE_CHECKLIST_ITEM, Data: &notespb.Block_ChecklistItem_{ChecklistItem: &notespb.Block_ChecklistItem{Text: "Brosser le chat"}}},
+			{Type: notespb.Block_TYPE_TABLE, Data: &notespb.Block_Table_{Table: &notespb.Block_Table{
+				HasHeaderRow: true,
+				Rows: []*notespb.Block_Table_Row{
+					{Cells: []*notespb.Block_Table_Cell{{Type: notespb.Block_Table_Cell_TYPE_TEXT, Value: "Race"}, {Type: notespb.Block_Table_Cell_TYPE_TEXT, Value: "Poids"}}},
+					{Cells: []*notespb.Block_Table_Cell{{Type: notespb.Block_Table_Cell_TYPE_TEXT, Value: "Siamois | Thaï"}, {Type: notespb.Block_Table_Cell_TYPE_NUMBER, Value: "4.5"}}},
+				},
+			}}},
+			{Type: notespb.Block_TYPE_CALLOUT, Data: &notespb.Block_Callout_{Callout: &notespb.Block_Callout{Tone: notespb.Block_Callout_TONE_WARNING, Text: "Ne pas\nmouiller"}}},
+			{Type: notespb.Block_TYPE_DIVIDER},
+			{Type: notespb.Block_TYPE_EMBED, Data: &notespb.Block_Embed_{Embed: &notespb.Block_Embed{Url: "https://fr.wikipedia.org/wiki/Chat", Title: "Chat"}}},
+		},
+	}
+}
+
+func Test_NoteToMarkdown_StructuredBlocks(t *testing.T) {
+	// When
+	result, err := exports.NoteToMarkdown(newTestStructuredBlocksNote())
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, "- [x] Nourrir le chat\n"+
+		"\n"+
+		"- [ ] Brosser le chat\n"+
+		"\n"+
+		"| Race | Poids |\n"+
+		"| --- | --: |\n"+
+		"| Siamois \\| Thaï | 4.5 |\n"+
+		"\n"+
+		"> [!WARNING]\n"+
+		"> Ne pas\n"+
+		"> mouiller\n"+
+		"\n"+
+		"---\n"+
+		"\n"+
+		"[Chat](<https://fr.wikipedia.org/wiki/Chat>)\n", string(result))
+}
+
+func Test_NoteToMarkdown_TableWithoutHeaderRow(t *testing.T) {
+	// Given
+	note := &notespb.Note{Blocks: []*notespb.Block{
+		{Type: notespb.Block_TYPE_TABLE, Data: &notespb.Block_Table_{Table: &notespb.Block_Table{
+			Rows: []*notespb.Block_Table_Row{
+				{Cells: []*notespb.Block_Table_Cell{{Type: notespb.Block_Table_Cell_TYPE_BOOLEAN, Value: "true"}}},
+			},
+		}}},
+	}}
+
+	// When
+	result, err := exports.NoteToMarkdown(note)
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, "|  |\n| --- |\n| true |\n", string(result), "markdown tables should always have a header row")
+}
+
+func Test_NoteToMarkdown_ParagraphsAreMarkdown(t *testing.T) {
+	// Given
+	note := &notespb.Note{Blocks: []*notespb.Block{
+		{Type: notespb.Block_TYPE_PARAGRAPH, Data: &notespb.Block_Paragraph{Paragraph: "Le **chat** est [noir](https://noted.fr)"}},
+		{Type: notespb.Block_TYPE_BULLET_POINT, Data: &notespb.Block_BulletPoint{BulletPoint: "Le *siamois*"}},
+		{Type: notespb.Block_TYPE_HEADING_1, Data: &notespb.Block_Heading{Heading: "Les *chats*"}},
+	}}
+
+	// When
+	result, err := exports.NoteToMarkdown(note)
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, "Le **chat** est [noir](https://noted.fr)\n"+
+		"\n"+
+		"- Le *siamois*\n"+
+		"\n"+
+		"# Les \\*chats\\*\n", string(result), "only the text of the other blocks should be escaped")
+}
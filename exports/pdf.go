@@ -2,27 +2,659 @@ package exports
 
 import (
 	"bytes"
+	"context"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	notespb "notes-service/protorepo/noted/notes/v1"
+	"strconv"
+	"strings"
 
-	"github.com/yuin/goldmark"
+	"github.com/phpdave11/gofpdf"
+)
 
-	pdf "github.com/edouard-sn/goldmark-pdf"
+// Layout of the PDF, in points.
+const (
+	pdfFontFamily     = "DejaVuSansCondensed"
+	pdfCodeFontFamily = "DejaVuSansMono"
+	pdfMargin         = 56
+	pdfFontSize       = 11
+	pdfLineHeight     = 15
+	pdfListIndent     = 18
 )
 
+var pdfContentsTitles = map[string]string{
+	"fr": "Sommaire",
+	"en": "Contents",
+	"es": "Índice",
+	"de": "Inhalt",
+}
+
+type PDFOptions struct {
+	// Displayed on the title page, defaults to the id of the author.
+	AuthorName string
+	GroupName  string
+	// Fetches the images of the note, defaults to FetchImage.
+	Images ImageFetcher
+}
+
+// pdfHeading is a heading of the note, listed in the table of contents.
+type pdfHeading struct {
+	number string
+	text   string
+	level  int
+	link   int
+	// Replaced by the page of the heading once the note is rendered.
+	pageAlias string
+	page      int
+}
+
+type pdfRenderer struct {
+	ctx      context.Context
+	pdf      *gofpdf.Fpdf
+	note     *notespb.Note
+	options  *PDFOptions
+	headings []*pdfHeading
+	images   int
+}
+
 func NoteToPDF(n *notespb.Note) ([]byte, error) {
-	markdownNote, err := NoteToMarkdown(n)
-	var result bytes.Buffer
+	return NoteToPDFWithOptions(context.Background(), n, nil)
+}
+
+// NoteToPDFWithOptions renders the note as a PDF document made of a title page,
+// a table of contents when the note has headings, and the blocks of the note.
+// Images which can't be fetched are replaced by their url.
+func NoteToPDFWithOptions(ctx context.Context, n *notespb.Note, options *PDFOptions) ([]byte, error) {
+	if options == nil {
+		options = &PDFOptions{}
+	}
+	if options.AuthorName == "" {
+		options.AuthorName = n.AuthorAccountId
+	}
+	if options.Images == nil {
+		options.Images = FetchImage
+	}
+
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle(n.Title, true)
+	pdf.SetAuthor(options.AuthorName, true)
+	pdf.SetCreator("Noted", true)
+	pdf.AliasNbPages("{nb}")
+	addPDFFonts(pdf)
+
+	r := &pdfRenderer{
+		ctx:     ctx,
+		pdf:     pdf,
+		note:    n,
+		options: options,
+	}
+
+	pdf.SetFooterFunc(r.footer)
 
+	r.numberHeadings()
+	r.titlePage()
+	if len(r.headings) > 0 {
+		r.tableOfContents()
+	}
+
+	pdf.AddPage()
+	r.blocks()
+
+	for _, heading := range r.headings {
+		pdf.RegisterAlias(heading.pageAlias, strconv.Itoa(heading.page))
+	}
+
+	var result bytes.Buffer
+	err := pdf.Output(&result)
 	if err != nil {
 		return nil, err
 	}
 
-	// NOTE: Can be customized later on
-	converter := goldmark.New(
-		goldmark.WithRenderer(pdf.New()),
-	)
+	return result.Bytes(), nil
+}
 
-	converter.Convert(markdownNote, &result)
+// numberHeadings numbers the headings of the note as 1, 1.1, 1.1.1, ...
+func (r *pdfRenderer) numberHeadings() {
+	counters := [3]int{}
 
-	return result.Bytes(), nil
+	for _, block := range r.note.Blocks {
+		if _, ok := block.Data.(*notespb.Block_Heading); !ok {
+			continue
+		}
+
+		level := headingLevel(block)
+		counters[level-1]++
+		for i := level; i < len(counters); i++ {
+			counters[i] = 0
+		}
+
+		numbers := make([]string, level)
+		for i := range numbers {
+			numbers[i] = strconv.Itoa(counters[i])
+		}
+
+		r.headings = append(r.headings, &pdfHeading{
+			number:    strings.Join(numbers, "."),
+			text:      block.GetHeading(),
+			level:     level,
+			link:      r.pdf.AddLink(),
+			pageAlias: "{heading-page-" + strconv.Itoa(len(r.headings)) + "}",
+		})
+	}
+}
+
+func headingLevel(b *notespb.Block) int {
+	switch b.Type {
+	case notespb.Block_TYPE_HEADING_2:
+		return 2
+	case notespb.Block_TYPE_HEADING_3:
+		return 3
+	}
+	return 1
+}
+
+func (r *pdfRenderer) footer() {
+	// The title page has no number.
+	if r.pdf.PageNo() == 1 {
+		return
+	}
+	r.pdf.SetY(-pdfMargin / 1.5)
+	r.pdf.SetFont(pdfFontFamily, "", 9)
+	r.pdf.SetTextColor(128, 128, 128)
+	r.pdf.CellFormat(0, 10, strconv.Itoa(r.pdf.PageNo())+" / {nb}", "", 0, "C", false, 0, "")
+	r.pdf.SetTextColor(0, 0, 0)
+}
+
+func (r *pdfRenderer) titlePage() {
+	r.pdf.AddPage()
+	_, pageHeight := r.pdf.GetPageSize()
+	r.pdf.SetY(pageHeight / 3)
+
+	r.pdf.SetFont(pdfFontFamily, "B", 28)
+	r.pdf.MultiCell(0, 34, r.note.Title, "", "C", false)
+	r.pdf.Ln(24)
+
+	date := r.note.ModifiedAt
+	if date == nil {
+		date = r.note.CreatedAt
+	}
+
+	r.pdf.SetFont(pdfFontFamily, "", 13)
+	for _, line := range []string{r.options.AuthorName, r.options.GroupName} {
+		if line != "" {
+			r.pdf.MultiCell(0, 18, line, "", "C", false)
+		}
+	}
+	if date != nil {
+		r.pdf.MultiCell(0, 18, date.AsTime().Format("02/01/2006"), "", "C", false)
+	}
+
+	if len(r.note.Keywords) > 0 {
+		keywords := make([]string, len(r.note.Keywords))
+		for i, keyword := range r.note.Keywords {
+			keywords[i] = keyword.Keyword
+		}
+		r.pdf.Ln(18)
+		r.pdf.SetFont(pdfFontFamily, "I", pdfFontSize)
+		r.pdf.SetTextColor(96, 96, 96)
+		r.pdf.MultiCell(0, pdfLineHeight, strings.Join(keywords, " · "), "", "C", false)
+		r.pdf.SetTextColor(0, 0, 0)
+	}
+}
+
+func (r *pdfRenderer) tableOfContents() {
+	r.pdf.AddPage()
+
+	title, ok := pdfContentsTitles[r.note.Lang]
+	if !ok {
+		title = pdfContentsTitles["en"]
+	}
+	r.pdf.SetFont(pdfFontFamily, "B", 18)
+	r.pdf.CellFormat(0, 28, title, "", 1, "L", false, 0, "")
+	r.pdf.Ln(8)
+
+	pageWidth, _ := r.pdf.GetPageSize()
+	width := pageWidth - 2*pdfMargin
+	r.pdf.SetFont(pdfFontFamily, "", pdfFontSize)
+
+	for _, heading := range r.headings {
+		indent := float64(heading.level-1) * pdfListIndent
+		r.pdf.SetX(pdfMargin + indent)
+		r.pdf.CellFormat(width-indent-40, pdfLineHeight+4, heading.number+"  "+heading.text, "", 0, "L", false, heading.link, "")
+		r.pdf.CellFormat(40, pdfLineHeight+4, heading.pageAlias, "", 1, "R", false, heading.link, "")
+	}
+}
+
+func (r *pdfRenderer) blocks() {
+	heading := 0
+	number := 0
+
+	for _, block := range r.note.Blocks {
+		// Number points are numbered from 1 in every list.
+		if _, ok := block.Data.(*notespb.Block_NumberPoint); !ok {
+			number = 0
+		}
+
+		// Dividers are the only blocks without data.
+		if block.Type == notespb.Block_TYPE_DIVIDER {
+			r.divider()
+			continue
+		}
+
+		switch op := block.Data.(type) {
+		case *notespb.Block_Heading:
+			r.heading(r.headings[heading], block.Styles)
+			heading++
+		case *notespb.Block_Paragraph:
+			r.pdf.SetX(pdfMargin)
+			r.styledText(op.Paragraph, block.Styles, "", pdfFontSize, pdfLineHeight)
+			r.pdf.Ln(pdfLineHeight * 1.5)
+		case *notespb.Block_NumberPoint:
+			number++
+			r.listItem(strconv.Itoa(number)+".", op.NumberPoint, block.Styles)
+		case *notespb.Block_BulletPoint:
+			r.listItem("•", op.BulletPoint, block.Styles)
+		case *notespb.Block_Math:
+			r.math(op.Math)
+		case *notespb.Block_Code_:
+			r.code(op.Code)
+		case *notespb.Block_Image_:
+			r.image(op.Image)
+		case *notespb.Block_Summary:
+			r.summary(op.Summary)
+		case *notespb.Block_ChecklistItem_:
+			r.checklistItem(op.ChecklistItem, block.Styles)
+		case *notespb.Block_Table_:
+			r.table(op.Table)
+		case *notespb.Block_Callout_:
+			r.callout(op.Callout, block.Styles)
+		case *notespb.Block_Embed_:
+			r.embed(op.Embed)
+		}
+	}
+}
+
+// styledText writes the text at the current position, applying the styles of
+// the block on top of the base font style.
+func (r *pdfRenderer) styledText(text string, styles []*notespb.Block_TextStyle, baseStyle string, size float64, lineHeight float64) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	for _, segment := range styledSegments(text, styles) {
+		bold := strings.Contains(baseStyle, "B")
+		italic := strings.Contains(baseStyle, "I")
+		underline, strikethrough := false, false
+		red, green, blue := 0, 0, 0
+
+		for _, style := range segment.styles {
+			if style.Color != nil {
+				red, green, blue = int(style.Color.R), int(style.Color.G), int(style.Color.B)
+			}
+			switch style.Style {
+			case notespb.Block_TextStyle_STYLE_BOLD:
+				bold = true
+			case notespb.Block_TextStyle_STYLE_ITALIC:
+				italic = true
+			case notespb.Block_TextStyle_STYLE_UNDERLINE:
+				underline = true
+			case notespb.Block_TextStyle_STYLE_STRIKETHROUGH:
+				strikethrough = true
+			}
+		}
+
+		fontStyle := ""
+		for i, enabled := range []bool{bold, italic, underline, strikethrough} {
+			if enabled {
+				fontStyle += string("BIUS"[i])
+			}
+		}
+
+		r.pdf.SetFont(pdfFontFamily, fontStyle, size)
+		r.pdf.SetTextColor(red, green, blue)
+		r.pdf.Write(lineHeight, segment.text)
+	}
+
+	r.pdf.SetTextColor(0, 0, 0)
+}
+
+// ensureSpace starts a new page when less than height is left on the current
+// one, so that headings are not left alone at the bottom of a page.
+func (r *pdfRenderer) ensureSpace(height float64) {
+	_, pageHeight := r.pdf.GetPageSize()
+	if r.pdf.GetY()+height > pageHeight-pdfMargin {
+		r.pdf.AddPage()
+	}
+}
+
+func (r *pdfRenderer) heading(heading *pdfHeading, styles []*notespb.Block_TextStyle) {
+	size := map[int]float64{1: 20, 2: 16, 3: 13}[heading.level]
+
+	r.pdf.Ln(size / 2)
+	r.ensureSpace(size * 4)
+
+	heading.page = r.pdf.PageNo()
+	r.pdf.SetLink(heading.link, -1, -1)
+	r.pdf.Bookmark(heading.number+" "+heading.text, heading.level-1, -1)
+
+	r.pdf.SetX(pdfMargin)
+	r.pdf.SetFont(pdfFontFamily, "B", size)
+	r.pdf.Write(size*1.3, heading.number+"  ")
+	r.styledText(heading.text, styles, "B", size, size*1.3)
+	r.pdf.Ln(size * 1.3)
+	r.pdf.Ln(4)
+}
+
+func (r *pdfRenderer) listItem(marker string, text string, styles []*notespb.Block_TextStyle) {
+	r.pdf.SetFont(pdfFontFamily, "", pdfFontSize)
+	r.pdf.SetX(pdfMargin)
+	r.pdf.CellFormat(pdfListIndent, pdfLineHeight, marker, "", 0, "L", false, 0, "")
+
+	// Wrapped lines are aligned on the text of the item, not on its marker.
+	r.pdf.SetLeftMargin(pdfMargin + pdfListIndent)
+	r.styledText(text, styles, "", pdfFontSize, pdfLineHeight)
+	r.pdf.SetLeftMargin(pdfMargin)
+	r.pdf.Ln(pdfLineHeight + 2)
+}
+
+// math writes the LaTeX source of the formula, there is no LaTeX renderer to
+// typeset it.
+func (r *pdfRenderer) math(math string) {
+	r.pdf.SetFont(pdfCodeFontFamily, "I", pdfFontSize)
+	r.pdf.MultiCell(0, pdfLineHeight, strings.TrimSpace(math), "", "C", false)
+	r.pdf.Ln(pdfLineHeight / 2)
+}
+
+func (r *pdfRenderer) code(code *notespb.Block_Code) {
+	if code.Lang != "" {
+		r.pdf.SetFont(pdfFontFamily, "I", 8)
+		r.pdf.SetTextColor(128, 128, 128)
+		r.pdf.CellFormat(0, 10, code.Lang, "", 1, "R", false, 0, "")
+		r.pdf.SetTextColor(0, 0, 0)
+	}
+
+	snippet := strings.ReplaceAll(strings.ReplaceAll(code.Snippet, "\r\n", "\n"), "\t", "    ")
+	r.pdf.SetFont(pdfCodeFontFamily, "", 9.5)
+	r.pdf.SetFillColor(245, 245, 245)
+	r.pdf.MultiCell(0, 12, strings.TrimSuffix(snippet, "\n"), "", "L", true)
+	r.pdf.Ln(pdfLineHeight / 2)
+}
+
+func (r *pdfRenderer) image(img *notespb.Block_Image) {
+	if !r.embedImage(img.Url) {
+		r.pdf.SetFont(pdfFontFamily, "I", 9)
+		r.pdf.SetTextColor(128, 128, 128)
+		r.pdf.MultiCell(0, 12, img.Url, "", "C", false)
+	}
+
+	if img.Caption != "" {
+		r.pdf.SetFont(pdfFontFamily, "I", 10)
+		r.pdf.SetTextColor(96, 96, 96)
+		r.pdf.MultiCell(0, 13, img.Caption, "", "C", false)
+	}
+
+	r.pdf.SetTextColor(0, 0, 0)
+	r.pdf.Ln(pdfLineHeight / 2)
+}
+
+// embedImage draws the image centered and scaled down to fit the page, and
+// reports whether it could be fetched and decoded.
+func (r *pdfRenderer) embedImage(url string) bool {
+	data, err := r.options.Images(r.ctx, url)
+	if err != nil {
+		return false
+	}
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+
+	name := "image-" + strconv.Itoa(r.images)
+	r.images++
+
+	info := r.pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: format}, bytes.NewReader(data))
+	if r.pdf.Err() || info == nil {
+		// Some images, such as interlaced PNGs, are not supported by the PDF
+		// library: skip them instead of failing the whole export.
+		r.pdf.ClearError()
+		return false
+	}
+
+	pageWidth, pageHeight := r.pdf.GetPageSize()
+	maxWidth, maxHeight := pageWidth-2*pdfMargin, (pageHeight-2*pdfMargin)/2
+
+	width, height := info.Extent()
+	if width > maxWidth {
+		width, height = maxWidth, height*maxWidth/width
+	}
+	if height > maxHeight {
+		width, height = width*maxHeight/height, maxHeight
+	}
+
+	r.ensureSpace(height)
+	r.pdf.ImageOptions(name, (pageWidth-width)/2, -1, width, height, true, gofpdf.ImageOptions{ImageType: format}, 0, "")
+
+	return true
+}
+
+func (r *pdfRenderer) summary(summary string) {
+	r.pdf.SetFont(pdfFontFamily, "I", pdfFontSize)
+	r.pdf.SetDrawColor(160, 160, 160)
+	r.pdf.MultiCell(0, pdfLineHeight, strings.TrimSuffix(strings.ReplaceAll(summary, "\r\n", "\n"), "\n"), "L", "L", false)
+	r.pdf.SetDrawColor(0, 0, 0)
+	r.pdf.Ln(pdfLineHeight / 2)
+}
+
+func (r *pdfRenderer) checklistItem(item *notespb.Block_ChecklistItem, styles []*notespb.Block_TextStyle) {
+	const boxSize = 8
+
+	r.pdf.SetX(pdfMargin)
+	x, y := r.pdf.GetXY()
+	boxY := y + (pdfLineHeight-boxSize)/2
+	r.pdf.SetDrawColor(96, 96, 96)
+	r.pdf.Rect(x, boxY, boxSize, boxSize, "D")
+	if item.Checked {
+		// Check mark drawn inside the box.
+		r.pdf.SetLineWidth(1.2)
+		r.pdf.Line(x+1.5, boxY+boxSize/2, x+boxSize/2.5, boxY+boxSize-1.5)
+		r.pdf.Line(x+boxSize/2.5, boxY+boxSize-1.5, x+boxSize-1, boxY+1)
+		r.pdf.SetLineWidth(0.2)
+	}
+	r.pdf.SetDrawColor(0, 0, 0)
+	r.pdf.SetX(x + pdfListIndent)
+
+	// Wrapped lines are aligned on the text of the item, not on its box.
+	r.pdf.SetLeftMargin(pdfMargin + pdfListIndent)
+	if item.Checked {
+		// Checked items are greyed out, on top of their own styles.
+		styles = append(append([]*notespb.Block_TextStyle{}, styles...), &notespb.Block_TextStyle{
+			Pos:   &notespb.Block_TextStyle_Position{Start: 0, Length: int64(len([]rune(item.Text)))},
+			Color: &notespb.Block_TextStyle_Color{R: 128, G: 128, B: 128},
+		})
+	}
+	r.styledText(item.Text, styles, "", pdfFontSize, pdfLineHeight)
+	r.pdf.SetLeftMargin(pdfMargin)
+	r.pdf.Ln(pdfLineHeight + 2)
+}
+
+// table draws the grid of the table, its columns sharing the width of the page
+// and each row being as high as its tallest cell.
+func (r *pdfRenderer) table(table *notespb.Block_Table) {
+	if len(table.Rows) == 0 || len(table.Rows[0].Cells) == 0 {
+		return
+	}
+
+	const padding = 4
+	const lineHeight = 13
+
+	pageWidth, _ := r.pdf.GetPageSize()
+	columns := len(table.Rows[0].Cells)
+	columnWidth := (pageWidth - 2*pdfMargin) / float64(columns)
+
+	r.pdf.SetDrawColor(160, 160, 160)
+	r.pdf.SetFillColor(240, 240, 240)
+	for i, row := range table.Rows {
+		header := i == 0 && table.HasHeaderRow
+		fontStyle := ""
+		if header {
+			fontStyle = "B"
+		}
+		r.pdf.SetFont(pdfFontFamily, fontStyle, 10)
+
+		lines := make([][]string, columns)
+		height := 0.0
+		for j := range lines {
+			value := ""
+			if j < len(row.Cells) {
+				value = strings.ReplaceAll(row.Cells[j].Value, "\r\n", "\n")
+			}
+			lines[j] = r.wrapText(value, columnWidth-2*padding)
+			if cellHeight := float64(len(lines[j]))*lineHeight + 2*padding; cellHeight > height {
+				height = cellHeight
+			}
+		}
+
+		r.ensureSpace(height)
+		y := r.pdf.GetY()
+		for j := range lines {
+			x := pdfMargin + float64(j)*columnWidth
+			if header {
+				r.pdf.Rect(x, y, columnWidth, height, "FD")
+			} else {
+				r.pdf.Rect(x, y, columnWidth, height, "D")
+			}
+
+			align := "L"
+			if !header && j < len(row.Cells) && row.Cells[j].Type == notespb.Block_Table_Cell_TYPE_NUMBER {
+				align = "R"
+			}
+			for k, line := range lines[j] {
+				r.pdf.SetXY(x+padding, y+padding+float64(k)*lineHeight)
+				r.pdf.CellFormat(columnWidth-2*padding, lineHeight, line, "", 0, align, false, 0, "")
+			}
+		}
+		r.pdf.SetXY(pdfMargin, y+height)
+	}
+	r.pdf.SetDrawColor(0, 0, 0)
+	r.pdf.Ln(pdfLineHeight / 2)
+}
+
+// wrapText cuts the text in lines fitting in width, breaking lines between
+// words and inside the words too wide to fit on a line.
+func (r *pdfRenderer) wrapText(text string, width float64) []string {
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for r.pdf.GetStringWidth(word) > width {
+				head, tail := r.splitWord(word, width)
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, head)
+				word = tail
+			}
+			if word == "" {
+				continue
+			}
+
+			if line == "" {
+				line = word
+			} else if r.pdf.GetStringWidth(line+" "+word) > width {
+				lines = append(lines, line)
+				line = word
+			} else {
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitWord returns the longest start of the word fitting in width, of at
+// least one character, and the rest of the word.
+func (r *pdfRenderer) splitWord(word string, width float64) (string, string) {
+	runes := []rune(word)
+	end := 1
+	for end < len(runes) && r.pdf.GetStringWidth(string(runes[:end+1])) <= width {
+		end++
+	}
+	return string(runes[:end]), string(runes[end:])
+}
+
+// Colors of the bar on the left of the callouts of each tone.
+var pdfCalloutColors = map[notespb.Block_Callout_Tone][3]int{
+	notespb.Block_Callout_TONE_INFO:    {52, 120, 246},
+	notespb.Block_Callout_TONE_WARNING: {230, 140, 20},
+	notespb.Block_Callout_TONE_TIP:     {40, 170, 90},
+}
+
+func (r *pdfRenderer) callout(callout *notespb.Block_Callout, styles []*notespb.Block_TextStyle) {
+	const barWidth = 3
+
+	color, ok := pdfCalloutColors[callout.Tone]
+	if !ok {
+		color = pdfCalloutColors[notespb.Block_Callout_TONE_INFO]
+	}
+
+	r.ensureSpace(pdfLineHeight * 2)
+	page, top := r.pdf.PageNo(), r.pdf.GetY()
+
+	r.pdf.SetLeftMargin(pdfMargin + pdfListIndent)
+	r.pdf.SetX(pdfMargin + pdfListIndent)
+	r.styledText(strings.TrimSuffix(callout.Text, "\n"), styles, "", pdfFontSize, pdfLineHeight)
+	r.pdf.SetLeftMargin(pdfMargin)
+	r.pdf.Ln(pdfLineHeight)
+
+	// The bar is only drawn on the page the callout starts on.
+	endPage, end := r.pdf.PageNo(), r.pdf.GetY()
+	bottom := end
+	if endPage != page {
+		_, pageHeight := r.pdf.GetPageSize()
+		bottom = pageHeight - pdfMargin
+	}
+	r.pdf.SetPage(page)
+	r.pdf.SetFillColor(color[0], color[1], color[2])
+	r.pdf.Rect(pdfMargin, top, barWidth, bottom-top, "F")
+	r.pdf.SetPage(endPage)
+	r.pdf.SetY(end)
+	r.pdf.Ln(pdfLineHeight / 2)
+}
+
+func (r *pdfRenderer) divider() {
+	pageWidth, _ := r.pdf.GetPageSize()
+	r.pdf.Ln(pdfLineHeight / 2)
+	y := r.pdf.GetY()
+	r.pdf.SetDrawColor(200, 200, 200)
+	r.pdf.Line(pdfMargin, y, pageWidth-pdfMargin, y)
+	r.pdf.SetDrawColor(0, 0, 0)
+	r.pdf.Ln(pdfLineHeight)
+}
+
+// embed writes a link to the embedded page, PDF readers can't display it.
+func (r *pdfRenderer) embed(embed *notespb.Block_Embed) {
+	text := embed.Title
+	if text == "" {
+		text = embed.Url
+	}
+
+	r.pdf.SetX(pdfMargin)
+	r.pdf.SetFont(pdfFontFamily, "U", pdfFontSize)
+	r.pdf.SetTextColor(40, 90, 200)
+	r.pdf.WriteLinkString(pdfLineHeight, text, embed.Url)
+	r.pdf.Ln(pdfLineHeight)
+
+	if embed.Title != "" {
+		r.pdf.SetFont(pdfFontFamily, "I", 9)
+		r.pdf.SetTextColor(128, 128, 128)
+		r.pdf.MultiCell(0, 12, embed.Url, "", "L", false)
+	}
+
+	r.pdf.SetTextColor(0, 0, 0)
+	r.pdf.Ln(pdfLineHeight / 2)
 }
@@ -0,0 +1,152 @@
+package exports_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/exports"
+	notespb "notes-service/protorepo/noted/notes/v1"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fixtureImages serves the images of the testdata directory instead of
+// downloading them.
+func fixtureImages(ctx context.Context, url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "https://noted.fr/") {
+		return nil, errors.New("no fixture for " + url)
+	}
+	return os.ReadFile(filepath.Join("testdata", strings.TrimPrefix(url, "https://noted.fr/")))
+}
+
+func newTestPDFNote(images ...string) *notespb.Note {
+	note := &notespb.Note{
+		Title:           "Les chats",
+		Lang:            "fr",
+		AuthorAccountId: "edouard",
+		Keywords:        []*notespb.Keyword{{Keyword: "Félin"}},
+		Blocks: []*notespb.Block{
+			{Type: notespb.Block_TYPE_HEADING_1, Data: &notespb.Block_Heading{Heading: "Définition"}},
+			{
+				Type: notespb.Block_TYPE_PARAGRAPH,
+				Data: &notespb.Block_Paragraph{Paragraph: "Le chat est un petit mammifère."},
+				Styles: []*notespb.Block_TextStyle{
+					{Style: notespb.Block_TextStyle_STYLE_BOLD, Pos: &notespb.Block_TextStyle_Position{Start: 3, Length: 4}},
+					{Style: notespb.Block_TextStyle_STYLE_STRIKETHROUGH, Pos: &notespb.Block_TextStyle_Position{Start: 15, Length: 5}},
+					{Pos: &notespb.Block_TextStyle_Position{Start: 21, Length: 9}, Color: &notespb.Block_TextStyle_Color{R: 200}},
+				},
+			},
+			{Type: notespb.Block_TYPE_HEADING_2, Data: &notespb.Block_Heading{Heading: "Races"}},
+			{Type: notespb.Block_TYPE_NUMBER_POINT, Data: &notespb.Block_NumberPoint{NumberPoint: "Siamois"}},
+			{Type: notespb.Block_TYPE_BULLET_POINT, Data: &notespb.Block_BulletPoint{BulletPoint: "Persan"}},
+			{Type: notespb.Block_TYPE_CODE, Data: &notespb.Block_Code_{Code: &notespb.Block_Code{Snippet: "miaou()", Lang: "go"}}},
+			{Type: notespb.Block_TYPE_MATH, Data: &notespb.Block_Math{Math: "E = mc^2"}},
+			{Type: notespb.Block_TYPE_SUMMARY, Data: &notespb.Block_Summary{Summary: "- Les chats sont des félins"}},
+		},
+	}
+
+	for _, image := range images {
+		note.Blocks = append(note.Blocks, &notespb.Block{
+			Type: notespb.Block_TYPE_IMAGE,
+			Data: &notespb.Block_Image_{Image: &notespb.Block_Image{Url: "https://noted.fr/" + image, Caption: "Un chat"}},
+		})
+	}
+
+	return note
+}
+
+func Test_NoteToPDFWithOptions(t *testing.T) {
+	// Given
+	note := newTestPDFNote("chat.png", "chat.jpg")
+
+	// When
+	result, err := exports.NoteToPDFWithOptions(context.TODO(), note, &exports.PDFOptions{
+		AuthorName: "Edouard",
+		GroupName:  "Biologie",
+		Images:     fixtureImages,
+	})
+	require.NoError(t, err)
+	document := string(result)
+
+	// Then
+	require.True(t, strings.HasPrefix(document, "%PDF-"))
+	require.Equal(t, 2, strings.Count(document, "/Subtype /Image"), "every image should be embedded")
+	require.Contains(t, document, "/Outlines", "headings should be bookmarked")
+	require.Contains(t, document, "/Annots", "the table of contents should link to the headings")
+	require.NotContains(t, document, "{heading-page-", "page numbers of the table of contents should be resolved")
+}
+
+func Test_NoteToPDFWithOptions_UnavailableImage(t *testing.T) {
+	// Given
+	note := newTestPDFNote("missing.png")
+
+	// When
+	result, err := exports.NoteToPDFWithOptions(context.TODO(), note, &exports.PDFOptions{Images: fixtureImages})
+
+	// Then
+	require.NoError(t, err, "an image which can't be fetched should not fail the export")
+	require.Zero(t, strings.Count(string(result), "/Subtype /Image"))
+}
+
+func Test_NoteToPDF_StructuredBlocks(t *testing.T) {
+	// When
+	result, err := exports.NoteToPDF(newTestStructuredBlocksNote())
+	require.NoError(t, err)
+
+	// Then
+	require.True(t, strings.HasPrefix(string(result), "%PDF-"))
+	require.Contains(t, string(result), "/URI (https://fr.wikipedia.org/wiki/Chat)", "embeds should be links")
+}
+
+func Test_NoteToPDF_UnicodeText(t *testing.T) {
+	// Given
+	note := &notespb.Note{
+		Title: "Фотосинтез",
+		Blocks: []*notespb.Block{
+			{Type: notespb.Block_TYPE_PARAGRAPH, Data: &notespb.Block_Paragraph{Paragraph: "Η φωτοσύνθεση παράγει γλυκόζη."}},
+			{Type: notespb.Block_TYPE_CODE, Data: &notespb.Block_Code_{Code: &notespb.Block_Code{Snippet: "énergie := lumière()"}}},
+		},
+	}
+
+	// When
+	result, err := exports.NoteToPDF(note)
+	require.NoError(t, err)
+
+	// Then
+	require.Contains(t, string(result), "/FontFile2", "the fonts covering the text should be embedded")
+	require.NotContains(t, string(result), "/Helvetica")
+}
+
+func Test_FetchImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	tests := map[string]string{
+		"file":      "file:///etc/passwd",
+		"gopher":    "gopher://noted.fr/image.png",
+		"loopback":  server.URL + "/image.png",
+		"localhost": strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/image.png",
+		"metadata":  "http://169.254.169.254/latest/meta-data/",
+		"private":   "http://10.0.0.1/image.png",
+	}
+
+	for name, url := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := exports.FetchImage(context.TODO(), url)
+			require.Error(t, err, "%s should not be fetched", url)
+		})
+	}
+
+	t.Run("data-url", func(t *testing.T) {
+		data, err := exports.FetchImage(context.TODO(), "data:image/png;base64,aW1hZ2U=")
+		require.NoError(t, err)
+		require.Equal(t, []byte("image"), data)
+	})
+}
@@ -0,0 +1,77 @@
+package exports
+
+import (
+	"bytes"
+	"encoding/csv"
+	notespb "notes-service/protorepo/noted/notes/v1"
+	"strings"
+)
+
+// QuizDeck holds the quizzes of a note exported as flashcards.
+type QuizDeck struct {
+	NoteID string
+	Title  string
+	// Tags of every card, usually the title and keywords of the note.
+	Tags    []string
+	Quizzes []*notespb.Quiz
+}
+
+// cardTags returns the tags of the deck the way flashcard tools expect them:
+// without any whitespace and without duplicates.
+func (d *QuizDeck) cardTags() []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range d.Tags {
+		tag = strings.Join(strings.Fields(tag), "_")
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// deckName returns the title of the note on a single line.
+func (d *QuizDeck) deckName() string {
+	name := strings.Join(strings.Fields(d.Title), " ")
+	if name == "" {
+		return "Noted"
+	}
+	return name
+}
+
+// QuizDeckToCSV writes a line per question of the deck. The file starts with
+// the headers understood by the Anki importer, other tools can skip the lines
+// starting with a '#'.
+func QuizDeckToCSV(deck *QuizDeck) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("#separator:comma\n")
+	buf.WriteString("#html:false\n")
+	buf.WriteString("#columns:Question,Answers,Solutions,Tags\n")
+	buf.WriteString("#deck:" + deck.deckName() + "\n")
+	buf.WriteString("#tags column:4\n")
+
+	tags := strings.Join(deck.cardTags(), " ")
+
+	writer := csv.NewWriter(&buf)
+	for _, quiz := range deck.Quizzes {
+		for _, question := range quiz.Questions {
+			err := writer.Write([]string{
+				question.Question,
+				strings.Join(question.Answers, "\n"),
+				strings.Join(question.Solutions, "\n"),
+				tags,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
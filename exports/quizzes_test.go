@@ -0,0 +1,89 @@
+package exports_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"io"
+	"notes-service/exports"
+	notespb "notes-service/protorepo/noted/notes/v1"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestQuizDeck() *exports.QuizDeck {
+	return &exports.QuizDeck{
+		NoteID: "note",
+		Title:  "Les chats",
+		Tags:   []string{"Les chats", "félin", "Félin", " "},
+		Quizzes: []*notespb.Quiz{{
+			Id: "quiz",
+			Questions: []*notespb.QuizQuestion{
+				{Question: "Couleur <du> chat ?", Answers: []string{"noir", "blanc"}, Solutions: []string{"noir"}},
+				{Question: "Nombre de pattes ?", Answers: []string{"2, ou 4"}, Solutions: []string{"2, ou 4"}},
+			},
+		}},
+	}
+}
+
+func Test_QuizDeckToCSV(t *testing.T) {
+	// When
+	result, err := exports.QuizDeckToCSV(newTestQuizDeck())
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, "#separator:comma\n"+
+		"#html:false\n"+
+		"#columns:Question,Answers,Solutions,Tags\n"+
+		"#deck:Les chats\n"+
+		"#tags column:4\n"+
+		"Couleur <du> chat ?,\"noir\nblanc\",noir,Les_chats félin\n"+
+		"Nombre de pattes ?,\"2, ou 4\",\"2, ou 4\",Les_chats félin\n", string(result))
+}
+
+func Test_QuizDeckToAnki(t *testing.T) {
+	// When
+	result, err := exports.QuizDeckToAnki(newTestQuizDeck())
+	require.NoError(t, err)
+
+	// Then
+	archive, err := zip.NewReader(bytes.NewReader(result), int64(len(result)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		content, err := file.Open()
+		require.NoError(t, err)
+		files[file.Name], err = io.ReadAll(content)
+		require.NoError(t, err)
+		content.Close()
+	}
+	require.Equal(t, "{}", string(files["media"]))
+
+	collectionPath := filepath.Join(t.TempDir(), "collection.anki2")
+	require.NoError(t, os.WriteFile(collectionPath, files["collection.anki2"], 0o600))
+	db, err := sql.Open("sqlite", collectionPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT flds, tags FROM notes ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	notes := [][]string{}
+	for rows.Next() {
+		var fields, tags string
+		require.NoError(t, rows.Scan(&fields, &tags))
+		notes = append(notes, append(strings.Split(fields, "\x1f"), tags))
+	}
+	require.Equal(t, [][]string{
+		{"Couleur &lt;du&gt; chat ?", "<ul><li>noir</li><li>blanc</li></ul>", "<ul><li>noir</li></ul>", " Les_chats félin "},
+		{"Nombre de pattes ?", "<ul><li>2, ou 4</li></ul>", "<ul><li>2, ou 4</li></ul>", " Les_chats félin "},
+	}, notes)
+
+	var cards int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM cards").Scan(&cards))
+	require.Equal(t, 2, cards, "every question should be a card")
+}
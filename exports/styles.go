@@ -0,0 +1,63 @@
+package exports
+
+import (
+	notespb "notes-service/protorepo/noted/notes/v1"
+	"sort"
+)
+
+// styledSegment is a part of the text of a block covered by the same styles.
+type styledSegment struct {
+	text   string
+	styles []*notespb.Block_TextStyle
+}
+
+// styledSegments cuts the text at every style boundary so that each segment is
+// covered by the same styles from its start to its end.
+func styledSegments(text string, styles []*notespb.Block_TextStyle) []styledSegment {
+	runes := []rune(text)
+
+	boundaries := []int{0, len(runes)}
+	for _, style := range styles {
+		start, end := styleRange(style, len(runes))
+		boundaries = append(boundaries, start, end)
+	}
+	sort.Ints(boundaries)
+
+	segments := []styledSegment{}
+	for i := 0; i+1 < len(boundaries); i++ {
+		start, end := boundaries[i], boundaries[i+1]
+		if start == end {
+			continue
+		}
+
+		segment := styledSegment{text: string(runes[start:end])}
+		for _, style := range styles {
+			styleStart, styleEnd := styleRange(style, len(runes))
+			if start >= styleStart && end <= styleEnd {
+				segment.styles = append(segment.styles, style)
+			}
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
+// styleRange returns the rune range of the style, clamped to the text.
+func styleRange(style *notespb.Block_TextStyle, length int) (int, int) {
+	if style.Pos == nil {
+		return 0, 0
+	}
+	start := int(style.Pos.Start)
+	end := start + int(style.Pos.Length)
+	if start < 0 {
+		start = 0
+	}
+	if end > length {
+		end = length
+	}
+	if start > end {
+		return 0, 0
+	}
+	return start, end
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"notes-service/gateway"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// serveGateway serves the REST/JSON gateway of the APIs until the server is
+// closed, it does nothing when the gateway port is 0.
+//
+// The gateway calls the grpc listener like any other client so that the
+// requests go through the interceptors: the Authorization header is forwarded
+// as the authorization metadata and the grpc codes of the errors are mapped to
+// HTTP statuses by runtime.HTTPStatusFromCode.
+func (s *server) serveGateway() {
+	if *gatewayPort == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.gatewayCancel = cancel
+
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(gatewayIncomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(gatewayOutgoingHeaderMatcher),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+	)
+	endpoint := fmt.Sprint("localhost:", *port)
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+	err := notesv1.RegisterNotesAPIHandlerFromEndpoint(ctx, mux, endpoint, opts)
+	must(err, "could not register notes api gateway")
+	err = notesv1.RegisterGroupsAPIHandlerFromEndpoint(ctx, mux, endpoint, opts)
+	must(err, "could not register groups api gateway")
+	err = notesv1.RegisterRecommendationsAPIHandlerFromEndpoint(ctx, mux, endpoint, opts)
+	must(err, "could not register recommendations api gateway")
+
+	root := http.NewServeMux()
+	openAPIHandler, err := gateway.OpenAPIHandler(*openAPIDocument)
+	if err != nil {
+		s.logger.Warn("openapi document not served, generate it with go generate ./gateway", zap.Error(err))
+	} else {
+		root.Handle("/openapi.json", openAPIHandler)
+	}
+	root.Handle("/", mux)
+
+	s.gatewayServer = &http.Server{
+		Addr:              fmt.Sprint(":", *gatewayPort),
+		Handler:           otelhttp.NewHandler(root, "gateway"),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		s.slogger.Infof("gateway served on : %d", *gatewayPort)
+		err := s.gatewayServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("failed to serve gateway", zap.Error(err))
+		}
+	}()
+}
+
+func (s *server) closeGateway(ctx context.Context) {
+	if s.gatewayServer == nil {
+		return
+	}
+
+	err := s.gatewayServer.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error("failed to shutdown gateway", zap.Error(err))
+	}
+	s.gatewayCancel()
+}
+
+// gatewayIncomingHeaderMatcher forwards the request ID of the caller along
+// with the headers forwarded by default.
+func gatewayIncomingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, requestIDHeaderKey) {
+		return requestIDHeaderKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// gatewayOutgoingHeaderMatcher returns the request ID as is, the other
+// metadata are prefixed like the gateway does by default.
+func gatewayOutgoingHeaderMatcher(key string) (string, bool) {
+	if key == requestIDHeaderKey {
+		return "X-Request-Id", true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
@@ -0,0 +1,26 @@
+// Package gateway serves the OpenAPI document of the REST/JSON gateway. The
+// document is generated by protoc-gen-openapiv2 from the google.api.http rules
+// of the protos, like the gateway itself, so that it only describes the routes
+// the gateway serves. It is read at startup rather than embedded so that
+// building the service does not depend on the generation.
+package gateway
+
+import (
+	"net/http"
+	"os"
+)
+
+//go:generate buf generate ../protorepo --template buf.gen.yaml --path ../protorepo/noted/notes/v1
+
+// OpenAPIHandler serves the OpenAPI document stored at path as JSON.
+func OpenAPIHandler(path string) (http.Handler, error) {
+	document, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document)
+	}), nil
+}
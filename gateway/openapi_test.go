@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIHandler(t *testing.T) {
+	t.Run("document-is-served-as-json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "openapi.swagger.json")
+		document := `{"swagger":"2.0","paths":{"/v1/groups":{}}}`
+		require.NoError(t, os.WriteFile(path, []byte(document), 0o600))
+
+		handler, err := OpenAPIHandler(path)
+		require.NoError(t, err)
+
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("GET", "/openapi.json", nil))
+		require.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		require.Equal(t, document, res.Body.String())
+	})
+
+	t.Run("missing-document-is-an-error", func(t *testing.T) {
+		_, err := OpenAPIHandler(filepath.Join(t.TempDir(), "openapi.swagger.json"))
+		require.Error(t, err)
+	})
+}
@@ -33,6 +33,8 @@ type groupsAPI struct {
 	groups     models.GroupsRepository
 	activities models.ActivitiesRepository
 	notes      models.NotesRepository
+	scores     models.ScoresRepository
+	links      models.LinksRepository
 }
 
 func (srv *groupsAPI) CreateGroup(ctx context.Context, req *notesv1.CreateGroupRequest) (*notesv1.CreateGroupResponse, error) {
@@ -150,7 +152,7 @@ func (srv *groupsAPI) DeleteGroup(ctx context.Context, req *notesv1.DeleteGroupR
 			&models.ManyNotesFilter{AuthorAccountID: member.AccountID, GroupID: req.GroupId},
 		)
 		if err != nil {
-			srv.logger.Error("could not move notes: " + err.Error())
+			loggerFromContext(ctx, srv.logger).Error("could not move notes: " + err.Error())
 		}
 	}
 
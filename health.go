@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"notes-service/language"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Services reported by the health server, the overall health of the server
+// ("") only depends on mongo since the language providers are optional.
+const (
+	healthServiceMongo    = "mongo"
+	healthServiceLanguage = "language"
+)
+
+// Maximum duration of a single readiness check.
+const healthCheckTimeout = 5 * time.Second
+
+func (s *server) initHealthServer() {
+	s.healthServer = health.NewServer()
+	s.checkHealth()
+}
+
+// watchHealth refreshes the readiness of mongo and of the language providers
+// every interval until ctx is done.
+func (s *server) watchHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkHealth()
+		}
+	}
+}
+
+func (s *server) checkHealth() {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	mongoStatus := healthpb.HealthCheckResponse_SERVING
+	err := s.mongoDB.Ping(ctx)
+	if err != nil {
+		s.logger.Warn("mongo is not ready", zap.Error(err))
+		mongoStatus = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	s.healthServer.SetServingStatus(healthServiceMongo, mongoStatus)
+	s.healthServer.SetServingStatus("", mongoStatus)
+
+	languageStatus := healthpb.HealthCheckResponse_SERVING
+	if checker, ok := s.languageService.(language.ReadinessChecker); ok {
+		err = checker.Ready(ctx)
+		if err != nil {
+			s.logger.Warn("language providers are not ready", zap.Error(err))
+			languageStatus = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+	s.healthServer.SetServingStatus(healthServiceLanguage, languageStatus)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+
+	"notes-service/backups"
+	"notes-service/imports"
+	"notes-service/language"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"notes-service/validators"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var protobufImportFormatToParser = map[notesv1.NoteImportFormat]func([]byte) ([]models.NoteBlock, error){
+	notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_MARKDOWN: imports.MarkdownToBlocks,
+	notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_HTML:     imports.HTMLToBlocks,
+	notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_TEXT:     imports.TextToBlocks,
+}
+
+func (srv *notesAPI) ImportNote(ctx context.Context, req *notesv1.ImportNoteRequest) (*notesv1.ImportNoteResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateImportNoteRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	var note *models.Note
+	if req.ImportFormat == notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_JSON {
+		note, err = srv.importNoteBackup(ctx, req, group, token.AccountID)
+	} else {
+		note, err = srv.importNoteFile(ctx, req, token.AccountID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.ImportNoteResponse{Note: modelsNoteToProtobufNote(note)}, nil
+}
+
+// importNoteFile creates a note from the blocks parsed from the file.
+func (srv *notesAPI) importNoteFile(ctx context.Context, req *notesv1.ImportNoteRequest, accountID string) (*models.Note, error) {
+	parser, ok := protobufImportFormatToParser[req.ImportFormat]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "format not recognized : %s", req.ImportFormat.String())
+	}
+
+	blocks, err := parser(req.File)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not parse file as %s: %s", req.ImportFormat.String(), err.Error())
+	}
+
+	return srv.createNote(ctx, &models.CreateNotePayload{
+		GroupID:         req.GroupId,
+		Title:           req.Title,
+		AuthorAccountID: accountID,
+		FolderID:        req.FolderId,
+		Lang:            req.Lang,
+		Blocks:          blocks,
+	})
+}
+
+// importNoteBackup restores a note exported as JSON with new IDs. Its author,
+// edit permissions and comment authors are kept for the members of the group,
+// the importing account replaces the others. Checklist items assigned outside
+// of the group are unassigned.
+func (srv *notesAPI) importNoteBackup(ctx context.Context, req *notesv1.ImportNoteRequest, group *models.Group, accountID string) (*models.Note, error) {
+	note, err := backups.NoteFromJSON(req.File)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not parse file as %s: %s", req.ImportFormat.String(), err.Error())
+	}
+
+	note.GroupID = req.GroupId
+	note.FolderID = req.FolderId
+	// The IDs are remapped, the note it was translated from can't be found.
+	note.SourceNoteID = ""
+	if req.Title != "" {
+		note.Title = req.Title
+	}
+	if req.Lang != "" {
+		note.Lang = req.Lang
+	}
+	err = validators.ValidateLang(note.Lang)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "lang: %s", err.Error())
+	}
+	// The backup only checks the structure of the blocks, their content goes
+	// through the checks of the blocks written by the RPCs.
+	for i := range *note.Blocks {
+		err = validators.ValidateRestoredBlock(modelsBlockToProtobufBlock(&(*note.Blocks)[i]))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "blocks[%d]: %s", i, err.Error())
+		}
+	}
+	if note.Lang == "" {
+		note.Lang = language.DetectLanguage(note.Title + "\n" + noteModelToString(note))
+	}
+
+	if group.FindMember(note.AuthorAccountID) == nil {
+		note.AuthorAccountID = accountID
+	}
+
+	permissions := []string{note.AuthorAccountID}
+	for _, id := range note.AccountsWithEditPermissions {
+		if id != note.AuthorAccountID && group.FindMember(id) != nil {
+			permissions = append(permissions, id)
+		}
+	}
+	note.AccountsWithEditPermissions = permissions
+
+	for _, block := range *note.Blocks {
+		if block.ChecklistItem != nil && group.FindMember(block.ChecklistItem.AssigneeAccountID) == nil {
+			block.ChecklistItem.AssigneeAccountID = ""
+		}
+		for i := range *block.Thread {
+			comment := &(*block.Thread)[i]
+			if group.FindMember(comment.AuthorAccountID) == nil {
+				comment.AuthorAccountID = accountID
+			}
+		}
+	}
+
+	note, err = srv.notes.ImportNoteInternal(ctx, note)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	err = srv.noteCreated(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
@@ -0,0 +1,308 @@
+package imports
+
+import (
+	"bytes"
+	"notes-service/models"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToBlocks parses an HTML document. Headings, paragraphs, lists, <pre>
+// code, images, figures, block quotes and math elements become blocks, inline
+// formatting tags become text styles. The title of a note exported as HTML is
+// skipped and its headings keep their levels.
+func HTMLToBlocks(source []byte) ([]models.NoteBlock, error) {
+	document, err := html.Parse(bytes.NewReader(source))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &htmlConverter{blocks: []models.NoteBlock{}}
+	if findElement(document, func(n *html.Node) bool { return n.DataAtom == atom.H1 && hasClass(n, "note-title") }) != nil {
+		c.headingOffset = 1
+	}
+	c.container(document)
+
+	return c.blocks, nil
+}
+
+type htmlConverter struct {
+	blocks []models.NoteBlock
+	// Images found in the paragraph being converted.
+	images []models.NoteBlock
+	// Difference between the level of the HTML headings and of the blocks.
+	headingOffset int
+}
+
+var htmlHeadingLevels = map[atom.Atom]int{
+	atom.H1: 1,
+	atom.H2: 2,
+	atom.H3: 3,
+	atom.H4: 4,
+	atom.H5: 5,
+	atom.H6: 6,
+}
+
+// isHTMLBlock reports whether the node starts a new block, as opposed to
+// inline content which is gathered into paragraphs.
+func isHTMLBlock(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return n.Type == html.DocumentNode
+	}
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.P, atom.Ul, atom.Ol, atom.Li,
+		atom.Pre, atom.Figure, atom.Blockquote, atom.Div, atom.Section, atom.Article, atom.Main,
+		atom.Header, atom.Footer, atom.Aside, atom.Nav, atom.Body, atom.Html, atom.Head, atom.Table, atom.Hr:
+		return true
+	}
+	return false
+}
+
+func isSkippedHTML(n *html.Node) bool {
+	if n.Type == html.CommentNode {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Template, atom.Noscript:
+		return true
+	case atom.Section:
+		return hasClass(n, "footnotes")
+	case atom.Sup, atom.P:
+		return hasClass(n, "footnote-ref") || hasClass(n, "footnote-refs")
+	}
+	return false
+}
+
+// container converts the children of the node, consecutive inline nodes being
+// gathered into paragraphs.
+func (c *htmlConverter) container(n *html.Node) {
+	inline := []*html.Node{}
+	flush := func() {
+		if len(inline) > 0 {
+			c.textBlock("TYPE_PARAGRAPH", inline...)
+			inline = []*html.Node{}
+		}
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if isSkippedHTML(child) {
+			continue
+		}
+		if isHTMLBlock(child) {
+			flush()
+			c.block(child)
+		} else {
+			inline = append(inline, child)
+		}
+	}
+	flush()
+}
+
+func (c *htmlConverter) block(n *html.Node) {
+	if level, ok := htmlHeadingLevels[n.DataAtom]; ok {
+		if level-c.headingOffset >= 1 {
+			c.textBlock(headingType(level-c.headingOffset), n)
+		}
+		return
+	}
+
+	switch {
+	case n.DataAtom == atom.P:
+		c.textBlock("TYPE_PARAGRAPH", n)
+	case n.DataAtom == atom.Ul, n.DataAtom == atom.Ol:
+		blockType := "TYPE_BULLET_POINT"
+		if n.DataAtom == atom.Ol {
+			blockType = "TYPE_NUMBER_POINT"
+		}
+		for item := n.FirstChild; item != nil; item = item.NextSibling {
+			if item.DataAtom == atom.Li {
+				c.listItem(blockType, item)
+			}
+		}
+	case n.DataAtom == atom.Pre:
+		lang := ""
+		if code := findElement(n, func(n *html.Node) bool { return n.DataAtom == atom.Code }); code != nil {
+			for _, class := range strings.Fields(attribute(code, "class")) {
+				if strings.HasPrefix(class, "language-") {
+					lang = strings.TrimPrefix(class, "language-")
+				}
+			}
+		}
+		c.blocks = append(c.blocks, models.NoteBlock{
+			Type: "TYPE_CODE",
+			Code: &models.NoteBlockCode{Snippet: strings.TrimSuffix(textContent(n), "\n"), Lang: lang},
+		})
+	case n.DataAtom == atom.Figure:
+		img := findElement(n, func(n *html.Node) bool { return n.DataAtom == atom.Img })
+		if img == nil {
+			c.container(n)
+			return
+		}
+		caption := attribute(img, "alt")
+		if figcaption := findElement(n, func(n *html.Node) bool { return n.DataAtom == atom.Figcaption }); figcaption != nil {
+			b := &styledTextBuilder{}
+			c.inline(b, figcaption)
+			caption, _ = b.Result()
+		}
+		c.blocks = append(c.blocks, models.NoteBlock{
+			Type:  "TYPE_IMAGE",
+			Image: &models.NoteBlockImage{Url: attribute(img, "src"), Caption: caption},
+		})
+	case n.DataAtom == atom.Blockquote:
+		b := &styledTextBuilder{}
+		c.inline(b, n)
+		summary, _ := b.Result()
+		c.blocks = append(c.blocks, textBlock("TYPE_SUMMARY", summary, nil))
+	case hasClass(n, "math"):
+		c.blocks = append(c.blocks, textBlock("TYPE_MATH", mathContent(textContent(n)), nil))
+	case n.DataAtom == atom.Hr:
+	default:
+		c.container(n)
+	}
+}
+
+// textBlock adds a block made of the inline content of the nodes, followed by
+// the images it contains.
+func (c *htmlConverter) textBlock(blockType string, nodes ...*html.Node) {
+	b := &styledTextBuilder{}
+	for _, n := range nodes {
+		c.inlineNode(b, n)
+	}
+
+	text, styles := b.Result()
+	if text != "" {
+		c.blocks = append(c.blocks, textBlock(blockType, text, styles))
+	}
+	c.blocks = append(c.blocks, c.images...)
+	c.images = nil
+}
+
+// listItem adds a point made of the inline content of the item. Nested lists
+// are flattened after it.
+func (c *htmlConverter) listItem(blockType string, item *html.Node) {
+	inline := []*html.Node{}
+	nested := []*html.Node{}
+	for child := item.FirstChild; child != nil; child = child.NextSibling {
+		// Paragraphs of the item are part of its text.
+		if isHTMLBlock(child) && child.DataAtom != atom.P {
+			nested = append(nested, child)
+		} else {
+			inline = append(inline, child)
+		}
+	}
+	c.textBlock(blockType, inline...)
+
+	for _, n := range nested {
+		c.block(n)
+	}
+}
+
+func (c *htmlConverter) inline(b *styledTextBuilder, n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.inlineNode(b, child)
+	}
+}
+
+var htmlWhitespaces = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+func (c *htmlConverter) inlineNode(b *styledTextBuilder, n *html.Node) {
+	if n == nil || isSkippedHTML(n) {
+		return
+	}
+
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(htmlWhitespaces.ReplaceAllString(n.Data, " "))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		b.WriteString("\n")
+		return
+	case atom.Img:
+		c.images = append(c.images, models.NoteBlock{
+			Type:  "TYPE_IMAGE",
+			Image: &models.NoteBlockImage{Url: attribute(n, "src"), Caption: attribute(n, "alt")},
+		})
+		return
+	case atom.P, atom.Div, atom.Li:
+		// Block elements nested in inline content, such as the paragraphs of a
+		// block quote, are separated by line breaks.
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+	}
+
+	style, ok := htmlTagStyles[n.Data]
+	if !ok {
+		c.inline(b, n)
+		return
+	}
+
+	var color *models.Color
+	if style == styleColor {
+		color = colorFromCSS(attribute(n, "style"))
+	}
+	b.Open(style, color)
+	c.inline(b, n)
+	b.Close(style)
+}
+
+// mathContent removes the delimiters KaTeX looks for around a formula.
+func mathContent(math string) string {
+	math = strings.TrimSpace(math)
+	for _, delimiters := range [][2]string{{`\[`, `\]`}, {"$$", "$$"}, {`\(`, `\)`}} {
+		if strings.HasPrefix(math, delimiters[0]) && strings.HasSuffix(math, delimiters[1]) {
+			return strings.TrimSpace(math[len(delimiters[0]) : len(math)-len(delimiters[1])])
+		}
+	}
+	return math
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var content strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		content.WriteString(textContent(child))
+	}
+	return content.String()
+}
+
+func findElement(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attribute(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attribute(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"notes-service/models"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	htmlrenderer "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.Strikethrough),
+	goldmark.WithParserOptions(parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 750))),
+)
+
+// MarkdownToBlocks parses a Markdown document. Bold, italic and strikethrough
+// emphasis as well as <u> and colored <span> tags become text styles, $$
+// delimited formulas become math blocks and block quotes become summaries.
+func MarkdownToBlocks(source []byte) ([]models.NoteBlock, error) {
+	source = bytes.ReplaceAll(source, []byte("\r\n"), []byte("\n"))
+	document := markdown.Parser().Parse(text.NewReader(source))
+
+	c := &markdownConverter{source: source, blocks: []models.NoteBlock{}}
+	for node := document.FirstChild(); node != nil; node = node.NextSibling() {
+		err := c.block(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c.blocks, nil
+}
+
+type markdownConverter struct {
+	source []byte
+	blocks []models.NoteBlock
+	// Images found in the paragraph being converted.
+	images []models.NoteBlock
+}
+
+func (c *markdownConverter) block(node ast.Node) error {
+	switch n := node.(type) {
+	case *ast.Heading:
+		c.textBlock(headingType(n.Level), n)
+	case *ast.Paragraph, *ast.TextBlock:
+		c.textBlock("TYPE_PARAGRAPH", n)
+	case *ast.List:
+		blockType := "TYPE_BULLET_POINT"
+		if n.IsOrdered() {
+			blockType = "TYPE_NUMBER_POINT"
+		}
+		for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+			err := c.listItem(blockType, item)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.FencedCodeBlock:
+		c.blocks = append(c.blocks, models.NoteBlock{
+			Type: "TYPE_CODE",
+			Code: &models.NoteBlockCode{Snippet: c.lines(n), Lang: string(n.Language(c.source))},
+		})
+	case *ast.CodeBlock:
+		c.blocks = append(c.blocks, models.NoteBlock{
+			Type: "TYPE_CODE",
+			Code: &models.NoteBlockCode{Snippet: c.lines(n)},
+		})
+	case *mathBlock:
+		c.blocks = append(c.blocks, textBlock("TYPE_MATH", strings.TrimSpace(c.lines(n)), nil))
+	case *ast.Blockquote:
+		b := &styledTextBuilder{}
+		for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			c.inline(b, child)
+		}
+		summary, _ := b.Result()
+		c.blocks = append(c.blocks, textBlock("TYPE_SUMMARY", summary, nil))
+	case *ast.HTMLBlock:
+		blocks, err := HTMLToBlocks([]byte(c.lines(n)))
+		if err != nil {
+			return err
+		}
+		c.blocks = append(c.blocks, blocks...)
+	}
+
+	return nil
+}
+
+// textBlock adds a block made of the inline content of the node, followed by
+// the images it contains.
+func (c *markdownConverter) textBlock(blockType string, node ast.Node) {
+	b := &styledTextBuilder{}
+	c.inline(b, node)
+
+	text, styles := b.Result()
+	if text != "" || len(c.images) == 0 {
+		c.blocks = append(c.blocks, textBlock(blockType, text, styles))
+	}
+	c.blocks = append(c.blocks, c.images...)
+	c.images = nil
+}
+
+// listItem adds a point made of the first paragraph of the item. Nested lists
+// are flattened after it.
+func (c *markdownConverter) listItem(blockType string, item ast.Node) error {
+	child := item.FirstChild()
+	switch child.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		c.textBlock(blockType, child)
+		child = child.NextSibling()
+	default:
+		c.blocks = append(c.blocks, textBlock(blockType, "", nil))
+	}
+
+	for ; child != nil; child = child.NextSibling() {
+		err := c.block(child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lines returns the raw content of a leaf block.
+func (c *markdownConverter) lines(node ast.Node) string {
+	var content strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		content.Write(segment.Value(c.source))
+	}
+	return strings.TrimSuffix(content.String(), "\n")
+}
+
+func (c *markdownConverter) inline(b *styledTextBuilder, node ast.Node) {
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			value := n.Segment.Value(c.source)
+			if n.IsRaw() {
+				b.WriteString(string(value))
+			} else {
+				b.WriteString(unescapeMarkdown(value))
+			}
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteString("\n")
+			}
+		case *ast.String:
+			if n.IsCode() || n.IsRaw() {
+				b.WriteString(string(n.Value))
+			} else {
+				b.WriteString(unescapeMarkdown(n.Value))
+			}
+		case *ast.CodeSpan:
+			b.WriteString(string(n.Text(c.source)))
+		case *ast.AutoLink:
+			b.WriteString(string(n.URL(c.source)))
+		case *ast.Image:
+			caption := &styledTextBuilder{}
+			c.inline(caption, n)
+			alt, _ := caption.Result()
+			c.images = append(c.images, models.NoteBlock{
+				Type:  "TYPE_IMAGE",
+				Image: &models.NoteBlockImage{Url: string(n.Destination), Caption: alt},
+			})
+		case *ast.Emphasis:
+			style := styleItalic
+			if n.Level >= 2 {
+				style = styleBold
+			}
+			b.Open(style, nil)
+			c.inline(b, n)
+			b.Close(style)
+		case *extast.Strikethrough:
+			b.Open(styleStrikethrough, nil)
+			c.inline(b, n)
+			b.Close(styleStrikethrough)
+		case *ast.RawHTML:
+			var tag strings.Builder
+			for i := 0; i < n.Segments.Len(); i++ {
+				segment := n.Segments.At(i)
+				tag.Write(segment.Value(c.source))
+			}
+			inlineHTMLTag(b, tag.String())
+		default:
+			// Links and other containers only keep their text.
+			c.inline(b, n)
+		}
+	}
+}
+
+// unescapeMarkdown resolves the backslash escapes and the character references
+// of a text.
+func unescapeMarkdown(value []byte) string {
+	var buffer bytes.Buffer
+	writer := bufio.NewWriter(&buffer)
+	// The writer of the HTML renderer resolves escapes and references but
+	// escapes its output for HTML.
+	htmlrenderer.DefaultWriter.Write(writer, value)
+	writer.Flush()
+	return html.UnescapeString(buffer.String())
+}
+
+// inlineHTMLTag opens or closes the style of an inline HTML tag written in a
+// Markdown document.
+func inlineHTMLTag(b *styledTextBuilder, tag string) {
+	fields := strings.Fields(strings.Trim(tag, "<>/ "))
+	if len(fields) == 0 {
+		return
+	}
+	name := strings.ToLower(strings.TrimSuffix(fields[0], "/"))
+	closing := strings.HasPrefix(tag, "</")
+
+	if name == "br" {
+		b.WriteString("\n")
+		return
+	}
+
+	style, ok := htmlTagStyles[name]
+	if !ok {
+		return
+	}
+	if closing {
+		b.Close(style)
+		return
+	}
+
+	var color *models.Color
+	if style == styleColor {
+		color = colorFromCSS(tag)
+	}
+	b.Open(style, color)
+}
+
+var htmlTagStyles = map[string]string{
+	"strong": styleBold,
+	"b":      styleBold,
+	"em":     styleItalic,
+	"i":      styleItalic,
+	"u":      styleUnderline,
+	"s":      styleStrikethrough,
+	"del":    styleStrikethrough,
+	"strike": styleStrikethrough,
+	"span":   styleColor,
+}
+
+var kindMathBlock = ast.NewNodeKind("MathBlock")
+
+// mathBlock is a formula written between two $$ lines.
+type mathBlock struct {
+	ast.BaseBlock
+	// Set when the formula is written on the same line as its delimiters.
+	closed bool
+}
+
+func (n *mathBlock) Kind() ast.NodeKind {
+	return kindMathBlock
+}
+
+func (n *mathBlock) IsRaw() bool {
+	return true
+}
+
+func (n *mathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+type mathBlockParser struct{}
+
+func (p *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	trimmed := bytes.TrimSpace(line)
+	if !bytes.HasPrefix(trimmed, []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+
+	node := &mathBlock{}
+	if len(trimmed) > 4 && bytes.HasSuffix(trimmed, []byte("$$")) {
+		start := segment.Start + bytes.Index(line, []byte("$$")) + 2
+		stop := segment.Start + bytes.LastIndex(line, []byte("$$"))
+		node.Lines().Append(text.NewSegment(start, stop))
+		node.closed = true
+	} else if len(trimmed) != 2 {
+		return nil, parser.NoChildren
+	}
+
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	if node.(*mathBlock).closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	if bytes.Equal(bytes.TrimSpace(line), []byte("$$")) {
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
@@ -0,0 +1,91 @@
+package imports_test
+
+import (
+	"notes-service/imports"
+	"notes-service/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MarkdownToBlocks(t *testing.T) {
+	// Given
+	source := "# Les chats\n\n" +
+		"Le **chat** est *noir* et ~~blanc~~, il <u>ronronne</u> en <span style=\"color: rgb(255, 0, 0)\">rouge</span>.\n\n" +
+		"- Siamois\n  - Persan\n\n" +
+		"1. Manger\n2. Dormir\n\n" +
+		"```go\nfmt.Println(\"miaou\")\n```\n\n" +
+		"$$\nE = mc^2\n$$\n\n" +
+		"![Un chat](https://noted.fr/chat.png)\n\n" +
+		"> Les chats dorment beaucoup\n"
+
+	// When
+	blocks, err := imports.MarkdownToBlocks([]byte(source))
+	require.NoError(t, err)
+
+	// Then
+	require.Len(t, blocks, 10)
+
+	require.Equal(t, "TYPE_HEADING_1", blocks[0].Type)
+	require.Equal(t, "Les chats", *blocks[0].Heading)
+
+	require.Equal(t, "Le chat est noir et blanc, il ronronne en rouge.", *blocks[1].Paragraph)
+	require.Equal(t, []models.TextStyle{
+		{Style: "STYLE_BOLD", Position: models.Position{Start: 3, Length: 4}},
+		{Style: "STYLE_ITALIC", Position: models.Position{Start: 12, Length: 4}},
+		{Style: "STYLE_STRIKETHROUGH", Position: models.Position{Start: 20, Length: 5}},
+		{Style: "STYLE_UNDERLINE", Position: models.Position{Start: 30, Length: 8}},
+		{Position: models.Position{Start: 42, Length: 5}, Color: &models.Color{R: 255}},
+	}, *blocks[1].Styles)
+
+	require.Equal(t, "Siamois", *blocks[2].BulletPoint)
+	require.Equal(t, "Persan", *blocks[3].BulletPoint, "nested points should be flattened")
+	require.Equal(t, "Manger", *blocks[4].NumberPoint)
+	require.Equal(t, "Dormir", *blocks[5].NumberPoint)
+	require.Equal(t, &models.NoteBlockCode{Snippet: "fmt.Println(\"miaou\")", Lang: "go"}, blocks[6].Code)
+	require.Equal(t, "E = mc^2", *blocks[7].Math)
+	require.Equal(t, &models.NoteBlockImage{Url: "https://noted.fr/chat.png", Caption: "Un chat"}, blocks[8].Image)
+	require.Equal(t, "Les chats dorment beaucoup", *blocks[9].Summary)
+}
+
+func Test_HTMLToBlocks(t *testing.T) {
+	// Given
+	source := `<html><head><title>Les chats</title><script>alert("miaou")</script></head><body>
+		<h1 class="note-title">Les chats</h1>
+		<h2>Définition</h2>
+		<p>Le <strong>chat</strong> est <em>noir</em><sup class="footnote-ref"><a href="#fn-1">1</a></sup></p>
+		<ul><li>Siamois</li><li>Persan</li></ul>
+		<pre><code class="language-go">fmt.Println("miaou")</code></pre>
+		<figure><img src="https://noted.fr/chat.png" alt="Un chat"><figcaption>Un chat</figcaption></figure>
+		<div class="math math-display">\[E = mc^2\]</div>
+		<section class="footnotes"><ol><li>Pas toujours</li></ol></section>
+	</body></html>`
+
+	// When
+	blocks, err := imports.HTMLToBlocks([]byte(source))
+	require.NoError(t, err)
+
+	// Then
+	require.Len(t, blocks, 7)
+	require.Equal(t, "TYPE_HEADING_1", blocks[0].Type, "the note title should be skipped")
+	require.Equal(t, "Définition", *blocks[0].Heading)
+	require.Equal(t, "Le chat est noir", *blocks[1].Paragraph)
+	require.Equal(t, []models.TextStyle{
+		{Style: "STYLE_BOLD", Position: models.Position{Start: 3, Length: 4}},
+		{Style: "STYLE_ITALIC", Position: models.Position{Start: 12, Length: 4}},
+	}, *blocks[1].Styles)
+	require.Equal(t, "Siamois", *blocks[2].BulletPoint)
+	require.Equal(t, "Persan", *blocks[3].BulletPoint)
+	require.Equal(t, &models.NoteBlockCode{Snippet: "fmt.Println(\"miaou\")", Lang: "go"}, blocks[4].Code)
+	require.Equal(t, &models.NoteBlockImage{Url: "https://noted.fr/chat.png", Caption: "Un chat"}, blocks[5].Image)
+	require.Equal(t, "E = mc^2", *blocks[6].Math)
+}
+
+func Test_TextToBlocks(t *testing.T) {
+	blocks, err := imports.TextToBlocks([]byte("Les chats\r\ndorment.\r\n\r\n\r\nBeaucoup.\n"))
+	require.NoError(t, err)
+
+	require.Len(t, blocks, 2)
+	require.Equal(t, "Les chats\ndorment.", *blocks[0].Paragraph)
+	require.Equal(t, "Beaucoup.", *blocks[1].Paragraph)
+}
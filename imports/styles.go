@@ -0,0 +1,188 @@
+package imports
+
+import (
+	"notes-service/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	styleBold          = "STYLE_BOLD"
+	styleItalic        = "STYLE_ITALIC"
+	styleUnderline     = "STYLE_UNDERLINE"
+	styleStrikethrough = "STYLE_STRIKETHROUGH"
+	// Colors are text styles with a color and no style.
+	styleColor = ""
+)
+
+// styledTextBuilder accumulates the text of a block along with the ranges of
+// its styles, which are opened and closed as the markup is walked.
+type styledTextBuilder struct {
+	runes  []rune
+	open   []models.TextStyle
+	styles []models.TextStyle
+}
+
+func (b *styledTextBuilder) WriteString(s string) {
+	b.runes = append(b.runes, []rune(s)...)
+}
+
+func (b *styledTextBuilder) Len() int {
+	return len(b.runes)
+}
+
+func (b *styledTextBuilder) Open(style string, color *models.Color) {
+	b.open = append(b.open, models.TextStyle{
+		Style:    style,
+		Position: models.Position{Start: int64(len(b.runes))},
+		Color:    color,
+	})
+}
+
+// Close closes the last opened style of the given kind.
+func (b *styledTextBuilder) Close(style string) {
+	for i := len(b.open) - 1; i >= 0; i-- {
+		if b.open[i].Style != style {
+			continue
+		}
+		opened := b.open[i]
+		b.open = append(b.open[:i], b.open[i+1:]...)
+
+		// Spans without a color are only opened to keep the tags balanced.
+		opened.Position.Length = int64(len(b.runes)) - opened.Position.Start
+		if opened.Position.Length > 0 && (opened.Style != styleColor || opened.Color != nil) {
+			b.styles = append(b.styles, opened)
+		}
+		return
+	}
+}
+
+// Result returns the text trimmed of its surrounding whitespaces and its
+// styles. Ranges of the same style only separated by whitespaces are merged,
+// as Markdown emphasis can't start nor end with a whitespace.
+func (b *styledTextBuilder) Result() (string, []models.TextStyle) {
+	for len(b.open) > 0 {
+		b.Close(b.open[len(b.open)-1].Style)
+	}
+
+	start, end := 0, len(b.runes)
+	for start < end && unicode.IsSpace(b.runes[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(b.runes[end-1]) {
+		end--
+	}
+	runes := b.runes[start:end]
+
+	styles := []models.TextStyle{}
+	for _, style := range b.styles {
+		styleStart := clamp(style.Position.Start-int64(start), 0, int64(len(runes)))
+		styleEnd := clamp(style.Position.Start+style.Position.Length-int64(start), 0, int64(len(runes)))
+		if styleEnd > styleStart {
+			style.Position = models.Position{Start: styleStart, Length: styleEnd - styleStart}
+			styles = append(styles, style)
+		}
+	}
+
+	return string(runes), mergeStyles(runes, styles)
+}
+
+func mergeStyles(runes []rune, styles []models.TextStyle) []models.TextStyle {
+	sort.SliceStable(styles, func(i, j int) bool {
+		return styles[i].Position.Start < styles[j].Position.Start
+	})
+
+	merged := []models.TextStyle{}
+	for _, style := range styles {
+		extended := false
+		for i := range merged {
+			previous := &merged[i]
+			if previous.Style != style.Style || !sameColor(previous.Color, style.Color) {
+				continue
+			}
+			previousEnd := previous.Position.Start + previous.Position.Length
+			if previousEnd < style.Position.Start && strings.TrimSpace(string(runes[previousEnd:style.Position.Start])) != "" {
+				continue
+			}
+			if end := style.Position.Start + style.Position.Length; end > previousEnd {
+				previous.Position.Length = end - previous.Position.Start
+			}
+			extended = true
+			break
+		}
+		if !extended {
+			merged = append(merged, style)
+		}
+	}
+
+	return merged
+}
+
+func sameColor(a *models.Color, b *models.Color) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func clamp(value int64, min int64, max int64) int64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+var (
+	rgbColor = regexp.MustCompile(`color\s*:\s*rgb\(\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)\s*\)`)
+	hexColor = regexp.MustCompile(`color\s*:\s*#([0-9a-fA-F]{6})\b`)
+)
+
+// colorFromCSS returns the text color set by an inline CSS declaration.
+func colorFromCSS(css string) *models.Color {
+	if match := rgbColor.FindStringSubmatch(css); match != nil {
+		r, _ := strconv.Atoi(match[1])
+		g, _ := strconv.Atoi(match[2])
+		b, _ := strconv.Atoi(match[3])
+		return &models.Color{R: int32(r), G: int32(g), B: int32(b)}
+	}
+	if match := hexColor.FindStringSubmatch(css); match != nil {
+		value, _ := strconv.ParseUint(match[1], 16, 32)
+		return &models.Color{R: int32(value >> 16 & 0xff), G: int32(value >> 8 & 0xff), B: int32(value & 0xff)}
+	}
+	return nil
+}
+
+func textBlock(blockType string, text string, styles []models.TextStyle) models.NoteBlock {
+	block := models.NoteBlock{Type: blockType, Styles: &styles}
+	switch blockType {
+	case "TYPE_HEADING_1", "TYPE_HEADING_2", "TYPE_HEADING_3":
+		block.Heading = &text
+	case "TYPE_BULLET_POINT":
+		block.BulletPoint = &text
+	case "TYPE_NUMBER_POINT":
+		block.NumberPoint = &text
+	case "TYPE_MATH":
+		block.Math = &text
+	case "TYPE_SUMMARY":
+		block.Summary = &text
+	default:
+		block.Paragraph = &text
+	}
+	return block
+}
+
+func headingType(level int) string {
+	if level < 1 {
+		level = 1
+	}
+	if level > 3 {
+		level = 3
+	}
+	return "TYPE_HEADING_" + strconv.Itoa(level)
+}
@@ -0,0 +1,26 @@
+package imports
+
+import (
+	"notes-service/models"
+	"regexp"
+	"strings"
+)
+
+var blankLines = regexp.MustCompile(`\n[ \t]*\n`)
+
+// TextToBlocks parses a plain text document, each group of lines separated by
+// blank lines becoming a paragraph.
+func TextToBlocks(source []byte) ([]models.NoteBlock, error) {
+	content := strings.ReplaceAll(string(source), "\r\n", "\n")
+
+	blocks := []models.NoteBlock{}
+	for _, paragraph := range blankLines.Split(content, -1) {
+		paragraph = strings.Trim(paragraph, "\n")
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		blocks = append(blocks, textBlock("TYPE_PARAGRAPH", paragraph, nil))
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"notes-service/backups"
+	"notes-service/exports"
+	"notes-service/imports"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestImportsSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	edouard := newTestAccount(t, tu)
+	maxime := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, edouard, maxime)
+
+	t.Run("member-can-import-markdown", func(t *testing.T) {
+		res, err := tu.notes.ImportNote(edouard.Context, &notesv1.ImportNoteRequest{
+			GroupId:      group.ID,
+			FolderId:     "folder",
+			Title:        "Les chats",
+			ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_MARKDOWN,
+			File:         []byte("# Définition\n\nLe **chat** est un petit mammifère.\n"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "fr", res.Note.Lang, "the lang of the note should be detected")
+		require.Len(t, res.Note.Blocks, 2)
+		require.NotEmpty(t, res.Note.Blocks[0].Id)
+		require.Equal(t, notesv1.Block_TYPE_HEADING_1, res.Note.Blocks[0].Type)
+		require.Equal(t, notesv1.Block_TextStyle_STYLE_BOLD, res.Note.Blocks[1].Styles[0].Style)
+	})
+
+	t.Run("member-can-import-text", func(t *testing.T) {
+		res, err := tu.notes.ImportNote(edouard.Context, &notesv1.ImportNoteRequest{
+			GroupId:      group.ID,
+			Title:        "Cats",
+			Lang:         "en",
+			ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_TEXT,
+			File:         []byte("Cats sleep.\n\nA lot."),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "en", res.Note.Lang)
+		require.Len(t, res.Note.Blocks, 2)
+	})
+
+	t.Run("member-can-import-json-backup", func(t *testing.T) {
+		note := newTestNote(t, tu, group, edouard, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Les chats dorment."}},
+		})
+		got, err := tu.notes.GetNote(edouard.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		blockID := got.Note.Blocks[0].Id
+		_, err = tu.notes.CreateBlockComment(maxime.Context, &notesv1.CreateBlockCommentRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			BlockId: blockID,
+			Comment: &notesv1.Block_Comment{AuthorId: maxime.ID, Content: "Beaucoup"},
+		})
+		require.NoError(t, err)
+
+		exported, err := tu.notes.ExportNote(edouard.Context, &notesv1.ExportNoteRequest{
+			GroupId:         group.ID,
+			NoteId:          note.ID,
+			ExportFormat:    notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_JSON,
+			IncludeComments: true,
+		})
+		require.NoError(t, err)
+
+		res, err := tu.notes.ImportNote(maxime.Context, &notesv1.ImportNoteRequest{
+			GroupId:      group.ID,
+			ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_JSON,
+			File:         exported.File,
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, note.ID, res.Note.Id, "the note should have a new id")
+		require.Equal(t, "Default Title", res.Note.Title)
+		require.Equal(t, edouard.ID, res.Note.AuthorAccountId, "the authorship should be preserved")
+		require.Len(t, res.Note.Blocks, 1)
+		require.NotEqual(t, blockID, res.Note.Blocks[0].Id, "the blocks should have new ids")
+
+		comments, err := tu.notes.ListBlockComments(edouard.Context, &notesv1.ListBlockCommentsRequest{
+			GroupId: group.ID,
+			NoteId:  res.Note.Id,
+			BlockId: res.Note.Blocks[0].Id,
+		})
+		require.NoError(t, err)
+		require.Len(t, comments.Comments, 1)
+		require.Equal(t, maxime.ID, comments.Comments[0].AuthorId)
+	})
+
+	t.Run("json-backup-without-comments-has-no-thread", func(t *testing.T) {
+		note := newTestNote(t, tu, group, edouard, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Les chats ronronnent."}},
+		})
+		got, err := tu.notes.GetNote(edouard.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		_, err = tu.notes.CreateBlockComment(maxime.Context, &notesv1.CreateBlockCommentRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			BlockId: got.Note.Blocks[0].Id,
+			Comment: &notesv1.Block_Comment{AuthorId: maxime.ID, Content: "Secret"},
+		})
+		require.NoError(t, err)
+
+		exported, err := tu.notes.ExportNote(edouard.Context, &notesv1.ExportNoteRequest{
+			GroupId:      group.ID,
+			NoteId:       note.ID,
+			ExportFormat: notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_JSON,
+		})
+		require.NoError(t, err)
+		require.NotContains(t, string(exported.File), "Secret", "the comments should not be exported")
+
+		res, err := tu.notes.ImportNote(edouard.Context, &notesv1.ImportNoteRequest{
+			GroupId:      group.ID,
+			ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_JSON,
+			File:         exported.File,
+		})
+		require.NoError(t, err)
+
+		comments, err := tu.notes.ListBlockComments(edouard.Context, &notesv1.ListBlockCommentsRequest{
+			GroupId: group.ID,
+			NoteId:  res.Note.Id,
+			BlockId: res.Note.Blocks[0].Id,
+		})
+		require.NoError(t, err)
+		require.Empty(t, comments.Comments)
+	})
+
+	t.Run("json-backup-comments-of-strangers-are-reassigned", func(t *testing.T) {
+		note := newTestNote(t, tu, group, edouard, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Les chats chassent."}},
+		})
+		got, err := tu.notes.GetNote(edouard.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		_, err = tu.notes.CreateBlockComment(maxime.Context, &notesv1.CreateBlockCommentRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			BlockId: got.Note.Blocks[0].Id,
+			Comment: &notesv1.Block_Comment{AuthorId: maxime.ID, Content: "Des souris"},
+		})
+		require.NoError(t, err)
+
+		exported, err := tu.notes.ExportNote(edouard.Context, &notesv1.ExportNoteRequest{
+			GroupId:         group.ID,
+			NoteId:          note.ID,
+			ExportFormat:    notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_JSON,
+			IncludeComments: true,
+		})
+		require.NoError(t, err)
+
+		otherGroup := newTestGroup(t, tu, edouard)
+		res, err := tu.notes.ImportNote(edouard.Context, &notesv1.ImportNoteRequest{
+			GroupId:      otherGroup.ID,
+			ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_JSON,
+			File:         exported.File,
+		})
+		require.NoError(t, err)
+
+		comments, err := tu.notes.ListBlockComments(edouard.Context, &notesv1.ListBlockCommentsRequest{
+			GroupId: otherGroup.ID,
+			NoteId:  res.Note.Id,
+			BlockId: res.Note.Blocks[0].Id,
+		})
+		require.NoError(t, err)
+		require.Len(t, comments.Comments, 1)
+		require.Equal(t, edouard.ID, comments.Comments[0].AuthorId, "like the author, comment authors outside of the group should be replaced")
+	})
+
+	t.Run("cannot-import-invalid-json-backup", func(t *testing.T) {
+		res, err := tu.notes.ImportNote(edouard.Context, &notesv1.ImportNoteRequest{
+			GroupId:      group.ID,
+			ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_JSON,
+			File:         []byte(`{"schema": "noted.note", "version": 42, "note": {}}`),
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("cannot-import-json-backup-with-invalid-content", func(t *testing.T) {
+		block := func(block models.NoteBlock) models.NoteBlock {
+			block.ID = "block"
+			block.Styles = &[]models.TextStyle{}
+			block.Thread = &[]models.BlockComment{}
+			return block
+		}
+		backup := func(lang string, blocks ...models.NoteBlock) []byte {
+			file, err := backups.NoteToJSON(&models.Note{
+				ID:              "note",
+				Title:           "Les chats",
+				AuthorAccountID: edouard.ID,
+				Lang:            lang,
+				Blocks:          &blocks,
+			})
+			require.NoError(t, err)
+			return file
+		}
+		paragraph := "Le chat est noir."
+
+		files := map[string][]byte{
+			"javascript-embed": backup("fr", block(models.NoteBlock{Type: "TYPE_EMBED", Embed: &models.NoteBlockEmbed{Url: "javascript:alert(1)"}})),
+			"unknown-tone":     backup("fr", block(models.NoteBlock{Type: "TYPE_CALLOUT", Callout: &models.NoteBlockCallout{Tone: "TONE_ANGRY", Text: "Attention"}})),
+			"style-past-text": backup("fr", models.NoteBlock{
+				ID:        "block",
+				Type:      "TYPE_PARAGRAPH",
+				Paragraph: &paragraph,
+				Styles:    &[]models.TextStyle{{Style: "STYLE_BOLD", Position: models.Position{Start: 12, Length: 40}}},
+				Thread:    &[]models.BlockComment{},
+			}),
+			"unsupported-lang": backup("tlh", block(models.NoteBlock{Type: "TYPE_PARAGRAPH", Paragraph: &paragraph})),
+		}
+		for name, file := range files {
+			res, err := tu.notes.ImportNote(edouard.Context, &notesv1.ImportNoteRequest{
+				GroupId:      group.ID,
+				ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_JSON,
+				File:         file,
+			})
+			requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+			require.Nil(t, res, name)
+		}
+	})
+
+	t.Run("cannot-import-without-format", func(t *testing.T) {
+		res, err := tu.notes.ImportNote(edouard.Context, &notesv1.ImportNoteRequest{
+			GroupId: group.ID,
+			Title:   "Les chats",
+			File:    []byte("Les chats dorment."),
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("stranger-cannot-import-note", func(t *testing.T) {
+		res, err := tu.notes.ImportNote(stranger.Context, &notesv1.ImportNoteRequest{
+			GroupId:      group.ID,
+			Title:        "Les chats",
+			ImportFormat: notesv1.NoteImportFormat_NOTE_IMPORT_FORMAT_TEXT,
+			File:         []byte("Les chats dorment."),
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Nil(t, res)
+	})
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	note := &notesv1.Note{
+		Blocks: []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_HEADING_2, Data: &notesv1.Block_Heading{Heading: "Les *chats* #1"}},
+			{
+				Type: notesv1.Block_TYPE_PARAGRAPH,
+				Data: &notesv1.Block_Paragraph{Paragraph: "Le chat est noir,\net pas blanche ni gris"},
+				Styles: []*notesv1.Block_TextStyle{
+					{Style: notesv1.Block_TextStyle_STYLE_BOLD, Pos: &notesv1.Block_TextStyle_Position{Start: 3, Length: 8}},
+					{Style: notesv1.Block_TextStyle_STYLE_ITALIC, Pos: &notesv1.Block_TextStyle_Position{Start: 8, Length: 8}},
+					{Style: notesv1.Block_TextStyle_STYLE_UNDERLINE, Pos: &notesv1.Block_TextStyle_Position{Start: 21, Length: 3}},
+					{Pos: &notesv1.Block_TextStyle_Position{Start: 25, Length: 7}, Color: &notesv1.Block_TextStyle_Color{R: 12, G: 34, B: 56}},
+				},
+			},
+			{Type: notesv1.Block_TYPE_BULLET_POINT, Data: &notesv1.Block_BulletPoint{BulletPoint: "Siamois"}},
+			{Type: notesv1.Block_TYPE_BULLET_POINT, Data: &notesv1.Block_BulletPoint{BulletPoint: "Persan"}},
+			{Type: notesv1.Block_TYPE_NUMBER_POINT, Data: &notesv1.Block_NumberPoint{NumberPoint: "Manger"}},
+			{Type: notesv1.Block_TYPE_CODE, Data: &notesv1.Block_Code_{Code: &notesv1.Block_Code{Snippet: "```\nfmt.Println(\"miaou\")", Lang: "go"}}},
+			{Type: notesv1.Block_TYPE_MATH, Data: &notesv1.Block_Math{Math: `\frac{a}{b} = \{x\}`}},
+			{Type: notesv1.Block_TYPE_IMAGE, Data: &notesv1.Block_Image_{Image: &notesv1.Block_Image{Url: "https://noted.fr/mon chat.png", Caption: "Un [chat]"}}},
+			{Type: notesv1.Block_TYPE_SUMMARY, Data: &notesv1.Block_Summary{Summary: "- Les chats\n- Les chiens"}},
+		},
+	}
+
+	markdown, err := exports.NoteToMarkdown(note)
+	require.NoError(t, err)
+
+	blocks, err := imports.MarkdownToBlocks(markdown)
+	require.NoError(t, err)
+	require.Len(t, blocks, len(note.Blocks), string(markdown))
+
+	for i, expected := range note.Blocks {
+		actual := modelsBlockToProtobufBlock(&blocks[i])
+		require.Equal(t, expected.Type, actual.Type)
+		require.Equal(t, expected.Data, actual.Data, string(markdown))
+		require.Equal(t, len(expected.Styles), len(actual.Styles), string(markdown))
+		for j, style := range expected.Styles {
+			require.Equal(t, style.Style, actual.Styles[j].Style)
+			require.Equal(t, style.Pos.Start, actual.Styles[j].Pos.Start)
+			require.Equal(t, style.Pos.Length, actual.Styles[j].Pos.Length)
+			require.Equal(t, style.Color, actual.Styles[j].Color)
+		}
+	}
+}
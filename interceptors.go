@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"notes-service/auth"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDHeaderKey is the metadata carrying the ID of the request, it is
+// generated when the caller did not send one and is returned in the response
+// headers and forwarded to the accounts service.
+const requestIDHeaderKey = "x-request-id"
+
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+type loggerContextKey struct{}
+
+// RequestIDUnaryInterceptor gives an ID to the request and stores the logger
+// of the request in its context.
+func (s *server) RequestIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, requestID := withRequestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeaderKey, requestID))
+	ctx = withLogger(ctx, s.logger.With(zap.String("request_id", requestID)))
+	return handler(ctx, req)
+}
+
+// RequestIDStreamInterceptor is the streaming equivalent of
+// RequestIDUnaryInterceptor.
+func (s *server) RequestIDStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, requestID := withRequestID(stream.Context())
+	stream.SetHeader(metadata.Pairs(requestIDHeaderKey, requestID))
+	ctx = withLogger(ctx, s.logger.With(zap.String("request_id", requestID)))
+	return handler(srv, auth.NewContextServerStream(stream, ctx))
+}
+
+// withRequestID returns a context holding the ID of the request, in its value
+// and in its incoming metadata so that it is forwarded with the authorization.
+func withRequestID(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+
+	requestID := ""
+	if values := md.Get(requestIDHeaderKey); len(values) > 0 && len(values[0]) <= maxRequestIDLength {
+		requestID = values[0]
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+		md.Set(requestIDHeaderKey, requestID)
+	}
+
+	ctx = metadata.NewIncomingContext(ctx, md)
+	return context.WithValue(ctx, requestIDContextKey{}, requestID), requestID
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+func withLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// loggerFromContext returns the logger of the request handled with ctx, or
+// fallback when ctx does not come from a request.
+func loggerFromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	logger, ok := ctx.Value(loggerContextKey{}).(*zap.Logger)
+	if !ok {
+		return fallback
+	}
+	return logger
+}
+
+func (s *server) LoggerUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	s.logRPC(ctx, info.FullMethod, time.Since(start), err)
+	return res, err
+}
+
+func (s *server) LoggerStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.logRPC(stream.Context(), info.FullMethod, time.Since(start), err)
+	return err
+}
+
+func (s *server) logRPC(ctx context.Context, fullMethod string, duration time.Duration, err error) {
+	logger := loggerFromContext(ctx, s.logger)
+	fields := []zap.Field{
+		zap.String("code", status.Code(err).String()),
+		zap.String("method", rpcMethodName(fullMethod)),
+		zap.Duration("duration", duration),
+	}
+
+	if err != nil {
+		var displayErr = err
+		st, ok := status.FromError(err)
+		if ok {
+			displayErr = errors.New(st.Message())
+		}
+		logger.Warn("failed rpc", append(fields, zap.Error(displayErr))...)
+		return
+	}
+
+	logger.Info("rpc", fields...)
+}
+
+// RecoveryUnaryInterceptor turns a panic of the handler into an Internal
+// error instead of crashing the whole service.
+func (s *server) RecoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.recoveredError(ctx, info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// RecoveryStreamInterceptor is the streaming equivalent of
+// RecoveryUnaryInterceptor.
+func (s *server) RecoveryStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.recoveredError(stream.Context(), info.FullMethod, r)
+		}
+	}()
+	return handler(srv, stream)
+}
+
+func (s *server) recoveredError(ctx context.Context, fullMethod string, recovered interface{}) error {
+	loggerFromContext(ctx, s.logger).Error("panic in rpc",
+		zap.String("method", rpcMethodName(fullMethod)),
+		zap.Any("panic", recovered),
+		zap.Stack("stack"),
+	)
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestInterceptors(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	s := &server{logger: zap.New(core)}
+
+	t.Run("request-id-is-generated-and-logged", func(t *testing.T) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/noted.notes.v1.NotesAPI/GetNote"}
+		var requestID string
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.LoggerUnaryInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				requestID = requestIDFromContext(ctx)
+				md, _ := metadata.FromIncomingContext(ctx)
+				require.Equal(t, []string{requestID}, md.Get(requestIDHeaderKey))
+				return nil, nil
+			})
+		}
+
+		_, err := s.RequestIDUnaryInterceptor(context.TODO(), nil, info, handler)
+		require.NoError(t, err)
+		require.Len(t, requestID, 32)
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		require.Equal(t, requestID, entries[0].ContextMap()["request_id"])
+		require.Equal(t, "GetNote", entries[0].ContextMap()["method"])
+	})
+
+	t.Run("request-id-of-the-caller-is-kept", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs(requestIDHeaderKey, "caller-request"))
+		stream := &fakeServerStream{ctx: ctx}
+		info := &grpc.StreamServerInfo{FullMethod: "/noted.notes.v1.GroupsAPI/StreamInvites", IsServerStream: true}
+
+		err := s.RequestIDStreamInterceptor(nil, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+			return s.LoggerStreamInterceptor(srv, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+				require.Equal(t, "caller-request", requestIDFromContext(stream.Context()))
+				return nil
+			})
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"caller-request"}, stream.header.Get(requestIDHeaderKey))
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		require.Equal(t, "caller-request", entries[0].ContextMap()["request_id"])
+		require.Equal(t, "StreamInvites", entries[0].ContextMap()["method"])
+	})
+
+	t.Run("handlers-log-with-the-logger-of-the-request", func(t *testing.T) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/noted.notes.v1.NotesAPI/GetNote"}
+		var requestID string
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			requestID = requestIDFromContext(ctx)
+			loggerFromContext(ctx, s.logger).Info("handled")
+			return nil, nil
+		}
+
+		_, err := s.RequestIDUnaryInterceptor(context.TODO(), nil, info, handler)
+		require.NoError(t, err)
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		require.Equal(t, requestID, entries[0].ContextMap()["request_id"])
+		require.Equal(t, s.logger, loggerFromContext(context.TODO(), s.logger))
+	})
+
+	t.Run("unary-panics-become-internal-errors", func(t *testing.T) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/noted.notes.v1.NotesAPI/GetNote"}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			var note *struct{ Title string }
+			return note.Title, nil
+		}
+
+		_, err := s.RecoveryUnaryInterceptor(context.TODO(), nil, info, handler)
+		requireErrorHasGRPCCode(t, codes.Internal, err)
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		require.Equal(t, "panic in rpc", entries[0].Message)
+		require.Contains(t, entries[0].ContextMap()["stack"], "RecoveryUnaryInterceptor")
+	})
+
+	t.Run("stream-panics-become-internal-errors", func(t *testing.T) {
+		stream := &fakeServerStream{ctx: context.TODO()}
+		info := &grpc.StreamServerInfo{FullMethod: "/noted.notes.v1.NotesAPI/AskNotes", IsServerStream: true}
+		handler := func(srv interface{}, stream grpc.ServerStream) error {
+			panic("closed channel")
+		}
+
+		err := s.RecoveryStreamInterceptor(nil, stream, info, handler)
+		requireErrorHasGRPCCode(t, codes.Internal, err)
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		require.Equal(t, "closed channel", entries[0].ContextMap()["panic"])
+	})
+}
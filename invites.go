@@ -51,10 +51,10 @@ func (srv *groupsAPI) SendInvite(ctx context.Context, req *notesv1.SendInviteReq
 			ValidUntil:  timestamppb.New(invite.ValidUntil),
 		})
 		if err != nil {
-			srv.logger.Warn("SendEmail for invite returned an error: " + err.Error())
+			loggerFromContext(ctx, srv.logger).Warn("SendEmail for invite returned an error: " + err.Error())
 		}
 	} else {
-		srv.logger.Warn("SendEmail for invite returned an error because notes service is not connected with the accountsClients")
+		loggerFromContext(ctx, srv.logger).Warn("SendEmail for invite returned an error because notes service is not connected with the accountsClients")
 	}
 
 	return &notesv1.SendInviteResponse{Invite: modelsInviteToProtobufInvite(invite, req.GroupId)}, nil
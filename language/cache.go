@@ -0,0 +1,214 @@
+package language
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"notes-service/models"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CachedLanguageService sits in front of another Service. Keywords, summaries
+// and translations are cached by a hash of the text they were generated from,
+// every call is bound to a timeout and counted in the Usage.
+//
+// Quizzes are not cached so that members can generate several different
+// quizzes for the same note.
+type CachedLanguageService struct {
+	service Service
+	usage   *Usage
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	swept   time.Time
+}
+
+type cacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+func NewCachedLanguageService(service Service, usage *Usage, ttl time.Duration, timeout time.Duration) *CachedLanguageService {
+	return &CachedLanguageService{
+		service: service,
+		usage:   usage,
+		timeout: timeout,
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+func (s *CachedLanguageService) Init(logger *zap.Logger) error {
+	return s.service.Init(logger)
+}
+
+// Ready reports the readiness of the underlying service, if it can tell.
+func (s *CachedLanguageService) Ready(ctx context.Context) error {
+	checker, ok := s.service.(ReadinessChecker)
+	if !ok {
+		return nil
+	}
+	return checker.Ready(ctx)
+}
+
+func (s *CachedLanguageService) GetKeywordsFromTextInput(ctx context.Context, input string, lang string) ([]*models.Keyword, error) {
+	key := cacheKey(MethodKeywords, input, lang)
+	if cached, ok := s.get(key); ok {
+		s.usage.IncCacheHits(MethodKeywords)
+		return copyKeywords(cached.([]*models.Keyword)), nil
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	s.usage.IncCalls(MethodKeywords)
+	keywords, err := s.service.GetKeywordsFromTextInput(ctx, input, lang)
+	if err != nil {
+		s.usage.IncErrors(MethodKeywords)
+		return nil, err
+	}
+
+	s.set(key, copyKeywords(keywords))
+	return keywords, nil
+}
+
+func (s *CachedLanguageService) GenerateQuizFromTextInput(ctx context.Context, input string, lang string) (*models.Quiz, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	s.usage.IncCalls(MethodQuiz)
+	quiz, err := s.service.GenerateQuizFromTextInput(ctx, input, lang)
+	if err != nil {
+		s.usage.IncErrors(MethodQuiz)
+		return nil, err
+	}
+
+	return quiz, nil
+}
+
+func (s *CachedLanguageService) GenerateSummaryFromTextInput(ctx context.Context, input string, lang string) (*models.Summary, error) {
+	key := cacheKey(MethodSummary, input, lang)
+	if cached, ok := s.get(key); ok {
+		s.usage.IncCacheHits(MethodSummary)
+		summary := *cached.(*models.Summary)
+		return &summary, nil
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	s.usage.IncCalls(MethodSummary)
+	summary, err := s.service.GenerateSummaryFromTextInput(ctx, input, lang)
+	if err != nil {
+		s.usage.IncErrors(MethodSummary)
+		return nil, err
+	}
+
+	cached := *summary
+	s.set(key, &cached)
+	return summary, nil
+}
+
+func (s *CachedLanguageService) TranslateTextInputs(ctx context.Context, inputs []string, lang string) ([]string, error) {
+	key := cacheKey(MethodTranslation, strings.Join(inputs, "\x00"), lang)
+	if cached, ok := s.get(key); ok {
+		s.usage.IncCacheHits(MethodTranslation)
+		return append([]string{}, cached.([]string)...), nil
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	s.usage.IncCalls(MethodTranslation)
+	translations, err := s.service.TranslateTextInputs(ctx, inputs, lang)
+	if err != nil {
+		s.usage.IncErrors(MethodTranslation)
+		return nil, err
+	}
+
+	s.set(key, append([]string{}, translations...))
+	return translations, nil
+}
+
+// AnswerQuestion is never cached, answers are streamed and members expect a
+// fresh answer when they ask the same question again.
+func (s *CachedLanguageService) AnswerQuestion(ctx context.Context, question string, excerpts []string, lang string, onChunk func(chunk string) error) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	s.usage.IncCalls(MethodAnswer)
+	answer, err := s.service.AnswerQuestion(ctx, question, excerpts, lang, onChunk)
+	if err != nil {
+		s.usage.IncErrors(MethodAnswer)
+		return "", err
+	}
+
+	return answer, nil
+}
+
+func (s *CachedLanguageService) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+func (s *CachedLanguageService) get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (s *CachedLanguageService) set(key string, value interface{}) {
+	if s.ttl <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	// Forget expired entries at most once per ttl so that set stays cheap.
+	if now.Sub(s.swept) >= s.ttl {
+		s.swept = now
+		for k, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	s.entries[key] = &cacheEntry{value: value, expiresAt: now.Add(s.ttl)}
+}
+
+func cacheKey(method string, input string, lang string) string {
+	hash := sha256.Sum256([]byte(method + "\x00" + lang + "\x00" + input))
+	return hex.EncodeToString(hash[:])
+}
+
+// copyKeywords returns a deep copy of the keywords so that callers can't
+// modify what is stored in the cache.
+func copyKeywords(keywords []*models.Keyword) []*models.Keyword {
+	if keywords == nil {
+		return nil
+	}
+
+	copied := make([]*models.Keyword, len(keywords))
+	for i, keyword := range keywords {
+		k := *keyword
+		copied[i] = &k
+	}
+	return copied
+}
@@ -0,0 +1,120 @@
+package language_test
+
+import (
+	"context"
+	"notes-service/language"
+	"notes-service/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeLanguageService struct {
+	calls int
+	wait  time.Duration
+}
+
+func (s *fakeLanguageService) Init(*zap.Logger) error { return nil }
+
+func (s *fakeLanguageService) GetKeywordsFromTextInput(ctx context.Context, input string, lang string) ([]*models.Keyword, error) {
+	s.calls++
+	return []*models.Keyword{{Keyword: input}}, nil
+}
+
+func (s *fakeLanguageService) GenerateQuizFromTextInput(ctx context.Context, input string, lang string) (*models.Quiz, error) {
+	s.calls++
+	return &models.Quiz{}, nil
+}
+
+func (s *fakeLanguageService) GenerateSummaryFromTextInput(ctx context.Context, input string, lang string) (*models.Summary, error) {
+	s.calls++
+	select {
+	case <-time.After(s.wait):
+		return &models.Summary{Content: input}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *fakeLanguageService) TranslateTextInputs(ctx context.Context, inputs []string, lang string) ([]string, error) {
+	s.calls++
+	return inputs, nil
+}
+
+func (s *fakeLanguageService) AnswerQuestion(ctx context.Context, question string, excerpts []string, lang string, onChunk func(chunk string) error) (string, error) {
+	s.calls++
+	return question, onChunk(question)
+}
+
+func Test_CachedLanguageService_CachesByContent(t *testing.T) {
+	// Given
+	fake := &fakeLanguageService{}
+	usage := language.NewUsage()
+	srv := language.NewCachedLanguageService(fake, usage, time.Minute, time.Second)
+
+	// When
+	first, err := srv.GetKeywordsFromTextInput(context.TODO(), "note", "fr")
+	require.NoError(t, err)
+	first[0].Keyword = "modified"
+	second, err := srv.GetKeywordsFromTextInput(context.TODO(), "note", "fr")
+	require.NoError(t, err)
+	_, err = srv.GetKeywordsFromTextInput(context.TODO(), "note", "en")
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, "note", second[0].Keyword, "the cached keywords should not be modified by callers")
+	require.Equal(t, 2, fake.calls)
+	require.Equal(t, language.UsageStats{Calls: 2, CacheHits: 1}, usage.Snapshot()[language.MethodKeywords])
+}
+
+func Test_CachedLanguageService_QuizIsNotCached(t *testing.T) {
+	// Given
+	fake := &fakeLanguageService{}
+	srv := language.NewCachedLanguageService(fake, language.NewUsage(), time.Minute, time.Second)
+
+	// When
+	_, err := srv.GenerateQuizFromTextInput(context.TODO(), "note", "fr")
+	require.NoError(t, err)
+	_, err = srv.GenerateQuizFromTextInput(context.TODO(), "note", "fr")
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, 2, fake.calls)
+}
+
+func Test_CachedLanguageService_TimesOut(t *testing.T) {
+	// Given
+	fake := &fakeLanguageService{wait: time.Second}
+	usage := language.NewUsage()
+	srv := language.NewCachedLanguageService(fake, usage, time.Minute, 10*time.Millisecond)
+
+	// When
+	summary, err := srv.GenerateSummaryFromTextInput(context.TODO(), "note", "fr")
+
+	// Then
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Nil(t, summary)
+	require.Equal(t, uint64(1), usage.Snapshot()[language.MethodSummary].Errors)
+}
+
+func Test_RateLimiter_Allow(t *testing.T) {
+	// Given
+	limiter := language.NewRateLimiter(2, time.Hour)
+
+	// Then
+	require.True(t, limiter.Allow("alice"))
+	require.True(t, limiter.Allow("alice"))
+	require.False(t, limiter.Allow("alice"), "the third call should exceed the limit")
+	require.True(t, limiter.Allow("bob"), "limits should be tracked per key")
+}
+
+func Test_RateLimiter_Unlimited(t *testing.T) {
+	// Given
+	var limiter *language.RateLimiter
+
+	// Then
+	require.True(t, limiter.Allow("alice"))
+	require.True(t, language.NewRateLimiter(0, time.Hour).Allow("alice"))
+}
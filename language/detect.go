@@ -0,0 +1,38 @@
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DetectLanguage guesses the language of the text by counting the stop words
+// of every supported locale it contains. It returns DefaultLocale when the
+// text is too short or has no stop word at all.
+func DetectLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	counts := make(map[string]int, len(locales))
+	for _, locale := range locales {
+		stopWords := make(map[string]bool, len(locale.StopWords))
+		for _, word := range locale.StopWords {
+			stopWords[word] = true
+		}
+		for _, word := range words {
+			if stopWords[word] {
+				counts[locale.Code]++
+			}
+		}
+	}
+
+	// Iterate over the sorted codes so that ties are always broken the same way.
+	detected, best := DefaultLocale, counts[DefaultLocale]
+	for _, code := range SupportedLocales() {
+		if counts[code] > best {
+			detected, best = code, counts[code]
+		}
+	}
+
+	return detected
+}
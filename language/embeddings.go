@@ -0,0 +1,225 @@
+package language
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const MethodEmbedding = "embedding"
+
+// EmbeddingProvider turns a text into a vector, texts about the same subject
+// having close vectors.
+type EmbeddingProvider interface {
+	// Model identifies the vectors returned by Embed, vectors computed by
+	// different models can't be compared.
+	Model() string
+	Embed(ctx context.Context, input string) ([]float32, error)
+	// EmbedAll embeds the inputs with as few calls as possible, the vectors
+	// are returned in the order of the inputs.
+	EmbedAll(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// NewEmbeddingProvider returns an OpenAI provider when an api key is set and
+// falls back on the local HashingEmbeddingProvider otherwise.
+func NewEmbeddingProvider(usage *Usage, timeout time.Duration) EmbeddingProvider {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return NewHashingEmbeddingProvider()
+	}
+	return &OpenAIEmbeddingProvider{
+		client:  openai.NewClient(apiKey),
+		usage:   usage,
+		timeout: timeout,
+	}
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, 1 meaning
+// the vectors point in the same direction.
+func CosineSimilarity(a []float32, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+type OpenAIEmbeddingProvider struct {
+	client  *openai.Client
+	usage   *Usage
+	timeout time.Duration
+}
+
+// Approximation of the 8191 tokens accepted by the model.
+const maxOpenAIEmbeddingInputLength = 24000
+
+func (p *OpenAIEmbeddingProvider) Model() string {
+	return string(openai.AdaEmbeddingV2)
+}
+
+func (p *OpenAIEmbeddingProvider) Embed(ctx context.Context, input string) ([]float32, error) {
+	vectors, err := p.EmbedAll(ctx, []string{input})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// Maximum number of inputs embedded by a single call.
+const maxOpenAIEmbeddingBatchSize = 100
+
+func (p *OpenAIEmbeddingProvider) EmbedAll(ctx context.Context, inputs []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += maxOpenAIEmbeddingBatchSize {
+		end := start + maxOpenAIEmbeddingBatchSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+		batch, err := p.embedBatch(ctx, inputs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (p *OpenAIEmbeddingProvider) embedBatch(ctx context.Context, inputs []string) ([][]float32, error) {
+	truncated := make([]string, len(inputs))
+	for i, input := range inputs {
+		if runes := []rune(input); len(runes) > maxOpenAIEmbeddingInputLength {
+			input = string(runes[:maxOpenAIEmbeddingInputLength])
+		}
+		truncated[i] = input
+	}
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	p.usage.IncCalls(MethodEmbedding)
+	start := time.Now()
+	res, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: truncated,
+		Model: openai.AdaEmbeddingV2,
+	})
+	observeProviderCall(ProviderOpenAI, MethodEmbedding, start, err)
+	if err != nil {
+		p.usage.IncErrors(MethodEmbedding)
+		return nil, err
+	}
+
+	if len(res.Data) != len(inputs) {
+		p.usage.IncErrors(MethodEmbedding)
+		return nil, errors.New("openai answered badly to compute embeddings (len(res.Data) != len(inputs))")
+	}
+
+	// The embeddings are not guaranteed to be in the order of the inputs.
+	vectors := make([][]float32, len(inputs))
+	for _, data := range res.Data {
+		if data.Index < 0 || data.Index >= len(inputs) {
+			p.usage.IncErrors(MethodEmbedding)
+			return nil, errors.New("openai answered badly to compute embeddings (index out of range)")
+		}
+		vectors[data.Index] = data.Embedding
+	}
+
+	return vectors, nil
+}
+
+const hashingEmbeddingDimensions = 512
+
+// HashingEmbeddingProvider computes embeddings locally by hashing the words of
+// the text into a fixed number of dimensions. It ignores the stop words of
+// every supported locale so that notes are only close when they share words
+// which carry meaning.
+type HashingEmbeddingProvider struct {
+	stopWords map[string]bool
+}
+
+func NewHashingEmbeddingProvider() *HashingEmbeddingProvider {
+	stopWords := make(map[string]bool)
+	for _, locale := range locales {
+		for _, word := range locale.StopWords {
+			stopWords[word] = true
+		}
+	}
+	return &HashingEmbeddingProvider{stopWords: stopWords}
+}
+
+func (p *HashingEmbeddingProvider) Model() string {
+	return "hashing-" + strconv.Itoa(hashingEmbeddingDimensions)
+}
+
+func (p *HashingEmbeddingProvider) EmbedAll(ctx context.Context, inputs []string) ([][]float32, error) {
+	vectors := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		vector, err := p.Embed(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func (p *HashingEmbeddingProvider) Embed(ctx context.Context, input string) ([]float32, error) {
+	vector := make([]float32, hashingEmbeddingDimensions)
+
+	words := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		if len([]rune(word)) < 3 || p.stopWords[word] {
+			continue
+		}
+
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		sum := h.Sum32()
+
+		// Use one bit of the hash as a sign so that collisions cancel out
+		// instead of always adding up.
+		sign := float32(1)
+		if sum&(1<<31) != 0 {
+			sign = -1
+		}
+		vector[sum%hashingEmbeddingDimensions] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return vector, nil
+	}
+
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+
+	return vector, nil
+}
@@ -0,0 +1,32 @@
+package language_test
+
+import (
+	"context"
+	"notes-service/language"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HashingEmbeddingProvider_Embed(t *testing.T) {
+	// Given
+	provider := language.NewHashingEmbeddingProvider()
+
+	// When
+	plants, err := provider.Embed(context.TODO(), "Les plantes utilisent la chlorophylle pour capter la lumière.")
+	require.NoError(t, err)
+	chlorophyll, err := provider.Embed(context.TODO(), "La chlorophylle capte la lumière dans les plantes.")
+	require.NoError(t, err)
+	history, err := provider.Embed(context.TODO(), "Napoléon fut sacré empereur en 1804.")
+	require.NoError(t, err)
+
+	// Then
+	require.InDelta(t, 1, language.CosineSimilarity(plants, plants), 0.0001)
+	require.Greater(t, language.CosineSimilarity(plants, chlorophyll), float32(0.5))
+	require.Less(t, language.CosineSimilarity(plants, history), float32(0.1), "stop words should not make unrelated texts close")
+}
+
+func Test_CosineSimilarity_MismatchingVectors(t *testing.T) {
+	require.Zero(t, language.CosineSimilarity([]float32{1, 0}, []float32{1, 0, 0}))
+	require.Zero(t, language.CosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+}
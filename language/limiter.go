@@ -0,0 +1,86 @@
+package language
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter allows at most limit calls per key during each window. A nil
+// RateLimiter or a limit of 0 never rejects any call.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*rateWindow
+	swept   time.Time
+	now     func() time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+		now:     time.Now,
+	}
+}
+
+// Allow records a call for the key and reports whether it fits in the limit.
+func (l *RateLimiter) Allow(key string) bool {
+	if l == nil || l.limit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evictExpired(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// Refund forgets a call recorded by Allow for the key, when the call could not
+// be made after all.
+func (l *RateLimiter) Refund(key string) {
+	if l == nil || l.limit <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if ok && w.count > 0 {
+		w.count--
+	}
+}
+
+// evictExpired forgets the keys whose window is over, at most once per window
+// so that Allow stays cheap.
+func (l *RateLimiter) evictExpired(now time.Time) {
+	if now.Sub(l.swept) < l.window {
+		return
+	}
+	l.swept = now
+
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package language_test
+
+import (
+	"notes-service/language"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("rejects-calls-over-the-limit", func(t *testing.T) {
+		limiter := language.NewRateLimiter(2, time.Hour)
+		require.True(t, limiter.Allow("alice"))
+		require.True(t, limiter.Allow("alice"))
+		require.False(t, limiter.Allow("alice"))
+		require.True(t, limiter.Allow("bob"), "keys should have their own limit")
+	})
+
+	t.Run("refunded-call-can-be-made-again", func(t *testing.T) {
+		limiter := language.NewRateLimiter(1, time.Hour)
+		require.True(t, limiter.Allow("alice"))
+		limiter.Refund("alice")
+		require.True(t, limiter.Allow("alice"))
+		require.False(t, limiter.Allow("alice"))
+	})
+
+	t.Run("refund-without-call-does-not-raise-the-limit", func(t *testing.T) {
+		limiter := language.NewRateLimiter(1, time.Hour)
+		limiter.Refund("alice")
+		require.True(t, limiter.Allow("alice"))
+		require.False(t, limiter.Allow("alice"))
+	})
+
+	t.Run("nil-limiter-allows-every-call", func(t *testing.T) {
+		var limiter *language.RateLimiter
+		limiter.Refund("alice")
+		require.True(t, limiter.Allow("alice"))
+	})
+}
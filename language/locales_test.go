@@ -0,0 +1,45 @@
+package language_test
+
+import (
+	"notes-service/language"
+	"testing"
+
+	"cloud.google.com/go/language/apiv1/languagepb"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"La photosynthèse est le processus par lequel les plantes produisent de l'énergie.":      "fr",
+		"Photosynthesis is the process by which plants turn the light of the sun into energy":    "en",
+		"La fotosíntesis es el proceso por el que las plantas producen energía con la luz":       "es",
+		"Die Photosynthese ist der Prozess, mit dem die Pflanzen Energie aus dem Licht gewinnen": "de",
+		"E = mc²": language.DefaultLocale,
+	}
+
+	for text, expected := range tests {
+		require.Equal(t, expected, language.DetectLanguage(text), text)
+	}
+}
+
+func Test_Locale_KeywordType(t *testing.T) {
+	// Given
+	de, ok := language.GetLocale("de")
+	require.True(t, ok)
+
+	// Then
+	require.Equal(t, "Person", de.KeywordType(languagepb.Entity_PERSON))
+	require.Equal(t, "Unbekannt", de.KeywordType(languagepb.Entity_Type(-1)), "unknown types should fallback to the unknown label")
+
+	en, ok := language.GetLocale("en")
+	require.True(t, ok)
+	require.Equal(t, "PERSON", en.KeywordType(languagepb.Entity_PERSON), "english labels should keep the casing of the stored keywords")
+	require.Equal(t, "WORK_OF_ART", en.KeywordType(languagepb.Entity_WORK_OF_ART))
+}
+
+func Test_SupportedLocales(t *testing.T) {
+	require.Equal(t, []string{"de", "en", "es", "fr"}, language.SupportedLocales())
+
+	_, ok := language.GetLocale("klingon")
+	require.False(t, ok)
+}
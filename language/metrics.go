@@ -0,0 +1,50 @@
+package language
+
+import (
+	"notes-service/metrics"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Providers called by the language services.
+const (
+	ProviderGoogleLanguage       = "google_language"
+	ProviderGoogleKnowledgeGraph = "google_knowledge_graph"
+	ProviderOpenAI               = "openai"
+)
+
+var (
+	providerCalls = promauto.With(metrics.Registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "notes_language_provider_calls_total",
+			Help: "Calls made to the language providers by provider and method.",
+		},
+		[]string{"provider", "method"},
+	)
+	providerErrors = promauto.With(metrics.Registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "notes_language_provider_errors_total",
+			Help: "Failed calls to the language providers by provider and method.",
+		},
+		[]string{"provider", "method"},
+	)
+	providerDuration = promauto.With(metrics.Registry).NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "notes_language_provider_duration_seconds",
+			Help: "Duration of the calls to the language providers by provider and method.",
+		},
+		[]string{"provider", "method"},
+	)
+)
+
+// observeProviderCall records a call to the provider which started at start
+// and returned err.
+func observeProviderCall(provider string, method string, start time.Time, err error) {
+	providerCalls.WithLabelValues(provider, method).Inc()
+	providerDuration.WithLabelValues(provider, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		providerErrors.WithLabelValues(provider, method).Inc()
+	}
+}
@@ -1,6 +1,7 @@
 package language
 
 import (
+	"context"
 	"notes-service/models"
 
 	"go.uber.org/zap"
@@ -8,7 +9,20 @@ import (
 
 type Service interface {
 	Init(*zap.Logger) error
-	GetKeywordsFromTextInput(input string, lang string) ([]*models.Keyword, error)
-	GenerateQuizFromTextInput(input string, lang string) (*models.Quiz, error)
-	GenerateSummaryFromTextInput(input string, lang string) (*models.Summary, error)
+	GetKeywordsFromTextInput(ctx context.Context, input string, lang string) ([]*models.Keyword, error)
+	GenerateQuizFromTextInput(ctx context.Context, input string, lang string) (*models.Quiz, error)
+	GenerateSummaryFromTextInput(ctx context.Context, input string, lang string) (*models.Summary, error)
+	// TranslateTextInputs translates every input into lang, the translations
+	// are returned in the same order as the inputs.
+	TranslateTextInputs(ctx context.Context, inputs []string, lang string) ([]string, error)
+	// AnswerQuestion answers the question from the excerpts, each chunk of the
+	// answer is given to onChunk as soon as it is generated. Excerpts are
+	// numbered from 1 in the prompt so that the answer can cite them as [n].
+	AnswerQuestion(ctx context.Context, question string, excerpts []string, lang string, onChunk func(chunk string) error) (string, error)
+}
+
+// ReadinessChecker is implemented by the services able to tell whether their
+// providers can currently be called.
+type ReadinessChecker interface {
+	Ready(ctx context.Context) error
 }
@@ -0,0 +1,86 @@
+package language
+
+import (
+	"context"
+	"notes-service/models"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("notes-service/language")
+
+// TracedLanguageService sits in front of another Service and wraps every call
+// in a span, so that the time spent in Google or OpenAI shows up in the trace
+// of the RPC which needed it.
+type TracedLanguageService struct {
+	service Service
+}
+
+func NewTracedLanguageService(service Service) *TracedLanguageService {
+	return &TracedLanguageService{service: service}
+}
+
+func (s *TracedLanguageService) Init(logger *zap.Logger) error {
+	return s.service.Init(logger)
+}
+
+// Ready reports the readiness of the underlying service, if it can tell.
+func (s *TracedLanguageService) Ready(ctx context.Context) error {
+	checker, ok := s.service.(ReadinessChecker)
+	if !ok {
+		return nil
+	}
+	return checker.Ready(ctx)
+}
+
+func (s *TracedLanguageService) GetKeywordsFromTextInput(ctx context.Context, input string, lang string) (keywords []*models.Keyword, err error) {
+	ctx, span := startSpan(ctx, MethodKeywords, lang, attribute.Int("language.input_length", len(input)))
+	defer func() { endSpan(span, err) }()
+
+	return s.service.GetKeywordsFromTextInput(ctx, input, lang)
+}
+
+func (s *TracedLanguageService) GenerateQuizFromTextInput(ctx context.Context, input string, lang string) (quiz *models.Quiz, err error) {
+	ctx, span := startSpan(ctx, MethodQuiz, lang, attribute.Int("language.input_length", len(input)))
+	defer func() { endSpan(span, err) }()
+
+	return s.service.GenerateQuizFromTextInput(ctx, input, lang)
+}
+
+func (s *TracedLanguageService) GenerateSummaryFromTextInput(ctx context.Context, input string, lang string) (summary *models.Summary, err error) {
+	ctx, span := startSpan(ctx, MethodSummary, lang, attribute.Int("language.input_length", len(input)))
+	defer func() { endSpan(span, err) }()
+
+	return s.service.GenerateSummaryFromTextInput(ctx, input, lang)
+}
+
+func (s *TracedLanguageService) TranslateTextInputs(ctx context.Context, inputs []string, lang string) (translations []string, err error) {
+	ctx, span := startSpan(ctx, MethodTranslation, lang, attribute.Int("language.inputs", len(inputs)))
+	defer func() { endSpan(span, err) }()
+
+	return s.service.TranslateTextInputs(ctx, inputs, lang)
+}
+
+func (s *TracedLanguageService) AnswerQuestion(ctx context.Context, question string, excerpts []string, lang string, onChunk func(chunk string) error) (answer string, err error) {
+	ctx, span := startSpan(ctx, MethodAnswer, lang, attribute.Int("language.excerpts", len(excerpts)))
+	defer func() { endSpan(span, err) }()
+
+	return s.service.AnswerQuestion(ctx, question, excerpts, lang, onChunk)
+}
+
+func startSpan(ctx context.Context, method string, lang string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("language.method", method), attribute.String("language.lang", lang))
+	return tracer.Start(ctx, "language."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,40 @@
+package language_test
+
+import (
+	"context"
+	"notes-service/language"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_TracedLanguageService_RecordsSpans(t *testing.T) {
+	// Given
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	srv := language.NewTracedLanguageService(&fakeLanguageService{wait: time.Second})
+
+	cancelled, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	// When
+	_, err := srv.GetKeywordsFromTextInput(context.TODO(), "note", "fr")
+	require.NoError(t, err)
+	_, err = srv.GenerateSummaryFromTextInput(cancelled, "note", "fr")
+	require.Error(t, err)
+
+	// Then
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "language.keywords", spans[0].Name())
+	require.Contains(t, spans[0].Attributes(), attribute.String("language.lang", "fr"))
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, "language.summary", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+}
@@ -0,0 +1,79 @@
+package language
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	MethodKeywords    = "keywords"
+	MethodQuiz        = "quiz"
+	MethodSummary     = "summary"
+	MethodTranslation = "translation"
+	MethodAnswer      = "answer"
+)
+
+// UsageStats counts the language calls made for a given method. Only Calls
+// reach Google or OpenAI, cache hits and rate limited calls are free.
+type UsageStats struct {
+	Calls       uint64 `json:"calls"`
+	Errors      uint64 `json:"errors"`
+	CacheHits   uint64 `json:"cacheHits"`
+	RateLimited uint64 `json:"rateLimited"`
+}
+
+// Usage keeps the language calls counters of every method so we can keep an
+// eye on what the service costs us.
+type Usage struct {
+	mu      sync.Mutex
+	methods map[string]*UsageStats
+}
+
+func NewUsage() *Usage {
+	return &Usage{methods: make(map[string]*UsageStats)}
+}
+
+func (u *Usage) IncCalls(method string) {
+	atomic.AddUint64(&u.stats(method).Calls, 1)
+}
+
+func (u *Usage) IncErrors(method string) {
+	atomic.AddUint64(&u.stats(method).Errors, 1)
+}
+
+func (u *Usage) IncCacheHits(method string) {
+	atomic.AddUint64(&u.stats(method).CacheHits, 1)
+}
+
+func (u *Usage) IncRateLimited(method string) {
+	atomic.AddUint64(&u.stats(method).RateLimited, 1)
+}
+
+// Snapshot returns a copy of the counters indexed by method.
+func (u *Usage) Snapshot() map[string]UsageStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	snapshot := make(map[string]UsageStats, len(u.methods))
+	for method, stats := range u.methods {
+		snapshot[method] = UsageStats{
+			Calls:       atomic.LoadUint64(&stats.Calls),
+			Errors:      atomic.LoadUint64(&stats.Errors),
+			CacheHits:   atomic.LoadUint64(&stats.CacheHits),
+			RateLimited: atomic.LoadUint64(&stats.RateLimited),
+		}
+	}
+	return snapshot
+}
+
+func (u *Usage) stats(method string) *UsageStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stats, ok := u.methods[method]
+	if !ok {
+		stats = &UsageStats{}
+		u.methods[method] = stats
+	}
+	return stats
+}
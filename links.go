@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"notes-service/validators"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (srv *notesAPI) ListBacklinks(ctx context.Context, req *notesv1.ListBacklinksRequest) (*notesv1.ListBacklinksResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateListBacklinksRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	_, err = srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	// Only the notes of the group are within reach of the member.
+	links, err := srv.links.ListNoteLinksInternal(ctx,
+		&models.ManyNoteLinksFilter{GroupID: req.GroupId, TargetNoteID: req.NoteId},
+		listOptionsFromLimitOffset(req.Limit, req.Offset))
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	backlinks := make([]*notesv1.Backlink, len(links))
+	for i, link := range links {
+		backlinks[i] = modelsNoteLinkToProtobufBacklink(link)
+	}
+
+	return &notesv1.ListBacklinksResponse{Backlinks: backlinks}, nil
+}
+
+func modelsNoteLinkToProtobufBacklink(link *models.NoteLink) *notesv1.Backlink {
+	return &notesv1.Backlink{
+		SourceNoteId:  link.SourceNoteID,
+		SourceBlockId: link.SourceBlockID,
+		TargetBlockId: link.TargetBlockID,
+		Broken:        link.Broken,
+		CreatedAt:     timestamppb.New(link.CreatedAt),
+	}
+}
+
+// noteLinksFromBlocks returns the links found in the styles of the blocks.
+func noteLinksFromBlocks(blocks *[]models.NoteBlock) []*models.NoteLinkPayload {
+	payloads := []*models.NoteLinkPayload{}
+	if blocks == nil {
+		return payloads
+	}
+
+	for _, block := range *blocks {
+		if block.Styles == nil {
+			continue
+		}
+		for _, style := range *block.Styles {
+			if style.Link == nil || style.Link.NoteID == "" {
+				continue
+			}
+			payloads = append(payloads, &models.NoteLinkPayload{
+				SourceBlockID: block.ID,
+				TargetNoteID:  style.Link.NoteID,
+				TargetBlockID: style.Link.BlockID,
+			})
+		}
+	}
+
+	return payloads
+}
+
+// indexNoteLinks replaces the links indexed for the note by the ones of its
+// blocks. A link to a note out of the group of the note, or to a block which
+// doesn't exist, is indexed as broken. The broken links of the group to the
+// note, or to one of its blocks, are restored.
+// The index is derived from the notes, failing to update it is logged but
+// doesn't fail the write of the note.
+func indexNoteLinks(ctx context.Context, logger *zap.Logger, notes models.NotesRepository, links models.LinksRepository, note *models.Note) {
+	blockIDs := []string{}
+	if note.Blocks != nil {
+		for _, block := range *note.Blocks {
+			blockIDs = append(blockIDs, block.ID)
+		}
+	}
+	err := links.RestoreNoteLinksInternal(ctx, &models.ManyNoteLinksFilter{GroupID: note.GroupID, TargetNoteID: note.ID}, blockIDs)
+	if err != nil {
+		logger.Error("failed to restore note links", zap.Error(err), zap.String("noteId", note.ID))
+	}
+
+	payloads := noteLinksFromBlocks(note.Blocks)
+
+	targets := map[string]*models.Note{}
+	for _, payload := range payloads {
+		target, ok := targets[payload.TargetNoteID]
+		if !ok {
+			target, err = notes.GetNote(ctx, &models.OneNoteFilter{GroupID: note.GroupID, NoteID: payload.TargetNoteID}, note.AuthorAccountID)
+			if err != nil && !errors.Is(err, models.ErrNotFound) {
+				logger.Error("failed to get linked note", zap.Error(err), zap.String("noteId", payload.TargetNoteID))
+				return
+			}
+			targets[payload.TargetNoteID] = target
+		}
+
+		payload.Broken = target == nil ||
+			(payload.TargetBlockID != "" && (target.Blocks == nil || target.FindBlock(payload.TargetBlockID) == nil))
+	}
+
+	err = links.IndexNoteLinksInternal(ctx, &models.OneNoteFilter{GroupID: note.GroupID, NoteID: note.ID}, payloads)
+	if err != nil {
+		logger.Error("failed to index note links", zap.Error(err), zap.String("noteId", note.ID))
+	}
+}
+
+// reindexNoteLinks indexes the links of the note after one of its blocks was
+// written.
+func (srv *notesAPI) reindexNoteLinks(ctx context.Context, filter *models.OneNoteFilter, accountID string) {
+	note, err := srv.notes.GetNote(ctx, filter, accountID)
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to get note to index its links", zap.Error(err), zap.String("noteId", filter.NoteID))
+		return
+	}
+	indexNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.notes, srv.links, note)
+}
+
+// breakLinksToNote flags the links to the note as broken, except the ones from
+// the notes of keepGroupID when it is set.
+func breakLinksToNote(ctx context.Context, logger *zap.Logger, links models.LinksRepository, noteID string, keepGroupID string) {
+	err := links.BreakNoteLinksInternal(ctx, &models.ManyNoteLinksFilter{TargetNoteID: noteID, OutsideGroupID: keepGroupID})
+	if err != nil {
+		logger.Error("failed to break note links", zap.Error(err), zap.String("noteId", noteID))
+	}
+}
+
+// forgetNoteLinks breaks the links to a deleted note and removes its own links
+// from the index.
+func forgetNoteLinks(ctx context.Context, logger *zap.Logger, links models.LinksRepository, noteID string) {
+	breakLinksToNote(ctx, logger, links, noteID, "")
+
+	err := links.DeleteNoteLinksInternal(ctx, &models.ManyNoteLinksFilter{SourceNoteID: noteID})
+	if err != nil {
+		logger.Error("failed to delete note links", zap.Error(err), zap.String("noteId", noteID))
+	}
+}
+
+// breakLinksToBlocks flags the links to the blocks of the note as broken.
+func breakLinksToBlocks(ctx context.Context, logger *zap.Logger, links models.LinksRepository, noteID string, blockIDs []string) {
+	for _, blockID := range blockIDs {
+		err := links.BreakNoteLinksInternal(ctx, &models.ManyNoteLinksFilter{TargetNoteID: noteID, TargetBlockID: blockID})
+		if err != nil {
+			logger.Error("failed to break block links", zap.Error(err), zap.String("noteId", noteID), zap.String("blockId", blockID))
+		}
+	}
+}
+
+// removedBlockIDs returns the IDs of the blocks of before missing from after.
+func removedBlockIDs(before *[]models.NoteBlock, after *[]models.NoteBlock) []string {
+	removed := []string{}
+	if before == nil {
+		return removed
+	}
+
+	kept := map[string]bool{}
+	if after != nil {
+		for _, block := range *after {
+			kept[block.ID] = true
+		}
+	}
+	for _, block := range *before {
+		if !kept[block.ID] {
+			removed = append(removed, block.ID)
+		}
+	}
+
+	return removed
+}
+
+// flagBrokenLinks marks the link styles of the note matching a broken link of
+// the index.
+func flagBrokenLinks(note *notesv1.Note, links []*models.NoteLink) {
+	type linkKey struct{ sourceBlockID, targetNoteID, targetBlockID string }
+
+	broken := map[linkKey]bool{}
+	for _, link := range links {
+		if link.Broken {
+			broken[linkKey{link.SourceBlockID, link.TargetNoteID, link.TargetBlockID}] = true
+		}
+	}
+	if len(broken) == 0 {
+		return
+	}
+
+	for _, block := range note.Blocks {
+		for _, style := range block.Styles {
+			if style.Link == nil {
+				continue
+			}
+			style.Link.Broken = broken[linkKey{block.Id, style.Link.NoteId, style.Link.BlockId}]
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// unreadableLinksRepository fails to list the links, as if the index was down.
+type unreadableLinksRepository struct {
+	models.LinksRepository
+}
+
+func (repo *unreadableLinksRepository) ListAllNoteLinksInternal(ctx context.Context, filter *models.ManyNoteLinksFilter) ([]*models.NoteLink, error) {
+	return nil, errors.New("links index is unavailable")
+}
+
+func TestLinksSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	author := newTestAccount(t, tu)
+	member := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, author, member)
+	otherGroup := newTestGroup(t, tu, stranger)
+
+	linkTo := func(text string, noteID string, blockID string) *notesv1.Block {
+		return &notesv1.Block{
+			Type: notesv1.Block_TYPE_PARAGRAPH,
+			Data: &notesv1.Block_Paragraph{Paragraph: text},
+			Styles: []*notesv1.Block_TextStyle{
+				{
+					Style: notesv1.Block_TextStyle_STYLE_LINK,
+					Pos:   &notesv1.Block_TextStyle_Position{Start: 4, Length: int64(len(text) - 4)},
+					Link:  &notesv1.Block_TextStyle_Link{NoteId: noteID, BlockId: blockID},
+				},
+			},
+		}
+	}
+
+	getLink := func(t *testing.T, note *testNote) *notesv1.Block_TextStyle_Link {
+		res, err := tu.notes.GetNote(note.Author.Context, &notesv1.GetNoteRequest{GroupId: note.Group.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		require.Len(t, res.Note.Blocks, 1)
+		require.Len(t, res.Note.Blocks[0].Styles, 1)
+		require.NotNil(t, res.Note.Blocks[0].Styles[0].Link)
+		return res.Note.Blocks[0].Styles[0].Link
+	}
+
+	listBacklinks := func(t *testing.T, account *testAccount, note *testNote) []*notesv1.Backlink {
+		res, err := tu.notes.ListBacklinks(account.Context, &notesv1.ListBacklinksRequest{GroupId: note.Group.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		return res.Backlinks
+	}
+
+	t.Run("member-can-list-backlinks", func(t *testing.T) {
+		chapter := newTestNote(t, tu, group, author, []*notesv1.Block{})
+		source := newTestNote(t, tu, group, member, []*notesv1.Block{linkTo("see chapter 3", chapter.ID, "")})
+
+		backlinks := listBacklinks(t, member, chapter)
+		require.Len(t, backlinks, 1)
+		require.Equal(t, source.ID, backlinks[0].SourceNoteId)
+		require.False(t, backlinks[0].Broken)
+		require.False(t, getLink(t, source).Broken)
+	})
+
+	t.Run("links-are-indexed-when-blocks-are-written", func(t *testing.T) {
+		chapter := newTestNote(t, tu, group, author, []*notesv1.Block{})
+		source := newTestNote(t, tu, group, author, []*notesv1.Block{})
+		block := source.InsertBlock(t, tu, linkTo("see chapter 3", chapter.ID, ""), 0)
+
+		backlinks := listBacklinks(t, author, chapter)
+		require.Len(t, backlinks, 1)
+		require.Equal(t, block.ID, backlinks[0].SourceBlockId)
+
+		_, err := tu.notes.DeleteBlock(author.Context, &notesv1.DeleteBlockRequest{GroupId: group.ID, NoteId: source.ID, BlockId: block.ID})
+		require.NoError(t, err)
+
+		require.Empty(t, listBacklinks(t, author, chapter))
+	})
+
+	t.Run("link-to-block-is-broken-when-block-is-deleted", func(t *testing.T) {
+		chapter := newTestNote(t, tu, group, author, []*notesv1.Block{})
+		heading := chapter.InsertBlock(t, tu, &notesv1.Block{
+			Type: notesv1.Block_TYPE_HEADING_1,
+			Data: &notesv1.Block_Heading{Heading: "Chapter 3"},
+		}, 0)
+		source := newTestNote(t, tu, group, author, []*notesv1.Block{linkTo("see chapter 3", chapter.ID, heading.ID)})
+		require.False(t, getLink(t, source).Broken)
+
+		_, err := tu.notes.DeleteBlock(author.Context, &notesv1.DeleteBlockRequest{GroupId: group.ID, NoteId: chapter.ID, BlockId: heading.ID})
+		require.NoError(t, err)
+
+		require.True(t, getLink(t, source).Broken)
+		backlinks := listBacklinks(t, author, chapter)
+		require.Len(t, backlinks, 1)
+		require.True(t, backlinks[0].Broken)
+	})
+
+	t.Run("link-is-broken-when-note-is-deleted", func(t *testing.T) {
+		chapter := newTestNote(t, tu, group, author, []*notesv1.Block{})
+		source := newTestNote(t, tu, group, author, []*notesv1.Block{linkTo("see chapter 3", chapter.ID, "")})
+
+		_, err := tu.notes.DeleteNote(author.Context, &notesv1.DeleteNoteRequest{GroupId: group.ID, NoteId: chapter.ID})
+		require.NoError(t, err)
+
+		require.True(t, getLink(t, source).Broken)
+	})
+
+	t.Run("link-is-broken-when-note-is-moved-out-of-the-group", func(t *testing.T) {
+		leaving := newTestAccount(t, tu)
+		leaving.Workspace = newTestWorkspace(t, tu, leaving.ID)
+		leaving.AcceptInvite(t, tu, author.SendInvite(t, tu, leaving, group))
+		chapter := newTestNote(t, tu, group, leaving, []*notesv1.Block{})
+		source := newTestNote(t, tu, group, author, []*notesv1.Block{linkTo("see chapter 3", chapter.ID, "")})
+
+		_, err := tu.groups.RemoveMember(author.Context, &notesv1.RemoveMemberRequest{GroupId: group.ID, AccountId: leaving.ID})
+		require.NoError(t, err)
+
+		require.True(t, getLink(t, source).Broken)
+	})
+
+	t.Run("link-is-restored-when-note-comes-back-to-the-group", func(t *testing.T) {
+		elsewhere := newTestGroup(t, tu, author)
+		chapter := newTestNote(t, tu, group, author, []*notesv1.Block{})
+		source := newTestNote(t, tu, group, author, []*notesv1.Block{linkTo("see chapter 3", chapter.ID, "")})
+
+		_, err := tu.notes.MoveNote(author.Context, &notesv1.MoveNoteRequest{GroupId: group.ID, NoteId: chapter.ID, DestinationGroupId: elsewhere.ID})
+		require.NoError(t, err)
+		require.True(t, getLink(t, source).Broken)
+
+		_, err = tu.notes.MoveNote(author.Context, &notesv1.MoveNoteRequest{GroupId: elsewhere.ID, NoteId: chapter.ID, DestinationGroupId: group.ID})
+		require.NoError(t, err)
+		require.False(t, getLink(t, source).Broken)
+		backlinks := listBacklinks(t, author, chapter)
+		require.Len(t, backlinks, 1)
+		require.False(t, backlinks[0].Broken)
+	})
+
+	t.Run("note-is-returned-when-links-index-cannot-be-read", func(t *testing.T) {
+		chapter := newTestNote(t, tu, group, author, []*notesv1.Block{})
+		source := newTestNote(t, tu, group, author, []*notesv1.Block{linkTo("see chapter 3", chapter.ID, "")})
+
+		api := tu.notes.(*notesAPI)
+		links := api.links
+		api.links = &unreadableLinksRepository{LinksRepository: links}
+		defer func() { api.links = links }()
+
+		link := getLink(t, source)
+		require.Equal(t, chapter.ID, link.NoteId)
+		require.False(t, link.Broken)
+	})
+
+	t.Run("link-to-note-of-another-group-is-broken", func(t *testing.T) {
+		chapter := newTestNote(t, tu, otherGroup, stranger, []*notesv1.Block{})
+		source := newTestNote(t, tu, group, author, []*notesv1.Block{linkTo("see chapter 3", chapter.ID, "")})
+
+		require.True(t, getLink(t, source).Broken)
+		require.Empty(t, listBacklinks(t, stranger, chapter))
+	})
+
+	t.Run("cannot-create-link-without-target", func(t *testing.T) {
+		_, err := tu.notes.CreateNote(author.Context, &notesv1.CreateNoteRequest{
+			GroupId: group.ID,
+			Title:   "Links",
+			Blocks:  []*notesv1.Block{linkTo("see chapter 3", "", "")},
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+	})
+
+	t.Run("stranger-cannot-list-backlinks", func(t *testing.T) {
+		chapter := newTestNote(t, tu, group, author, []*notesv1.Block{})
+
+		_, err := tu.notes.ListBacklinks(stranger.Context, &notesv1.ListBacklinksRequest{GroupId: group.ID, NoteId: chapter.ID})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+	})
+}
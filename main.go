@@ -5,6 +5,7 @@ import (
 
 	"notes-service/auth"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -16,10 +17,25 @@ var (
 	environment        = app.Flag("env", "either development or production").Default(envIsProd).Enum(envIsProd, envIsDev)
 	accountsServiceUrl = app.Flag("accounts-service-url", "address of the accounts-service url to communicate with").Default("accounts.noted.koyeb:3000").String()
 	port               = app.Flag("port", "grpc server port").Default("3000").Int()
+	metricsPort        = app.Flag("metrics-port", "port of the http server exposing prometheus metrics on /metrics, 0 disables it").Default("9090").Int()
+	gatewayPort        = app.Flag("gateway-port", "port of the rest/json gateway of the grpc apis, which serves its openapi document on /openapi.json, 0 disables it").Default("8080").Int()
+	openAPIDocument    = app.Flag("openapi-document", "path of the openapi document served by the gateway on /openapi.json, generated with go generate ./gateway").Default("gateway/openapi.swagger.json").String()
 	mongoUri           = app.Flag("mongo-uri", "mongo uri with password to connect client").Default("mongodb://localhost:27017").String()
 	mongoDbName        = app.Flag("mongo-db-name", "name of the mongo database").Default("notes-service").String()
 	jwtPrivateKey      = app.Flag("jwt-private-key", "base64 encoded ed25519 private key").Default("SGfCQAb05CtmhEesWxcrfXSQR6JjmEMeyjR7Mo21S60ZDW9VVTUuCvEMlGjlqiw4I/z8T11KqAXexvGIPiuffA==").String()
 	gmailSuperSecret   = app.Flag("gmail-super-secret", "token to authenticate notes service with noted gmail account").Default("").String()
+
+	languageTimeout          = app.Flag("language-timeout", "timeout of a single call to google's or openai's apis").Default("30s").Duration()
+	languageCacheTTL         = app.Flag("language-cache-ttl", "duration during which keywords and summaries are cached, 0 disables the cache").Default("1h").Duration()
+	languageAccountRateLimit = app.Flag("language-account-rate-limit", "maximum number of quizs and summaries an account can generate per hour, 0 means unlimited").Default("20").Int()
+	languageGroupRateLimit   = app.Flag("language-group-rate-limit", "maximum number of quizs and summaries a group can generate per hour, 0 means unlimited").Default("100").Int()
+
+	shutdownTimeout     = app.Flag("shutdown-timeout", "maximum duration of the drain of the in-flight rpcs, they are cancelled past it").Default("30s").Duration()
+	healthCheckInterval = app.Flag("health-check-interval", "interval between two checks of the readiness of mongo and of the language providers").Default("10s").Duration()
+
+	otlpEndpoint     = app.Flag("otlp-endpoint", "host:port of the otlp grpc collector receiving the traces, empty disables the export").Default("").String()
+	otlpInsecure     = app.Flag("otlp-insecure", "connect to the otlp collector without tls").Default("false").Bool()
+	traceSampleRatio = app.Flag("trace-sample-ratio", "ratio of the traces started by this service which are exported").Default("1").Float64()
 )
 
 var (
@@ -30,7 +46,23 @@ var (
 func main() {
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 	s := &server{}
-	s.Init(grpc.ChainUnaryInterceptor(s.LoggerUnaryInterceptor, auth.ForwardAuthMetadatathUnaryInterceptor))
+	s.Init(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			s.RequestIDUnaryInterceptor,
+			s.LoggerUnaryInterceptor,
+			s.MetricsUnaryInterceptor,
+			s.RecoveryUnaryInterceptor,
+			auth.ForwardAuthMetadatathUnaryInterceptor,
+		),
+		grpc.ChainStreamInterceptor(
+			s.RequestIDStreamInterceptor,
+			s.LoggerStreamInterceptor,
+			s.MetricsStreamInterceptor,
+			s.RecoveryStreamInterceptor,
+			auth.ForwardAuthMetadataStreamInterceptor,
+		),
+	)
 	s.Run()
-	defer s.Close()
+	s.Close()
 }
@@ -116,6 +116,17 @@ func (srv *groupsAPI) TrackScore(ctx context.Context, req *notesv1.TrackScoreReq
 		return nil, status.Error(codes.Unauthenticated, "member not found")
 	}
 
+	_, err = srv.scores.CreateScoreEventInternal(ctx, &models.ScoreEventPayload{
+		GroupID:   req.GroupId,
+		AccountID: token.AccountID,
+		Season:    group.ScoreSeason,
+		Score:     int(req.Score),
+		Responses: int(req.Responses),
+	})
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
 	memberScore := member.Score + int(req.Score)
 	memberResponses := member.QuizTotal + int(req.Responses)
 
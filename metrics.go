@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"notes-service/metrics"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	rpcRequests = promauto.With(metrics.Registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "notes_rpc_requests_total",
+			Help: "RPCs handled by method and status code.",
+		},
+		[]string{"method", "code"},
+	)
+	rpcDuration = promauto.With(metrics.Registry).NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "notes_rpc_duration_seconds",
+			Help: "Duration of the RPCs by method and status code.",
+		},
+		[]string{"method", "code"},
+	)
+	rpcActiveStreams = promauto.With(metrics.Registry).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "notes_rpc_active_streams",
+			Help: "Streaming RPCs currently open by method.",
+		},
+		[]string{"method"},
+	)
+)
+
+func (s *server) MetricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	observeRPC(info.FullMethod, start, err)
+	return res, err
+}
+
+func (s *server) MetricsStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	method := rpcMethodName(info.FullMethod)
+	rpcActiveStreams.WithLabelValues(method).Inc()
+	defer rpcActiveStreams.WithLabelValues(method).Dec()
+
+	start := time.Now()
+	err := handler(srv, stream)
+	observeRPC(info.FullMethod, start, err)
+	return err
+}
+
+func observeRPC(fullMethod string, start time.Time, err error) {
+	method := rpcMethodName(fullMethod)
+	code := status.Code(err).String()
+	rpcRequests.WithLabelValues(method, code).Inc()
+	rpcDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
+
+// rpcMethodName strips the service from the full method name, e.g.
+// /noted.notes.v1.NotesAPI/GetNote becomes GetNote.
+func rpcMethodName(fullMethod string) string {
+	return fullMethod[strings.LastIndexByte(fullMethod, '/')+1:]
+}
+
+// serveMetrics serves the metrics on /metrics until the server is closed, it
+// does nothing when the metrics port is 0.
+func (s *server) serveMetrics() {
+	if *metricsPort == 0 {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	s.metricsServer = &http.Server{
+		Addr:              fmt.Sprint(":", *metricsPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		s.slogger.Infof("metrics served on : %d", *metricsPort)
+		err := s.metricsServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("failed to serve metrics", zap.Error(err))
+		}
+	}()
+}
+
+func (s *server) closeMetrics(ctx context.Context) {
+	if s.metricsServer == nil {
+		return
+	}
+
+	err := s.metricsServer.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error("failed to shutdown metrics server", zap.Error(err))
+	}
+}
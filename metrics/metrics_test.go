@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	promauto.With(Registry).NewCounter(prometheus.CounterOpts{
+		Name: "test_handler_total",
+		Help: "Served by the handler.",
+	}).Inc()
+
+	res := httptest.NewRecorder()
+	Handler().ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	require.Contains(t, res.Body.String(), "test_handler_total 1\n")
+	require.Contains(t, res.Body.String(), "go_goroutines ")
+	require.Contains(t, res.Body.String(), "process_start_time_seconds ")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetricsInterceptors(t *testing.T) {
+	s := &server{}
+
+	t.Run("unary-rpcs-are-counted-by-method-and-code", func(t *testing.T) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/noted.notes.v1.NotesAPI/GetTestNote"}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "not found")
+		}
+
+		_, err := s.MetricsUnaryInterceptor(context.TODO(), nil, info, handler)
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+
+		require.Equal(t, 1.0, testutil.ToFloat64(rpcRequests.WithLabelValues("GetTestNote", "NotFound")))
+		require.Equal(t, 1, testutil.CollectAndCount(rpcDuration, "notes_rpc_duration_seconds"))
+	})
+
+	t.Run("streams-are-active-until-they-return", func(t *testing.T) {
+		info := &grpc.StreamServerInfo{FullMethod: "/noted.notes.v1.GroupsAPI/StreamTestInvites", IsServerStream: true}
+		handler := func(srv interface{}, stream grpc.ServerStream) error {
+			require.Equal(t, 1.0, testutil.ToFloat64(rpcActiveStreams.WithLabelValues("StreamTestInvites")))
+			return nil
+		}
+
+		err := s.MetricsStreamInterceptor(nil, nil, info, handler)
+		require.NoError(t, err)
+
+		require.Equal(t, 0.0, testutil.ToFloat64(rpcActiveStreams.WithLabelValues("StreamTestInvites")))
+		require.Equal(t, 1.0, testutil.ToFloat64(rpcRequests.WithLabelValues("StreamTestInvites", "OK")))
+	})
+}
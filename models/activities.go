@@ -8,9 +8,11 @@ import (
 type ActivityType string
 
 const (
-	NoteAdded     ActivityType = "ADD-NOTE"
-	MemberJoined  ActivityType = "ADD-MEMBER"
-	MemberRemoved ActivityType = "REMOVE-MEMBER"
+	NoteAdded      ActivityType = "ADD-NOTE"
+	NoteDuplicated ActivityType = "DUPLICATE-NOTE"
+	NoteMoved      ActivityType = "MOVE-NOTE"
+	MemberJoined   ActivityType = "ADD-MEMBER"
+	MemberRemoved  ActivityType = "REMOVE-MEMBER"
 )
 
 type ActivityPayload struct {
@@ -75,6 +75,7 @@ type Group struct {
 	Members            *[]GroupMember       `json:"members,omitempty" bson:"members,omitempty"`
 	Invites            *[]GroupInvite       `json:"invites,omitempty" bson:"invites,omitempty"`
 	InviteLinks        *[]GroupInviteLink   `json:"inviteLinks,omitempty" bson:"inviteLinks,omitempty"`
+	ScoreSeason        int                  `json:"scoreSeason" bson:"scoreSeason"`
 }
 
 func (group *Group) FindConversation(id string) *GroupConversation {
@@ -301,6 +302,8 @@ type GroupsRepository interface {
 	UpdateGroupMember(ctx context.Context, filter *OneMemberFilter, payload *UpdateMemberPayload, accountID string) (*GroupMember, error)
 	RemoveGroupMember(ctx context.Context, filter *OneMemberFilter, accountID string) error
 	UpdateGroupMemberScore(ctx context.Context, filter *OneMemberFilter, payload *UpdateMemberScorePayload, accountID string) (*GroupMember, error)
+	// Starts a new score season, resetting the score of every member.
+	ResetGroupScores(ctx context.Context, filter *OneGroupFilter, accountID string) (*Group, error)
 
 	// Invite Links
 	GenerateGroupInviteLink(ctx context.Context, filter *OneGroupFilter, payload *GenerateGroupInviteLinkPayload, accountID string) (*GroupInviteLink, error)
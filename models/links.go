@@ -0,0 +1,55 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// NoteLink is a reference from a block of a note to another note, or to one
+// of its blocks. Links are indexed every time the blocks of their source note
+// are written so that the target can list its backlinks.
+type NoteLink struct {
+	ID            string `json:"id" bson:"_id"`
+	GroupID       string `json:"groupId" bson:"groupId"`
+	SourceNoteID  string `json:"sourceNoteId" bson:"sourceNoteId"`
+	SourceBlockID string `json:"sourceBlockId" bson:"sourceBlockId"`
+	TargetNoteID  string `json:"targetNoteId" bson:"targetNoteId"`
+	TargetBlockID string `json:"targetBlockId,omitempty" bson:"targetBlockId,omitempty"`
+	// A link is broken once its target is deleted or out of the group of
+	// its source.
+	Broken    bool      `json:"broken" bson:"broken"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type NoteLinkPayload struct {
+	SourceBlockID string
+	TargetNoteID  string
+	TargetBlockID string
+	Broken        bool
+}
+
+type ManyNoteLinksFilter struct {
+	// (Optional) Links whose source note belongs to group.
+	GroupID string
+	// (Optional) Links whose source note doesn't belong to group.
+	OutsideGroupID string
+	// (Optional) Links from note.
+	SourceNoteID string
+	// (Optional) Links to note.
+	TargetNoteID string
+	// (Optional) Links to block of the target note.
+	TargetBlockID string
+}
+
+// LinksRepository is the index of the links between the notes.
+type LinksRepository interface {
+	// Replaces the links indexed for the blocks of the note.
+	IndexNoteLinksInternal(ctx context.Context, filter *OneNoteFilter, payloads []*NoteLinkPayload) error
+	ListNoteLinksInternal(ctx context.Context, filter *ManyNoteLinksFilter, lo *ListOptions) ([]*NoteLink, error)
+	ListAllNoteLinksInternal(ctx context.Context, filter *ManyNoteLinksFilter) ([]*NoteLink, error)
+	BreakNoteLinksInternal(ctx context.Context, filter *ManyNoteLinksFilter) error
+	// Flags the broken links as not broken when they target a whole note or
+	// one of the blocks.
+	RestoreNoteLinksInternal(ctx context.Context, filter *ManyNoteLinksFilter, blockIDs []string) error
+	DeleteNoteLinksInternal(ctx context.Context, filter *ManyNoteLinksFilter) error
+}
@@ -31,6 +31,9 @@ func NewActivitiesRepository(db *mongo.Database, logger *zap.Logger) models.Acti
 }
 
 func (repo *activitiesRepository) ListActivitiesInternal(ctx context.Context, filter *models.ManyActivitiesFilter, lo *models.ListOptions) ([]*models.Activity, error) {
+	ctx, span := repo.startSpan(ctx, "ListActivitiesInternal")
+	defer span.End()
+
 	activities := make([]*models.Activity, 0)
 
 	query := getActivityQuery(filter)
@@ -44,6 +47,9 @@ func (repo *activitiesRepository) ListActivitiesInternal(ctx context.Context, fi
 }
 
 func (repo *activitiesRepository) GetActivityInternal(ctx context.Context, filter *models.OneActivityFilter) (*models.Activity, error) {
+	ctx, span := repo.startSpan(ctx, "GetActivityInternal")
+	defer span.End()
+
 	activity := &models.Activity{}
 
 	query := bson.D{
@@ -60,6 +66,9 @@ func (repo *activitiesRepository) GetActivityInternal(ctx context.Context, filte
 }
 
 func (repo *activitiesRepository) CreateActivityInternal(ctx context.Context, payload *models.ActivityPayload) (*models.Activity, error) {
+	ctx, span := repo.startSpan(ctx, "CreateActivityInternal")
+	defer span.End()
+
 	activity := &models.Activity{
 		ID:        repo.newUUID(),
 		GroupID:   payload.GroupID,
@@ -32,6 +32,9 @@ func NewGroupsRepository(db *mongo.Database, logger *zap.Logger) models.GroupsRe
 }
 
 func (repo *groupsRepository) CreateGroup(ctx context.Context, payload *models.CreateGroupPayload, accountID string) (*models.Group, error) {
+	ctx, span := repo.startSpan(ctx, "CreateGroup")
+	defer span.End()
+
 	group := &models.Group{
 		ID:                 repo.newUUID(),
 		Name:               payload.Name,
@@ -59,6 +62,9 @@ func (repo *groupsRepository) CreateGroup(ctx context.Context, payload *models.C
 }
 
 func (repo *groupsRepository) CreateWorkspace(ctx context.Context, payload *models.CreateWorkspacePayload, accountID string) (*models.Group, error) {
+	ctx, span := repo.startSpan(ctx, "CreateWorkspace")
+	defer span.End()
+
 	workspace := &models.Group{
 		ID:                 repo.newUUID(),
 		Name:               payload.Name,
@@ -82,6 +88,9 @@ func (repo *groupsRepository) CreateWorkspace(ctx context.Context, payload *mode
 }
 
 func (repo *groupsRepository) GetWorkspaceInternal(ctx context.Context, accountID string) (*models.Group, error) {
+	ctx, span := repo.startSpan(ctx, "GetWorkspaceInternal")
+	defer span.End()
+
 	group := &models.Group{}
 
 	query := bson.D{
@@ -97,6 +106,9 @@ func (repo *groupsRepository) GetWorkspaceInternal(ctx context.Context, accountI
 }
 
 func (repo *groupsRepository) GetGroup(ctx context.Context, filter *models.OneGroupFilter, accountID string) (*models.Group, error) {
+	ctx, span := repo.startSpan(ctx, "GetGroup")
+	defer span.End()
+
 	group := &models.Group{}
 
 	query := bson.D{
@@ -116,6 +128,9 @@ func (repo *groupsRepository) GetGroup(ctx context.Context, filter *models.OneGr
 }
 
 func (repo *groupsRepository) GetGroupInternal(ctx context.Context, filter *models.OneGroupFilter) (*models.Group, error) {
+	ctx, span := repo.startSpan(ctx, "GetGroupInternal")
+	defer span.End()
+
 	group := &models.Group{}
 
 	query := bson.D{{Key: "_id", Value: filter.GroupID}}
@@ -129,6 +144,9 @@ func (repo *groupsRepository) GetGroupInternal(ctx context.Context, filter *mode
 }
 
 func (repo *groupsRepository) UpdateGroup(ctx context.Context, filter *models.OneGroupFilter, payload *models.UpdateGroupPayload, accountID string) (*models.Group, error) {
+	ctx, span := repo.startSpan(ctx, "UpdateGroup")
+	defer span.End()
+
 	group := &models.Group{}
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
@@ -151,6 +169,9 @@ func (repo *groupsRepository) UpdateGroup(ctx context.Context, filter *models.On
 }
 
 func (repo *groupsRepository) DeleteGroup(ctx context.Context, filter *models.OneGroupFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "DeleteGroup")
+	defer span.End()
+
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
 		{Key: "members", Value: bson.D{
@@ -164,6 +185,9 @@ func (repo *groupsRepository) DeleteGroup(ctx context.Context, filter *models.On
 }
 
 func (repo *groupsRepository) ListGroupsInternal(ctx context.Context, filter *models.ManyGroupsFilter, lo *models.ListOptions) ([]*models.Group, error) {
+	ctx, span := repo.startSpan(ctx, "ListGroupsInternal")
+	defer span.End()
+
 	groups := make([]*models.Group, 0)
 
 	query := bson.D{}
@@ -186,6 +210,9 @@ func (repo *groupsRepository) ListGroupsInternal(ctx context.Context, filter *mo
 }
 
 func (repo *groupsRepository) SendInvite(ctx context.Context, filter *models.OneGroupFilter, payload *models.SendInvitePayload, accountID string) (*models.GroupInvite, error) {
+	ctx, span := repo.startSpan(ctx, "SendInvite")
+	defer span.End()
+
 	group := &models.Group{}
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
@@ -226,6 +253,9 @@ func (repo *groupsRepository) SendInvite(ctx context.Context, filter *models.One
 }
 
 func (repo *groupsRepository) AcceptInvite(ctx context.Context, filter *models.OneInviteFilter, accountID string) (*models.GroupMember, error) {
+	ctx, span := repo.startSpan(ctx, "AcceptInvite")
+	defer span.End()
+
 	group := &models.Group{}
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
@@ -258,6 +288,9 @@ func (repo *groupsRepository) AcceptInvite(ctx context.Context, filter *models.O
 }
 
 func (repo *groupsRepository) DenyInvite(ctx context.Context, filter *models.OneInviteFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "DenyInvite")
+	defer span.End()
+
 	group := &models.Group{}
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
@@ -279,6 +312,9 @@ func (repo *groupsRepository) DenyInvite(ctx context.Context, filter *models.One
 }
 
 func (repo *groupsRepository) GetInvite(ctx context.Context, filter *models.OneInviteFilter, accountID string) (*models.GroupInvite, error) {
+	ctx, span := repo.startSpan(ctx, "GetInvite")
+	defer span.End()
+
 	group := &models.Group{}
 
 	query := bson.D{
@@ -313,6 +349,9 @@ func (repo *groupsRepository) GetInvite(ctx context.Context, filter *models.OneI
 }
 
 func (repo *groupsRepository) ListInvites(ctx context.Context, filter *models.ManyInvitesFilter, lo *models.ListOptions) ([]*models.ListInvitesResult, error) {
+	ctx, span := repo.startSpan(ctx, "ListInvites")
+	defer span.End()
+
 	invites := make([]*models.ListInvitesResult, 0)
 
 	mongoDocumentMatch := bson.D{}
@@ -391,13 +430,16 @@ func (repo *groupsRepository) ListInvites(ctx context.Context, filter *models.Ma
 
 	err := repo.aggregate(ctx, mongo.Pipeline{matchQuery, filterQuery, unwindQuery, paginationSkip, paginationLimit, projectionQuery}, &invites)
 	if err != nil {
-		return nil, repo.mongoFindErrorToModelsError(mongoDocumentMatch, lo, err)
+		return nil, repo.mongoFindErrorToModelsError(ctx, mongoDocumentMatch, lo, err)
 	}
 
 	return invites, nil
 }
 
 func (repo *groupsRepository) RevokeGroupInvite(ctx context.Context, filter *models.OneInviteFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "RevokeGroupInvite")
+	defer span.End()
+
 	group := &models.Group{}
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
@@ -428,36 +470,60 @@ func (repo *groupsRepository) RevokeGroupInvite(ctx context.Context, filter *mod
 }
 
 func (repo *groupsRepository) GetConversation(ctx context.Context, filter *models.OneConversationFilter, accountID string) (*models.GroupConversation, error) {
+	ctx, span := repo.startSpan(ctx, "GetConversation")
+	defer span.End()
+
 	return nil, nil
 }
 
 func (repo *groupsRepository) UpdateConversation(ctx context.Context, filter *models.OneConversationFilter, payload *models.UpdateGroupConversationPayload, accountID string) (*models.GroupConversation, error) {
+	ctx, span := repo.startSpan(ctx, "UpdateConversation")
+	defer span.End()
+
 	return nil, nil
 }
 
 func (repo *groupsRepository) SendConversationMessage(ctx context.Context, filter *models.OneConversationFilter, accountID string) (*models.ConversationMessage, error) {
+	ctx, span := repo.startSpan(ctx, "SendConversationMessage")
+	defer span.End()
+
 	return nil, nil
 }
 
 func (repo *groupsRepository) GetConversationMessage(ctx context.Context, filter *models.OneConversationMessageFilter, accountID string) (*models.ConversationMessage, error) {
+	ctx, span := repo.startSpan(ctx, "GetConversationMessage")
+	defer span.End()
+
 	return nil, nil
 }
 
 func (repo *groupsRepository) UpdateConversationMessage(ctx context.Context, filter *models.OneConversationMessageFilter, payload *models.UpdateGroupConversationMessagePayload, accountID string) (*models.ConversationMessage, error) {
+	ctx, span := repo.startSpan(ctx, "UpdateConversationMessage")
+	defer span.End()
+
 	return nil, nil
 }
 
 func (repo *groupsRepository) DeleteConversationMessage(ctx context.Context, filter *models.OneConversationMessageFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "DeleteConversationMessage")
+	defer span.End()
+
 	return nil
 }
 
 func (repo *groupsRepository) ListConversationMessages(ctx context.Context, filter *models.OneConversationFilter, accountID string) ([]*models.ConversationMessage, error) {
+	ctx, span := repo.startSpan(ctx, "ListConversationMessages")
+	defer span.End()
+
 	return nil, nil
 }
 
 // TODO: Improve the implementation of this method because it is not going to
 // work well if in the future we need to update fields other than `isAdmin`.
 func (repo *groupsRepository) UpdateGroupMember(ctx context.Context, filter *models.OneMemberFilter, payload *models.UpdateMemberPayload, accountID string) (*models.GroupMember, error) {
+	ctx, span := repo.startSpan(ctx, "UpdateGroupMember")
+	defer span.End()
+
 	group := &models.Group{}
 
 	// NOTE: There's something very weird about the ordering of these fields.
@@ -496,6 +562,9 @@ func (repo *groupsRepository) UpdateGroupMember(ctx context.Context, filter *mod
 }
 
 func (repo *groupsRepository) UpdateGroupMemberScore(ctx context.Context, filter *models.OneMemberFilter, payload *models.UpdateMemberScorePayload, accountID string) (*models.GroupMember, error) {
+	ctx, span := repo.startSpan(ctx, "UpdateGroupMemberScore")
+	defer span.End()
+
 	group := &models.Group{}
 
 	// NOTE: There's something very weird about the ordering of these fields.
@@ -524,7 +593,42 @@ func (repo *groupsRepository) UpdateGroupMemberScore(ctx context.Context, filter
 	return group.FindMember(filter.AccountID), nil
 }
 
+func (repo *groupsRepository) ResetGroupScores(ctx context.Context, filter *models.OneGroupFilter, accountID string) (*models.Group, error) {
+	ctx, span := repo.startSpan(ctx, "ResetGroupScores")
+	defer span.End()
+
+	group := &models.Group{}
+
+	query := bson.D{
+		{Key: "_id", Value: filter.GroupID},
+		// Caller is admin.
+		{Key: "members", Value: bson.D{
+			{Key: "$elemMatch", Value: bson.D{
+				{Key: "accountId", Value: accountID},
+				{Key: "isAdmin", Value: true},
+			}},
+		}},
+	}
+	update := bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "scoreSeason", Value: 1}}},
+		{Key: "$set", Value: bson.D{
+			{Key: "members.$[].score", Value: 0},
+			{Key: "members.$[].quizTotal", Value: 0},
+			{Key: "modifiedAt", Value: time.Now()},
+		}},
+	}
+	err := repo.findOneAndUpdate(ctx, query, update, group)
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
 func (repo *groupsRepository) RemoveGroupMember(ctx context.Context, filter *models.OneMemberFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "RemoveGroupMember")
+	defer span.End()
+
 	group := &models.Group{}
 	condition := bson.E{Key: "$and", Value: bson.A{
 		// Caller is admin.
@@ -579,6 +683,9 @@ func (repo *groupsRepository) RemoveGroupMember(ctx context.Context, filter *mod
 }
 
 func (repo *groupsRepository) GenerateGroupInviteLink(ctx context.Context, filter *models.OneGroupFilter, payload *models.GenerateGroupInviteLinkPayload, accountID string) (*models.GroupInviteLink, error) {
+	ctx, span := repo.startSpan(ctx, "GenerateGroupInviteLink")
+	defer span.End()
+
 	group := &models.Group{}
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
@@ -620,6 +727,9 @@ func (repo *groupsRepository) GenerateGroupInviteLink(ctx context.Context, filte
 }
 
 func (repo *groupsRepository) GetInviteLink(ctx context.Context, filter *models.OneInviteLinkFilter, accountID string) (*models.GroupInviteLink, error) {
+	ctx, span := repo.startSpan(ctx, "GetInviteLink")
+	defer span.End()
+
 	group := &models.Group{}
 
 	query := bson.D{
@@ -644,6 +754,9 @@ func (repo *groupsRepository) GetInviteLink(ctx context.Context, filter *models.
 }
 
 func (repo *groupsRepository) RevokeInviteLink(ctx context.Context, filter *models.OneInviteLinkFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "RevokeInviteLink")
+	defer span.End()
+
 	group := &models.Group{}
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
@@ -674,6 +787,9 @@ func (repo *groupsRepository) RevokeInviteLink(ctx context.Context, filter *mode
 }
 
 func (repo *groupsRepository) UseInviteLink(ctx context.Context, filter *models.OneInviteLinkFilter, accountID string) (*models.GroupMember, error) {
+	ctx, span := repo.startSpan(ctx, "UseInviteLink")
+	defer span.End()
+
 	group := &models.Group{}
 	query := bson.D{
 		{Key: "_id", Value: filter.GroupID},
@@ -772,6 +888,9 @@ func (repo *groupsRepository) deleteWorkspaces(ctx context.Context, accountID st
 }
 
 func (repo *groupsRepository) OnAccountDelete(ctx context.Context, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "OnAccountDelete")
+	defer span.End()
+
 	err := repo.deleteEveryInviteOfAccount(ctx, accountID)
 	if err != nil {
 		repo.logger.Warn("Could not delete invites of " + accountID + " reason " + err.Error())
@@ -0,0 +1,193 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"notes-service/models"
+	"time"
+
+	"github.com/jaevor/go-nanoid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+type linksRepository struct {
+	repository
+}
+
+func NewLinksRepository(db *mongo.Database, logger *zap.Logger) models.LinksRepository {
+	newUUID, err := nanoid.Standard(21)
+	if err != nil {
+		panic(err)
+	}
+
+	repo := &linksRepository{
+		repository: repository{
+			logger:  logger.Named("mongo").Named("links"),
+			coll:    db.Collection("links"),
+			newUUID: newUUID,
+		},
+	}
+
+	// A link is keyed on its source block and its target, so that indexing
+	// the same note concurrently can't duplicate it.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = repo.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "sourceNoteId", Value: 1},
+			{Key: "sourceBlockId", Value: 1},
+			{Key: "targetNoteId", Value: 1},
+			{Key: "targetBlockId", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		repo.logger.Error("failed to create links index", zap.Error(err))
+	}
+
+	return repo
+}
+
+func (repo *linksRepository) IndexNoteLinksInternal(ctx context.Context, filter *models.OneNoteFilter, payloads []*models.NoteLinkPayload) error {
+	ctx, span := repo.startSpan(ctx, "IndexNoteLinksInternal")
+	defer span.End()
+
+	// The links are upserted then the ones of the note which are not in the
+	// payloads anymore are deleted, in one round trip.
+	writes := make([]mongo.WriteModel, 0, len(payloads)+1)
+	keys := bson.A{}
+	now := time.Now()
+	for _, payload := range payloads {
+		key := getNoteLinkKey(filter.NoteID, payload)
+		keys = append(keys, key)
+
+		update := bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "groupId", Value: filter.GroupID},
+				{Key: "broken", Value: payload.Broken},
+			}},
+			{Key: "$setOnInsert", Value: bson.D{
+				{Key: "_id", Value: repo.newUUID()},
+				{Key: "createdAt", Value: now},
+			}},
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(key).SetUpdate(update).SetUpsert(true))
+	}
+
+	stale := bson.D{{Key: "sourceNoteId", Value: filter.NoteID}}
+	if len(keys) > 0 {
+		stale = append(stale, bson.E{Key: "$nor", Value: keys})
+	}
+	writes = append(writes, mongo.NewDeleteManyModel().SetFilter(stale))
+
+	return repo.bulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true))
+}
+
+func (repo *linksRepository) ListNoteLinksInternal(ctx context.Context, filter *models.ManyNoteLinksFilter, lo *models.ListOptions) ([]*models.NoteLink, error) {
+	ctx, span := repo.startSpan(ctx, "ListNoteLinksInternal")
+	defer span.End()
+
+	links := make([]*models.NoteLink, 0)
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: 1}})
+
+	err := repo.find(ctx, getNoteLinksQuery(filter), &links, lo, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (repo *linksRepository) ListAllNoteLinksInternal(ctx context.Context, filter *models.ManyNoteLinksFilter) ([]*models.NoteLink, error) {
+	ctx, span := repo.startSpan(ctx, "ListAllNoteLinksInternal")
+	defer span.End()
+
+	links := make([]*models.NoteLink, 0)
+
+	err := repo.findAll(ctx, getNoteLinksQuery(filter), &links)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (repo *linksRepository) BreakNoteLinksInternal(ctx context.Context, filter *models.ManyNoteLinksFilter) error {
+	ctx, span := repo.startSpan(ctx, "BreakNoteLinksInternal")
+	defer span.End()
+
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "broken", Value: true}}}}
+
+	_, err := repo.updateMany(ctx, getNoteLinksQuery(filter), update)
+	return err
+}
+
+func (repo *linksRepository) RestoreNoteLinksInternal(ctx context.Context, filter *models.ManyNoteLinksFilter, blockIDs []string) error {
+	ctx, span := repo.startSpan(ctx, "RestoreNoteLinksInternal")
+	defer span.End()
+
+	if blockIDs == nil {
+		blockIDs = []string{}
+	}
+
+	query := append(getNoteLinksQuery(filter),
+		bson.E{Key: "broken", Value: true},
+		bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "targetBlockId", Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{{Key: "targetBlockId", Value: bson.D{{Key: "$in", Value: blockIDs}}}},
+		}},
+	)
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "broken", Value: false}}}}
+
+	_, err := repo.updateMany(ctx, query, update)
+	return err
+}
+
+func (repo *linksRepository) DeleteNoteLinksInternal(ctx context.Context, filter *models.ManyNoteLinksFilter) error {
+	ctx, span := repo.startSpan(ctx, "DeleteNoteLinksInternal")
+	defer span.End()
+
+	err := repo.deleteMany(ctx, getNoteLinksQuery(filter))
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// getNoteLinkKey returns the query matching the link of the payload from the
+// note, the target block is left out of the links to a whole note.
+func getNoteLinkKey(sourceNoteID string, payload *models.NoteLinkPayload) bson.D {
+	key := bson.D{
+		{Key: "sourceNoteId", Value: sourceNoteID},
+		{Key: "sourceBlockId", Value: payload.SourceBlockID},
+		{Key: "targetNoteId", Value: payload.TargetNoteID},
+	}
+	if payload.TargetBlockID != "" {
+		return append(key, bson.E{Key: "targetBlockId", Value: payload.TargetBlockID})
+	}
+	return append(key, bson.E{Key: "targetBlockId", Value: bson.D{{Key: "$exists", Value: false}}})
+}
+
+func getNoteLinksQuery(filter *models.ManyNoteLinksFilter) bson.D {
+	query := bson.D{}
+	if filter.GroupID != "" {
+		query = append(query, bson.E{Key: "groupId", Value: filter.GroupID})
+	}
+	if filter.OutsideGroupID != "" {
+		query = append(query, bson.E{Key: "groupId", Value: bson.D{{Key: "$ne", Value: filter.OutsideGroupID}}})
+	}
+	if filter.SourceNoteID != "" {
+		query = append(query, bson.E{Key: "sourceNoteId", Value: filter.SourceNoteID})
+	}
+	if filter.TargetNoteID != "" {
+		query = append(query, bson.E{Key: "targetNoteId", Value: filter.TargetNoteID})
+	}
+	if filter.TargetBlockID != "" {
+		query = append(query, bson.E{Key: "targetBlockId", Value: filter.TargetBlockID})
+	}
+	return query
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"notes-service/models"
+	"reflect"
 	"time"
 
 	notesv1 "notes-service/protorepo/noted/notes/v1"
@@ -35,8 +36,12 @@ func NewNotesRepository(db *mongo.Database, logger *zap.Logger) models.NotesRepo
 }
 
 func (repo *notesRepository) CreateNote(ctx context.Context, payload *models.CreateNotePayload, accountID string) (*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "CreateNote")
+	defer span.End()
+
 	for i := range payload.Blocks {
 		payload.Blocks[i].ID = repo.newUUID()
+		payload.Blocks[i].Revision = 1
 	}
 
 	now := time.Now()
@@ -46,7 +51,9 @@ func (repo *notesRepository) CreateNote(ctx context.Context, payload *models.Cre
 		// Create "real" empty array for mongodb golang drivers
 		for i := 0; i < len(payload.Blocks); i++ {
 			(payload.Blocks[i]).Thread = &[]models.BlockComment{}
-			(payload.Blocks[i]).Styles = &[]models.TextStyle{}
+			if (payload.Blocks[i]).Styles == nil {
+				(payload.Blocks[i]).Styles = &[]models.TextStyle{}
+			}
 		}
 		blocks = &payload.Blocks
 	} else {
@@ -58,6 +65,7 @@ func (repo *notesRepository) CreateNote(ctx context.Context, payload *models.Cre
 			Paragraph: &content,
 			Thread:    &[]models.BlockComment{},
 			Styles:    &[]models.TextStyle{},
+			Revision:  1,
 		})
 	}
 
@@ -66,6 +74,7 @@ func (repo *notesRepository) CreateNote(ctx context.Context, payload *models.Cre
 		Title:                       payload.Title,
 		AuthorAccountID:             accountID,
 		GroupID:                     payload.GroupID,
+		FolderID:                    payload.FolderID,
 		CreatedAt:                   now,
 		ModifiedAt:                  nil,
 		AnalyzedAt:                  nil,
@@ -74,6 +83,8 @@ func (repo *notesRepository) CreateNote(ctx context.Context, payload *models.Cre
 		AccountsWithEditPermissions: []string{accountID},
 		Quizs:                       &[]models.Quiz{},
 		Lang:                        payload.Lang,
+		SourceNoteID:                payload.SourceNoteID,
+		Revision:                    1,
 	}
 
 	err := repo.insertOne(ctx, &note)
@@ -83,7 +94,105 @@ func (repo *notesRepository) CreateNote(ctx context.Context, payload *models.Cre
 	return note, nil
 }
 
+func (repo *notesRepository) ImportNoteInternal(ctx context.Context, note *models.Note) (*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "ImportNoteInternal")
+	defer span.End()
+
+	note.ID = repo.newUUID()
+	note.Revision = 1
+
+	// Create "real" empty arrays for mongodb golang drivers
+	if note.Blocks == nil {
+		note.Blocks = &[]models.NoteBlock{}
+	}
+	for i := range *note.Blocks {
+		block := &(*note.Blocks)[i]
+		block.ID = repo.newUUID()
+		block.Revision = 1
+		if block.Styles == nil {
+			block.Styles = &[]models.TextStyle{}
+		}
+		if block.Thread == nil {
+			block.Thread = &[]models.BlockComment{}
+		}
+		for j := range *block.Thread {
+			(*block.Thread)[j].ID = repo.newUUID()
+		}
+	}
+	if note.Quizs == nil {
+		note.Quizs = &[]models.Quiz{}
+	}
+	for i := range *note.Quizs {
+		(*note.Quizs)[i].ID = repo.newUUID()
+	}
+	if note.Keywords == nil {
+		note.Keywords = []*models.Keyword{}
+	}
+	if note.AccountsWithEditPermissions == nil {
+		note.AccountsWithEditPermissions = []string{}
+	}
+
+	err := repo.insertOne(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+func (repo *notesRepository) MoveNote(ctx context.Context, filter *models.OneNoteFilter, payload *models.MoveNotePayload, accountID string) (*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "MoveNote")
+	defer span.End()
+
+	note := &models.Note{}
+	query := bson.D{
+		{Key: "_id", Value: filter.NoteID},
+		{Key: "groupId", Value: filter.GroupID},
+		{Key: "authorAccountId", Value: accountID},
+	}
+
+	set := bson.D{
+		{Key: "groupId", Value: payload.GroupID},
+		{Key: "accountsWithEditPermissions", Value: payload.AccountsWithEditPermissions},
+		{Key: "modifiedAt", Value: time.Now()},
+	}
+	inc := bson.D{{Key: "revision", Value: 1}}
+	if payload.DropComments {
+		set = append(set, bson.E{Key: "blocks.$[].thread", Value: bson.A{}})
+		inc = append(inc, bson.E{Key: "blocks.$[].revision", Value: 1})
+	}
+	if payload.DropQuizzes {
+		set = append(set, bson.E{Key: "quizs", Value: bson.A{}})
+	}
+	// The folders of the note belong to its previous group.
+	unset := bson.D{{Key: "folderId", Value: ""}}
+	opts := options.FindOneAndUpdate()
+	if len(payload.UnassignedAccountIDs) > 0 {
+		unset = append(unset, bson.E{Key: "blocks.$[unassigned].checklistItem.assigneeAccountId", Value: ""})
+		if !payload.DropComments {
+			inc = append(inc, bson.E{Key: "blocks.$[unassigned].revision", Value: 1})
+		}
+		opts.SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+			bson.D{{Key: "unassigned.checklistItem.assigneeAccountId", Value: bson.D{{Key: "$in", Value: payload.UnassignedAccountIDs}}}},
+		}})
+	}
+	update := bson.D{
+		{Key: "$set", Value: set},
+		{Key: "$unset", Value: unset},
+		{Key: "$inc", Value: inc},
+	}
+
+	err := repo.findOneAndUpdate(ctx, noteRevisionQuery(query, filter), update, note, opts)
+	if err != nil {
+		return nil, repo.revisionError(ctx, query, filter.ExpectedRevision, err)
+	}
+
+	return note, nil
+}
+
 func (repo *notesRepository) GetNote(ctx context.Context, filter *models.OneNoteFilter, accountID string) (*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "GetNote")
+	defer span.End()
+
 	note := &models.Note{}
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
@@ -99,6 +208,9 @@ func (repo *notesRepository) GetNote(ctx context.Context, filter *models.OneNote
 }
 
 func (repo *notesRepository) UpdateNotesInternal(ctx context.Context, filter *models.ManyNotesFilter, payload interface{}) (*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "UpdateNotesInternal")
+	defer span.End()
+
 	note := &models.Note{}
 	query := bson.D{
 		{Key: "groupId", Value: filter.GroupID},
@@ -119,6 +231,9 @@ func (repo *notesRepository) UpdateNotesInternal(ctx context.Context, filter *mo
 }
 
 func (repo *notesRepository) UpdateNote(ctx context.Context, filter *models.OneNoteFilter, payload *models.UpdateNotePayload, accountID string) (*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "UpdateNote")
+	defer span.End()
+
 	note := &models.Note{}
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
@@ -131,6 +246,7 @@ func (repo *notesRepository) UpdateNote(ctx context.Context, filter *models.OneN
 			// @note: if the id is invalid or wrong format, it's a new block, then we create a new ID
 			if len((*payload.Blocks)[i].ID) < 21 {
 				(*payload.Blocks)[i].ID = repo.newUUID()
+				(*payload.Blocks)[i].Revision = 1
 			} else {
 				// @note: Reset the "thread" field to its current value to avoid modification
 				block, err := repo.GetBlock(ctx, &models.OneBlockFilter{
@@ -148,6 +264,12 @@ func (repo *notesRepository) UpdateNote(ctx context.Context, filter *models.OneN
 					if (*payload.Blocks)[i].Styles == nil {
 						(*payload.Blocks)[i].Styles = block.Styles
 					}
+
+					// Only the blocks whose content changed get a new revision.
+					(*payload.Blocks)[i].Revision = block.Revision
+					if !sameBlockContent(&(*payload.Blocks)[i], block) {
+						(*payload.Blocks)[i].Revision++
+					}
 				} else {
 					repo.logger.Error("error while getting block in UpdateNote", zap.Error(err))
 				}
@@ -160,26 +282,41 @@ func (repo *notesRepository) UpdateNote(ctx context.Context, filter *models.OneN
 		{Key: "$set", Value: bson.D{
 			{Key: "modifiedAt", Value: time.Now()},
 		}}}
+	// @note: the keywords are not part of the content guarded by the revision
+	if payload.Title != "" || payload.Blocks != nil {
+		update = append(update, bson.E{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}})
+	}
 
-	err := repo.findOneAndUpdate(ctx, query, update, note)
+	err := repo.findOneAndUpdate(ctx, noteRevisionQuery(query, filter), update, note)
 	if err != nil {
-		return nil, err
+		return nil, repo.revisionError(ctx, query, filter.ExpectedRevision, err)
 	}
 
 	return note, nil
 }
 
 func (repo *notesRepository) DeleteNote(ctx context.Context, filter *models.OneNoteFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "DeleteNote")
+	defer span.End()
+
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
 		{Key: "groupId", Value: filter.GroupID},
 		{Key: "authorAccountId", Value: accountID},
 	}
 
-	return repo.deleteOne(ctx, query)
+	err := repo.deleteOne(ctx, noteRevisionQuery(query, filter))
+	if err != nil {
+		return repo.revisionError(ctx, query, filter.ExpectedRevision, err)
+	}
+
+	return nil
 }
 
 func (repo *notesRepository) DeleteNotes(ctx context.Context, filter *models.ManyNotesFilter) error {
+	ctx, span := repo.startSpan(ctx, "DeleteNotes")
+	defer span.End()
+
 	query := bson.D{}
 	if filter != nil {
 		if filter.AuthorAccountID != "" {
@@ -194,6 +331,9 @@ func (repo *notesRepository) DeleteNotes(ctx context.Context, filter *models.Man
 }
 
 func (repo *notesRepository) ListNotesInternal(ctx context.Context, filter *models.ManyNotesFilter, lo *models.ListOptions) ([]*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "ListNotesInternal")
+	defer span.End()
+
 	notes := make([]*models.Note, 0)
 
 	query := bson.D{}
@@ -205,7 +345,7 @@ func (repo *notesRepository) ListNotesInternal(ctx context.Context, filter *mode
 			query = append(query, bson.E{Key: "groupId", Value: filter.GroupID})
 		}
 	}
-	requiredFields := bson.D{{Key: "blocks", Value: 0}, {Key: "keywords", Value: 0}, {Key: "quizs", Value: 0}}
+	requiredFields := bson.D{{Key: "blocks", Value: 0}, {Key: "keywords", Value: 0}, {Key: "quizs", Value: 0}, {Key: "summary", Value: 0}, {Key: "embedding", Value: 0}}
 	opts := options.Find().SetProjection(requiredFields)
 
 	err := repo.find(ctx, query, &notes, lo, opts)
@@ -217,6 +357,9 @@ func (repo *notesRepository) ListNotesInternal(ctx context.Context, filter *mode
 }
 
 func (repo *notesRepository) ListAllNotesInternal(ctx context.Context, filter *models.ManyNotesFilter) ([]*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "ListAllNotesInternal")
+	defer span.End()
+
 	notes := make([]*models.Note, 0)
 
 	query := bson.D{}
@@ -227,6 +370,9 @@ func (repo *notesRepository) ListAllNotesInternal(ctx context.Context, filter *m
 		if filter.GroupID != "" {
 			query = append(query, bson.E{Key: "groupId", Value: filter.GroupID})
 		}
+		if filter.FolderID != "" {
+			query = append(query, bson.E{Key: "folderId", Value: filter.FolderID})
+		}
 	}
 
 	err := repo.findAll(ctx, query, &notes)
@@ -238,6 +384,9 @@ func (repo *notesRepository) ListAllNotesInternal(ctx context.Context, filter *m
 }
 
 func (repo *notesRepository) InsertBlock(ctx context.Context, filter *models.OneNoteFilter, payload *models.InsertNoteBlockPayload, accountID string) (*models.NoteBlock, error) {
+	ctx, span := repo.startSpan(ctx, "InsertBlock")
+	defer span.End()
+
 	payload.Block.ID = repo.newUUID()
 
 	query := bson.D{
@@ -248,6 +397,7 @@ func (repo *notesRepository) InsertBlock(ctx context.Context, filter *models.One
 
 	payload.Block.Thread = &[]models.BlockComment{} // Make non-null empty array
 	payload.Block.Styles = &[]models.TextStyle{}    // Make non-null empty array
+	payload.Block.Revision++                        // New blocks start at 1
 
 	update := bson.D{
 		{Key: "$push", Value: bson.D{
@@ -256,11 +406,12 @@ func (repo *notesRepository) InsertBlock(ctx context.Context, filter *models.One
 				{Key: "$position", Value: payload.Index},
 			}},
 		}},
+		{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
 	}
 
-	err := repo.updateOne(ctx, query, update)
+	err := repo.updateOne(ctx, noteRevisionQuery(query, filter), update)
 	if err != nil {
-		return nil, err
+		return nil, repo.revisionError(ctx, query, filter.ExpectedRevision, err)
 	}
 
 	block, err := repo.GetBlock(ctx,
@@ -279,6 +430,9 @@ func (repo *notesRepository) InsertBlock(ctx context.Context, filter *models.One
 }
 
 func (repo *notesRepository) UpdateBlock(ctx context.Context, filter *models.OneBlockFilter, payload *models.UpdateBlockPayload, accountID string) (*models.NoteBlock, error) {
+	ctx, span := repo.startSpan(ctx, "UpdateBlock")
+	defer span.End()
+
 	note := &models.Note{}
 
 	query := bson.D{
@@ -306,17 +460,24 @@ func (repo *notesRepository) UpdateBlock(ctx context.Context, filter *models.One
 
 	update := bson.D{
 		{Key: "$set", Value: setQuery},
+		{Key: "$inc", Value: bson.D{
+			{Key: "revision", Value: 1},
+			{Key: "blocks.$.revision", Value: 1},
+		}},
 	}
 
-	err := repo.findOneAndUpdate(ctx, query, update, note)
+	err := repo.findOneAndUpdate(ctx, blockRevisionQuery(query, filter), update, note)
 	if err != nil {
-		return nil, err
+		return nil, repo.revisionError(ctx, query, filter.ExpectedRevision, err)
 	}
 
 	return note.FindBlock(filter.BlockID), nil
 }
 
 func (repo *notesRepository) GetBlock(ctx context.Context, filter *models.OneBlockFilter, accountID string) (*models.NoteBlock, error) {
+	ctx, span := repo.startSpan(ctx, "GetBlock")
+	defer span.End()
+
 	note := &models.Note{}
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
@@ -332,6 +493,9 @@ func (repo *notesRepository) GetBlock(ctx context.Context, filter *models.OneBlo
 }
 
 func (repo *notesRepository) DeleteBlock(ctx context.Context, filter *models.OneBlockFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "DeleteBlock")
+	defer span.End()
+
 	note := &models.Note{}
 
 	query := bson.D{
@@ -345,12 +509,204 @@ func (repo *notesRepository) DeleteBlock(ctx context.Context, filter *models.One
 			{Key: "blocks", Value: bson.D{
 				{Key: "id", Value: filter.BlockID},
 			}},
-		}}}
+		}},
+		{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
+	}
+
+	err := repo.findOneAndUpdate(ctx, blockRevisionQuery(query, filter), update, note)
+	if err != nil {
+		return repo.revisionError(ctx, query, filter.ExpectedRevision, err)
+	}
+
+	return nil
+}
+
+func (repo *notesRepository) BatchUpdateBlocks(ctx context.Context, filter *models.OneNoteFilter, operations []models.BlockOperation, accountID string) (*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "BatchUpdateBlocks")
+	defer span.End()
+
+	return repo.updateBlocks(ctx, filter, accountID, func(blocks []models.NoteBlock) ([]models.NoteBlock, error) {
+		return repo.applyBlockOperations(blocks, operations)
+	})
+}
+
+func (repo *notesRepository) MoveBlock(ctx context.Context, filter *models.OneBlockFilter, payload *models.MoveBlockPayload, accountID string) (*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "MoveBlock")
+	defer span.End()
+
+	return repo.updateBlocks(ctx, &models.OneNoteFilter{GroupID: filter.GroupID, NoteID: filter.NoteID}, accountID, func(blocks []models.NoteBlock) ([]models.NoteBlock, error) {
+		return moveBlocks(blocks, filter.BlockID, filter.ExpectedRevision, payload.Count, payload.Index)
+	})
+}
+
+// Number of times the blocks are changed again from the latest ones when the
+// note is modified while they are changed.
+const maxUpdateBlocksAttempts = 3
+
+// updateBlocks replaces the blocks of the note by the result of apply in a
+// single update, as long as nobody modified them since they were read.
+func (repo *notesRepository) updateBlocks(ctx context.Context, filter *models.OneNoteFilter, accountID string, apply func(blocks []models.NoteBlock) ([]models.NoteBlock, error)) (*models.Note, error) {
+	query := bson.D{
+		{Key: "_id", Value: filter.NoteID},
+		{Key: "groupId", Value: filter.GroupID},
+		{Key: "authorAccountId", Value: accountID},
+	}
+
+	for attempt := 0; attempt < maxUpdateBlocksAttempts; attempt++ {
+		note := &models.Note{}
+		err := repo.findOne(ctx, query, note)
+		if err != nil {
+			return nil, err
+		}
+		if filter.ExpectedRevision != 0 && note.Revision != filter.ExpectedRevision {
+			return nil, models.ErrConflict
+		}
+
+		blocks := []models.NoteBlock{}
+		if note.Blocks != nil {
+			blocks = append(blocks, *note.Blocks...)
+		}
+		blocks, err = apply(blocks)
+		if err != nil {
+			return nil, err
+		}
+
+		// @note: the blocks are only replaced if nobody modified them since they were read
+		update := bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "blocks", Value: blocks},
+				{Key: "modifiedAt", Value: time.Now()},
+			}},
+			{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
+		}
+		revisionQuery := append(query[:len(query):len(query)], revisionEquals(note.Revision))
+
+		err = repo.findOneAndUpdate(ctx, revisionQuery, update, note)
+		if err == nil {
+			return note, nil
+		}
+		if !errors.Is(err, models.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, models.ErrConflict
+}
+
+func (repo *notesRepository) applyBlockOperations(blocks []models.NoteBlock, operations []models.BlockOperation) ([]models.NoteBlock, error) {
+	for _, operation := range operations {
+		if operation.Type == models.BlockOperationInsert {
+			block := operation.Block
+			block.ID = repo.newUUID()
+			block.Revision = 1
+			block.Thread = &[]models.BlockComment{} // Make non-null empty array
+			if block.Styles == nil {
+				block.Styles = &[]models.TextStyle{} // Make non-null empty array
+			}
+			blocks = insertBlockAt(blocks, block, operation.Index)
+			continue
+		}
+
+		if operation.Type == models.BlockOperationMove {
+			var err error
+			blocks, err = moveBlocks(blocks, operation.BlockID, operation.ExpectedRevision, operation.Count, operation.Index)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		index, err := findBlockIndex(blocks, operation.BlockID, operation.ExpectedRevision)
+		if err != nil {
+			return nil, err
+		}
+
+		switch operation.Type {
+		case models.BlockOperationUpdate:
+			// @note: like UpdateBlock, keep the comments and the styles when none are given
+			block := operation.Block
+			block.ID = blocks[index].ID
+			block.Revision = blocks[index].Revision + 1
+			block.Thread = blocks[index].Thread
+			if block.Styles == nil || len(*block.Styles) == 0 {
+				block.Styles = blocks[index].Styles
+			}
+			blocks[index] = block
+		case models.BlockOperationDelete:
+			blocks = append(blocks[:index], blocks[index+1:]...)
+		default:
+			return nil, models.ErrUnknown
+		}
+	}
+
+	return blocks, nil
+}
+
+// findBlockIndex returns the index of the block, ErrConflict if it is not at
+// the expected revision.
+func findBlockIndex(blocks []models.NoteBlock, blockID string, expectedRevision int64) (int, error) {
+	for i := range blocks {
+		if blocks[i].ID != blockID {
+			continue
+		}
+		if expectedRevision != 0 && blocks[i].Revision != expectedRevision {
+			return -1, models.ErrConflict
+		}
+		return i, nil
+	}
+
+	return -1, models.ErrNotFound
+}
+
+// moveBlocks moves count blocks starting at the block so that the first one is
+// at the index once moved. The range stops at the last block and blocks past
+// the end are appended.
+func moveBlocks(blocks []models.NoteBlock, blockID string, expectedRevision int64, count uint, index uint) ([]models.NoteBlock, error) {
+	start, err := findBlockIndex(blocks, blockID, expectedRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	end := start + 1
+	if count > 1 {
+		end = start + int(count)
+	}
+	if end > len(blocks) {
+		end = len(blocks)
+	}
+
+	moved := append([]models.NoteBlock{}, blocks[start:end]...)
+	rest := append(append([]models.NoteBlock{}, blocks[:start]...), blocks[end:]...)
+	if index > uint(len(rest)) {
+		index = uint(len(rest))
+	}
+
+	result := make([]models.NoteBlock, 0, len(blocks))
+	result = append(result, rest[:index]...)
+	result = append(result, moved...)
+	result = append(result, rest[index:]...)
+
+	return result, nil
+}
 
-	return repo.findOneAndUpdate(ctx, query, update, note)
+// insertBlockAt inserts the block at the index, or after the last block if the
+// index is past the end.
+func insertBlockAt(blocks []models.NoteBlock, block models.NoteBlock, index uint) []models.NoteBlock {
+	if index > uint(len(blocks)) {
+		index = uint(len(blocks))
+	}
+
+	blocks = append(blocks, models.NoteBlock{})
+	copy(blocks[index+1:], blocks[index:])
+	blocks[index] = block
+
+	return blocks
 }
 
 func (repo *notesRepository) GrantNoteEditPermission(ctx context.Context, filter *models.OneNoteFilter, AccountID string, recipientAccountID string) error {
+	ctx, span := repo.startSpan(ctx, "GrantNoteEditPermission")
+	defer span.End()
+
 	note := &models.Note{}
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
@@ -361,10 +717,11 @@ func (repo *notesRepository) GrantNoteEditPermission(ctx context.Context, filter
 		{Key: "$push", Value: bson.D{
 			{Key: "accountsWithEditPermissions", Value: recipientAccountID},
 		}},
+		{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
 	}
-	err := repo.findOneAndUpdate(ctx, query, update, note)
+	err := repo.findOneAndUpdate(ctx, noteRevisionQuery(query, filter), update, note)
 	if err != nil {
-		return err
+		return repo.revisionError(ctx, query, filter.ExpectedRevision, err)
 	}
 
 	return nil
@@ -373,6 +730,9 @@ func (repo *notesRepository) GrantNoteEditPermission(ctx context.Context, filter
 // If filter is set to nil, every edit permissions of his will be deleted on the db
 // If filter is not set to nil, GroupID is mandatory. To specify one note, fill NoteID
 func (repo *notesRepository) RemoveEditPermissions(ctx context.Context, filter *models.OneNoteFilter, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "RemoveEditPermissions")
+	defer span.End()
+
 	query := bson.D{
 		{Key: "accountsWithEditPermissions", Value: accountID}, // NOTE: MongoDB model logic is not "safe" here - Who/What can call this function is decided in the endpoint's logic
 	}
@@ -391,13 +751,32 @@ func (repo *notesRepository) RemoveEditPermissions(ctx context.Context, filter *
 		{Key: "$pull", Value: bson.D{
 			{Key: "accountsWithEditPermissions", Value: accountID},
 		}},
+		{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
 	}
 
-	_, err := repo.updateMany(ctx, query, update)
+	if filter == nil || filter.ExpectedRevision == 0 {
+		_, err := repo.updateMany(ctx, query, update)
+		return err
+	}
+
+	modified, err := repo.updateMany(ctx, noteRevisionQuery(query, filter), update)
+	if err != nil || modified > 0 {
+		return err
+	}
+
+	// There is nothing to remove, unless the note was modified since the
+	// expected revision.
+	err = repo.revisionError(ctx, query, filter.ExpectedRevision, models.ErrNotFound)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
 	return err
 }
 
 func (repo *notesRepository) CreateBlockComment(ctx context.Context, filter *models.OneBlockFilter, payload *models.BlockComment, accountID string) (*models.BlockComment, error) {
+	ctx, span := repo.startSpan(ctx, "CreateBlockComment")
+	defer span.End()
+
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
 		{Key: "groupId", Value: filter.GroupID},
@@ -417,11 +796,15 @@ func (repo *notesRepository) CreateBlockComment(ctx context.Context, filter *mod
 				Content:         payload.Content,
 			}},
 		}},
+		{Key: "$inc", Value: bson.D{
+			{Key: "revision", Value: 1},
+			{Key: "blocks.$.revision", Value: 1},
+		}},
 	}
 
-	err := repo.updateOne(ctx, query, update)
+	err := repo.updateOne(ctx, blockRevisionQuery(query, filter), update)
 	if err != nil {
-		return nil, err
+		return nil, repo.revisionError(ctx, query, filter.ExpectedRevision, err)
 	}
 
 	res, err := repo.GetBlock(ctx, &models.OneBlockFilter{
@@ -434,6 +817,9 @@ func (repo *notesRepository) CreateBlockComment(ctx context.Context, filter *mod
 }
 
 func (repo *notesRepository) ListBlockComments(ctx context.Context, filter *models.OneBlockFilter, lo *models.ListOptions, accountID string) (*[]models.BlockComment, error) {
+	ctx, span := repo.startSpan(ctx, "ListBlockComments")
+	defer span.End()
+
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
 		{Key: "groupId", Value: filter.GroupID},
@@ -457,33 +843,51 @@ func (repo *notesRepository) ListBlockComments(ctx context.Context, filter *mode
 }
 
 func (repo *notesRepository) DeleteBlockComment(ctx context.Context, filter *models.OneBlockFilter, payload *models.BlockComment, accountID string) (*models.BlockComment, error) {
-	query := bson.D{
-		{Key: "_id", Value: filter.NoteID},
-		{Key: "groupId", Value: filter.GroupID},
-		{Key: "blocks.id", Value: filter.BlockID},
-		// {Key: "$or", Value: bson.A{
-		// 	bson.D{{Key: "accountsWithEditPermissions", Value: accountID}},
-		// 	bson.D{{Key: "authorAccountId", Value: accountID}},
-		// }},
+	ctx, span := repo.startSpan(ctx, "DeleteBlockComment")
+	defer span.End()
+
+	// The comment of the account is matched along with its block so that the
+	// revisions are only incremented when it is removed.
+	comment := bson.D{
+		{Key: "id", Value: payload.ID},
+		{Key: "authorAccountId", Value: accountID},
+	}
+	blockQuery := func(revision int64) bson.D {
+		block := bson.D{
+			{Key: "id", Value: filter.BlockID},
+			{Key: "thread", Value: bson.D{{Key: "$elemMatch", Value: comment}}},
+		}
+		if revision != 0 {
+			block = append(block, bson.E{Key: "revision", Value: revision})
+		}
+		return bson.D{
+			{Key: "_id", Value: filter.NoteID},
+			{Key: "groupId", Value: filter.GroupID},
+			{Key: "blocks", Value: bson.D{{Key: "$elemMatch", Value: block}}},
+		}
 	}
 
 	update := bson.D{
 		{Key: "$pull", Value: bson.D{
-			{Key: "blocks.$.thread", Value: bson.D{
-				{Key: "id", Value: payload.ID},
-				{Key: "authorAccountId", Value: accountID},
-			}},
+			{Key: "blocks.$.thread", Value: comment},
+		}},
+		{Key: "$inc", Value: bson.D{
+			{Key: "revision", Value: 1},
+			{Key: "blocks.$.revision", Value: 1},
 		}},
 	}
 
-	err := repo.updateOne(ctx, query, update)
+	err := repo.updateOne(ctx, blockQuery(filter.ExpectedRevision), update)
 	if err != nil {
-		return nil, err
+		return nil, repo.revisionError(ctx, blockQuery(0), filter.ExpectedRevision, err)
 	}
 	return payload, err
 }
 
 func (repo *notesRepository) StoreNewQuiz(ctx context.Context, filter *models.OneNoteFilter, payload *models.Quiz, accountID string) (*models.Quiz, error) {
+	ctx, span := repo.startSpan(ctx, "StoreNewQuiz")
+	defer span.End()
+
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
 		{Key: "groupId", Value: filter.GroupID},
@@ -506,6 +910,9 @@ func (repo *notesRepository) StoreNewQuiz(ctx context.Context, filter *models.On
 }
 
 func (repo *notesRepository) ListQuizs(ctx context.Context, filter *models.OneNoteFilter, accountID string) (*[]models.Quiz, error) {
+	ctx, span := repo.startSpan(ctx, "ListQuizs")
+	defer span.End()
+
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
 		{Key: "groupId", Value: filter.GroupID},
@@ -526,6 +933,9 @@ func (repo *notesRepository) ListQuizs(ctx context.Context, filter *models.OneNo
 
 // This function is used to put an expiration date on all Quizs after a server reboot (background services)
 func (repo *notesRepository) ListQuizsCreatedDateInternal(ctx context.Context) (*[]models.Quiz, error) {
+	ctx, span := repo.startSpan(ctx, "ListQuizsCreatedDateInternal")
+	defer span.End()
+
 	unwind := bson.D{{
 		Key: "$unwind", Value: "$quizs",
 	}}
@@ -546,7 +956,96 @@ func (repo *notesRepository) ListQuizsCreatedDateInternal(ctx context.Context) (
 	return res, nil
 }
 
+func (repo *notesRepository) StoreSummaryInternal(ctx context.Context, filter *models.OneNoteFilter, payload *models.Summary) (*models.Summary, error) {
+	ctx, span := repo.startSpan(ctx, "StoreSummaryInternal")
+	defer span.End()
+
+	query := bson.D{
+		{Key: "_id", Value: filter.NoteID},
+		{Key: "groupId", Value: filter.GroupID},
+	}
+
+	payload.CreatedAt = time.Now()
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "summary", Value: payload},
+		}},
+	}
+
+	err := repo.updateOne(ctx, query, update)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (repo *notesRepository) StoreEmbeddingInternal(ctx context.Context, filter *models.OneNoteFilter, payload *models.NoteEmbedding) error {
+	ctx, span := repo.startSpan(ctx, "StoreEmbeddingInternal")
+	defer span.End()
+
+	query := bson.D{
+		{Key: "_id", Value: filter.NoteID},
+		{Key: "groupId", Value: filter.GroupID},
+	}
+
+	payload.UpdatedAt = time.Now()
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "embedding", Value: payload},
+		}},
+	}
+
+	return repo.updateOne(ctx, query, update)
+}
+
+func (repo *notesRepository) StoreBlockEmbeddingsInternal(ctx context.Context, filter *models.OneNoteFilter, payload []*models.NoteEmbedding) error {
+	ctx, span := repo.startSpan(ctx, "StoreBlockEmbeddingsInternal")
+	defer span.End()
+
+	query := bson.D{
+		{Key: "_id", Value: filter.NoteID},
+		{Key: "groupId", Value: filter.GroupID},
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "blockEmbeddings", Value: payload},
+		}},
+	}
+
+	return repo.updateOne(ctx, query, update)
+}
+
+func (repo *notesRepository) ListEmbeddingsInternal(ctx context.Context, filter *models.ManyEmbeddingsFilter) ([]*models.Note, error) {
+	ctx, span := repo.startSpan(ctx, "ListEmbeddingsInternal")
+	defer span.End()
+
+	notes := make([]*models.Note, 0)
+
+	query := bson.D{
+		{Key: "groupId", Value: bson.D{{Key: "$in", Value: filter.GroupIDs}}},
+		{Key: "embedding.model", Value: filter.Model},
+	}
+	requiredFields := bson.D{{Key: "_id", Value: 1}, {Key: "groupId", Value: 1}, {Key: "title", Value: 1}, {Key: "embedding", Value: 1}}
+	opts := options.Find().SetProjection(requiredFields).SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+
+	err := repo.findAll(ctx, query, &notes, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
 func (repo *notesRepository) DeleteQuiz(ctx context.Context, filter *models.OneNoteFilter, quizID string, accountID string) error {
+	ctx, span := repo.startSpan(ctx, "DeleteQuiz")
+	defer span.End()
+
 	query := bson.D{
 		{Key: "_id", Value: filter.NoteID},
 		{Key: "groupId", Value: filter.GroupID},
@@ -568,6 +1067,9 @@ func (repo *notesRepository) DeleteQuiz(ctx context.Context, filter *models.OneN
 }
 
 func (repo *notesRepository) DeleteQuizFromIDInternal(ctx context.Context, quizID string) error {
+	ctx, span := repo.startSpan(ctx, "DeleteQuizFromIDInternal")
+	defer span.End()
+
 	query := bson.D{}
 
 	update := bson.D{
@@ -602,6 +1104,94 @@ func updateBlockPayloadToDocument(payload *models.UpdateBlockPayload) bson.E {
 		return bson.E{Key: "blocks.$.image", Value: payload.Block.Image}
 	case notesv1.Block_TYPE_CODE.String():
 		return bson.E{Key: "blocks.$.code", Value: payload.Block.Code}
+	case notesv1.Block_TYPE_SUMMARY.String():
+		return bson.E{Key: "blocks.$.summary", Value: payload.Block.Summary}
+	case notesv1.Block_TYPE_CHECKLIST_ITEM.String():
+		return bson.E{Key: "blocks.$.checklistItem", Value: payload.Block.ChecklistItem}
+	case notesv1.Block_TYPE_TABLE.String():
+		return bson.E{Key: "blocks.$.table", Value: payload.Block.Table}
+	case notesv1.Block_TYPE_CALLOUT.String():
+		return bson.E{Key: "blocks.$.callout", Value: payload.Block.Callout}
+	case notesv1.Block_TYPE_EMBED.String():
+		return bson.E{Key: "blocks.$.embed", Value: payload.Block.Embed}
+	case notesv1.Block_TYPE_DIVIDER.String():
+		// Dividers have no data, only their type is set.
+		return bson.E{Key: "", Value: nil}
 	}
 	return bson.E{Key: "", Value: nil}
 }
+
+// sameBlockContent returns whether the blocks only differ by their revision.
+// Empty styles and threads are the same as none.
+func sameBlockContent(a *models.NoteBlock, b *models.NoteBlock) bool {
+	return reflect.DeepEqual(blockContent(a), blockContent(b))
+}
+
+func blockContent(block *models.NoteBlock) models.NoteBlock {
+	content := *block
+	content.Revision = 0
+	if content.Styles != nil && len(*content.Styles) == 0 {
+		content.Styles = nil
+	}
+	if content.Thread != nil && len(*content.Thread) == 0 {
+		content.Thread = nil
+	}
+	return content
+}
+
+// revisionError returns ErrConflict when a write made at an expected revision
+// matched nothing only because the note was modified since, err otherwise.
+func (repo *notesRepository) revisionError(ctx context.Context, query bson.D, expectedRevision int64, err error) error {
+	if expectedRevision == 0 || !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+
+	err = repo.findOne(ctx, query, &models.Note{})
+	if err != nil {
+		return err
+	}
+
+	return models.ErrConflict
+}
+
+// noteRevisionQuery restricts the query to the expected revision of the note,
+// if any.
+func noteRevisionQuery(query bson.D, filter *models.OneNoteFilter) bson.D {
+	if filter.ExpectedRevision == 0 {
+		return query
+	}
+
+	return append(query[:len(query):len(query)], bson.E{Key: "revision", Value: filter.ExpectedRevision})
+}
+
+// revisionEquals matches the notes at the revision. The notes stored before
+// revisions were introduced have none and match revision 0.
+func revisionEquals(revision int64) bson.E {
+	if revision == 0 {
+		return bson.E{Key: "revision", Value: bson.D{{Key: "$in", Value: bson.A{0, nil}}}}
+	}
+
+	return bson.E{Key: "revision", Value: revision}
+}
+
+// blockRevisionQuery restricts the query to the expected revision of the block,
+// if any. The block is matched with $elemMatch so that the positional operator
+// of the update still designates it.
+func blockRevisionQuery(query bson.D, filter *models.OneBlockFilter) bson.D {
+	if filter.ExpectedRevision == 0 {
+		return query
+	}
+
+	revisionQuery := bson.D{}
+	for _, elem := range query {
+		if elem.Key == "blocks.id" {
+			elem = bson.E{Key: "blocks", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+				{Key: "id", Value: filter.BlockID},
+				{Key: "revision", Value: filter.ExpectedRevision},
+			}}}}
+		}
+		revisionQuery = append(revisionQuery, elem)
+	}
+
+	return revisionQuery
+}
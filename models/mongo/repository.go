@@ -3,10 +3,18 @@ package mongo
 import (
 	"context"
 	"errors"
+	"notes-service/metrics"
 	"notes-service/models"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -16,28 +24,71 @@ type repository struct {
 	newUUID func() string
 }
 
+var tracer = otel.Tracer("notes-service/models/mongo")
+
+var operationDuration = promauto.With(metrics.Registry).NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "notes_mongo_operation_duration_seconds",
+		Help: "Duration of the mongo operations by collection, repository method and operation.",
+	},
+	[]string{"collection", "method", "operation"},
+)
+
+type methodContextKey struct{}
+
+// observe records the duration of the operation of the helper deferring it,
+// labelled with the repository method given to startSpan.
+func (repo *repository) observe(ctx context.Context, operation string, start time.Time) {
+	method, _ := ctx.Value(methodContextKey{}).(string)
+	operationDuration.WithLabelValues(repo.coll.Name(), method, operation).Observe(time.Since(start).Seconds())
+}
+
+// startSpan starts the span of the repository method, the span must be ended
+// by the caller. The method also labels the metrics of the helpers it calls.
+func (repo *repository) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	ctx = context.WithValue(ctx, methodContextKey{}, method)
+	return tracer.Start(ctx, "mongo."+repo.coll.Name()+"."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.mongodb.collection", repo.coll.Name()),
+			attribute.String("db.operation", method),
+		),
+	)
+}
+
+// recordSpanError marks the span of the repository method as failed. Not found
+// and duplicate key errors are expected and are not recorded.
+func recordSpanError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
 func (repo *repository) aggregate(ctx context.Context, pipeline interface{}, result interface{}, opts ...*options.AggregateOptions) error {
+	defer repo.observe(ctx, "aggregate", time.Now())
 	repo.logger.Debug("aggregate", zap.Any("pipeline", pipeline))
 
 	cur, err := repo.coll.Aggregate(ctx, pipeline, opts...)
 	if err != nil {
-		return repo.mongoAggregateErrorToModelsError(pipeline, err)
+		return repo.mongoAggregateErrorToModelsError(ctx, pipeline, err)
 	}
 
 	cur.All(ctx, result)
 	if err != nil {
-		return repo.mongoAggregateErrorToModelsError(pipeline, err)
+		return repo.mongoAggregateErrorToModelsError(ctx, pipeline, err)
 	}
 
 	return nil
 }
 
 func (repo *repository) updateOne(ctx context.Context, query interface{}, update interface{}, opts ...*options.UpdateOptions) error {
+	defer repo.observe(ctx, "update_one", time.Now())
 	repo.logger.Debug("update one", zap.Any("query", query), zap.Any("update", update))
 	res, err := repo.coll.UpdateOne(ctx, query, update, opts...)
 
 	if err != nil {
-		return repo.mongoUpdateOneErrorToModelsError(query, update, err)
+		return repo.mongoUpdateOneErrorToModelsError(ctx, query, update, err)
 	}
 	if res.ModifiedCount == 0 {
 		return models.ErrNotFound
@@ -46,30 +97,33 @@ func (repo *repository) updateOne(ctx context.Context, query interface{}, update
 }
 
 func (repo *repository) updateMany(ctx context.Context, query interface{}, update interface{}, opts ...*options.UpdateOptions) (int64, error) {
+	defer repo.observe(ctx, "update_many", time.Now())
 	repo.logger.Debug("update many", zap.Any("query", query), zap.Any("update", update))
 	res, err := repo.coll.UpdateMany(ctx, query, update, opts...)
 
 	if err != nil {
-		return 0, repo.mongoUpdateManyErrorToModelsError(query, update, err)
+		return 0, repo.mongoUpdateManyErrorToModelsError(ctx, query, update, err)
 	}
 	return res.ModifiedCount, nil
 }
 
 func (repo *repository) findOneAndUpdate(ctx context.Context, query interface{}, update interface{}, result interface{}, opts ...*options.FindOneAndUpdateOptions) error {
+	defer repo.observe(ctx, "find_one_and_update", time.Now())
 	repo.logger.Debug("find one and update", zap.Any("query", query), zap.Any("update", update))
 	opts = append(opts, options.FindOneAndUpdate().SetReturnDocument(options.After))
 	err := repo.coll.FindOneAndUpdate(ctx, query, update, opts...).Decode(result)
 	if err != nil {
-		return repo.mongoFindOneAndUpdateErrorToModelsError(query, update, err)
+		return repo.mongoFindOneAndUpdateErrorToModelsError(ctx, query, update, err)
 	}
 	return nil
 }
 
 func (repo *repository) deleteOne(ctx context.Context, query interface{}, opts ...*options.DeleteOptions) error {
+	defer repo.observe(ctx, "delete_one", time.Now())
 	repo.logger.Debug("delete one", zap.Any("query", query))
 	res, err := repo.coll.DeleteOne(ctx, query, opts...)
 	if err != nil {
-		return repo.mongoDeleteOneErrorToModelsError(query, err)
+		return repo.mongoDeleteOneErrorToModelsError(ctx, query, err)
 	}
 	if res.DeletedCount == 0 {
 		return models.ErrNotFound
@@ -78,10 +132,11 @@ func (repo *repository) deleteOne(ctx context.Context, query interface{}, opts .
 }
 
 func (repo *repository) deleteMany(ctx context.Context, query interface{}, opts ...*options.DeleteOptions) error {
+	defer repo.observe(ctx, "delete_many", time.Now())
 	repo.logger.Debug("delete many", zap.Any("query", query))
 	res, err := repo.coll.DeleteMany(ctx, query, opts...)
 	if err != nil {
-		return repo.mongoDeleteManyErrorToModelsError(query, err)
+		return repo.mongoDeleteManyErrorToModelsError(ctx, query, err)
 	}
 	if res.DeletedCount == 0 {
 		return models.ErrNotFound
@@ -90,24 +145,37 @@ func (repo *repository) deleteMany(ctx context.Context, query interface{}, opts
 }
 
 func (repo *repository) findOne(ctx context.Context, query interface{}, result interface{}, opts ...*options.FindOneOptions) error {
+	defer repo.observe(ctx, "find_one", time.Now())
 	repo.logger.Debug("find one", zap.Any("query", query))
 	err := repo.coll.FindOne(ctx, query, opts...).Decode(result)
 	if err != nil {
-		return repo.mongoFindOneErrorToModelsError(query, err)
+		return repo.mongoFindOneErrorToModelsError(ctx, query, err)
 	}
 	return nil
 }
 
 func (repo *repository) insertOne(ctx context.Context, payload interface{}, opts ...*options.InsertOneOptions) error {
+	defer repo.observe(ctx, "insert_one", time.Now())
 	repo.logger.Debug("insert one", zap.Any("payload", payload))
 	_, err := repo.coll.InsertOne(ctx, payload, opts...)
 	if err != nil {
-		return repo.mongoInsertOneErrorToModelsError(payload, err)
+		return repo.mongoInsertOneErrorToModelsError(ctx, payload, err)
+	}
+	return nil
+}
+
+func (repo *repository) bulkWrite(ctx context.Context, writes []mongo.WriteModel, opts ...*options.BulkWriteOptions) error {
+	defer repo.observe(ctx, "bulk_write", time.Now())
+	repo.logger.Debug("bulk write", zap.Any("writes", writes))
+	_, err := repo.coll.BulkWrite(ctx, writes, opts...)
+	if err != nil {
+		return repo.mongoBulkWriteErrorToModelsError(ctx, writes, err)
 	}
 	return nil
 }
 
 func (repo *repository) find(ctx context.Context, query interface{}, results interface{}, lo *models.ListOptions, opts ...*options.FindOptions) error {
+	defer repo.observe(ctx, "find", time.Now())
 	repo.logger.Debug("find", zap.Any("query", query))
 	if lo == nil {
 		lo = &models.ListOptions{Limit: 20, Offset: 0}
@@ -117,97 +185,117 @@ func (repo *repository) find(ctx context.Context, query interface{}, results int
 
 	res, err := repo.coll.Find(ctx, query, opts...)
 	if err != nil {
-		return repo.mongoFindErrorToModelsError(query, lo, err)
+		return repo.mongoFindErrorToModelsError(ctx, query, lo, err)
 	}
 
 	err = res.All(ctx, results)
 	if err != nil {
-		return repo.mongoFindErrorToModelsError(query, lo, err)
+		return repo.mongoFindErrorToModelsError(ctx, query, lo, err)
 	}
 
 	return nil
 }
 
 func (repo *repository) findAll(ctx context.Context, query interface{}, results interface{}, opts ...*options.FindOptions) error {
+	defer repo.observe(ctx, "find_all", time.Now())
 	repo.logger.Debug("find", zap.Any("query", query))
 
 	res, err := repo.coll.Find(ctx, query, opts...)
 	if err != nil {
-		return repo.mongoFindAllErrorToModelsError(query, err)
+		return repo.mongoFindAllErrorToModelsError(ctx, query, err)
 	}
 
 	err = res.All(ctx, results)
 	if err != nil {
-		return repo.mongoFindAllErrorToModelsError(query, err)
+		return repo.mongoFindAllErrorToModelsError(ctx, query, err)
 	}
 
 	return nil
 }
 
-func (repo *repository) mongoAggregateErrorToModelsError(query interface{}, err error) error {
+func (repo *repository) mongoAggregateErrorToModelsError(ctx context.Context, query interface{}, err error) error {
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return models.ErrNotFound
 	}
 	repo.logger.Error("aggregate failed", zap.Any("query", query), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoFindOneErrorToModelsError(query interface{}, err error) error {
+func (repo *repository) mongoFindOneErrorToModelsError(ctx context.Context, query interface{}, err error) error {
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return models.ErrNotFound
 	}
 	repo.logger.Error("find one failed", zap.Any("query", query), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoDeleteOneErrorToModelsError(query interface{}, err error) error {
+func (repo *repository) mongoDeleteOneErrorToModelsError(ctx context.Context, query interface{}, err error) error {
 	repo.logger.Error("delete one failed", zap.Any("query", query), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoDeleteManyErrorToModelsError(query interface{}, err error) error {
+func (repo *repository) mongoDeleteManyErrorToModelsError(ctx context.Context, query interface{}, err error) error {
 	repo.logger.Error("delete many failed", zap.Any("query", query), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoInsertOneErrorToModelsError(query interface{}, err error) error {
+func (repo *repository) mongoInsertOneErrorToModelsError(ctx context.Context, query interface{}, err error) error {
 	if mongo.IsDuplicateKeyError(err) {
 		return models.ErrAlreadyExists
 	}
 	repo.logger.Error("find one failed", zap.Any("query", query), zap.Error(err))
+	recordSpanError(ctx, err)
+	return models.ErrUnknown
+}
+
+func (repo *repository) mongoBulkWriteErrorToModelsError(ctx context.Context, writes interface{}, err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return models.ErrAlreadyExists
+	}
+	repo.logger.Error("bulk write failed", zap.Any("writes", writes), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoFindOneAndUpdateErrorToModelsError(query interface{}, update interface{}, err error) error {
+func (repo *repository) mongoFindOneAndUpdateErrorToModelsError(ctx context.Context, query interface{}, update interface{}, err error) error {
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return models.ErrNotFound
 	}
 	repo.logger.Error("find one and update failed", zap.Any("query", query), zap.Any("update", update), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoUpdateOneErrorToModelsError(query interface{}, update interface{}, err error) error {
+func (repo *repository) mongoUpdateOneErrorToModelsError(ctx context.Context, query interface{}, update interface{}, err error) error {
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return models.ErrNotFound
 	}
 	repo.logger.Error("update one failed", zap.Any("query", query), zap.Any("update", update), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoUpdateManyErrorToModelsError(query interface{}, update interface{}, err error) error {
+func (repo *repository) mongoUpdateManyErrorToModelsError(ctx context.Context, query interface{}, update interface{}, err error) error {
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return models.ErrNotFound
 	}
 	repo.logger.Error("update many failed", zap.Any("query", query), zap.Any("update", update), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoFindErrorToModelsError(query interface{}, lo *models.ListOptions, err error) error {
+func (repo *repository) mongoFindErrorToModelsError(ctx context.Context, query interface{}, lo *models.ListOptions, err error) error {
 	repo.logger.Error("find failed", zap.Any("query", query), zap.Int32("limit", lo.Limit), zap.Int32("offset", lo.Offset), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
 
-func (repo *repository) mongoFindAllErrorToModelsError(query interface{}, err error) error {
+func (repo *repository) mongoFindAllErrorToModelsError(ctx context.Context, query interface{}, err error) error {
 	repo.logger.Error("findAll failed", zap.Any("query", query), zap.Error(err))
+	recordSpanError(ctx, err)
 	return models.ErrUnknown
 }
@@ -0,0 +1,115 @@
+package mongo
+
+import (
+	"context"
+	"notes-service/models"
+	"time"
+
+	"github.com/jaevor/go-nanoid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+type scoresRepository struct {
+	repository
+}
+
+func NewScoresRepository(db *mongo.Database, logger *zap.Logger) models.ScoresRepository {
+	newUUID, err := nanoid.Standard(21)
+	if err != nil {
+		panic(err)
+	}
+
+	return &scoresRepository{
+		repository: repository{
+			logger:  logger.Named("mongo").Named("scores"),
+			coll:    db.Collection("scores"),
+			newUUID: newUUID,
+		},
+	}
+}
+
+func (repo *scoresRepository) CreateScoreEventInternal(ctx context.Context, payload *models.ScoreEventPayload) (*models.ScoreEvent, error) {
+	ctx, span := repo.startSpan(ctx, "CreateScoreEventInternal")
+	defer span.End()
+
+	event := &models.ScoreEvent{
+		ID:        repo.newUUID(),
+		GroupID:   payload.GroupID,
+		AccountID: payload.AccountID,
+		Season:    payload.Season,
+		Score:     payload.Score,
+		Responses: payload.Responses,
+		CreatedAt: time.Now(),
+	}
+
+	err := repo.insertOne(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (repo *scoresRepository) ListScoreEventsInternal(ctx context.Context, filter *models.ManyScoreEventsFilter, lo *models.ListOptions) ([]*models.ScoreEvent, error) {
+	ctx, span := repo.startSpan(ctx, "ListScoreEventsInternal")
+	defer span.End()
+
+	events := make([]*models.ScoreEvent, 0)
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	err := repo.find(ctx, getScoreEventsQuery(filter), &events, lo, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (repo *scoresRepository) GetLeaderboardInternal(ctx context.Context, filter *models.ManyScoreEventsFilter) ([]*models.LeaderboardEntry, error) {
+	ctx, span := repo.startSpan(ctx, "GetLeaderboardInternal")
+	defer span.End()
+
+	match := bson.D{{Key: "$match", Value: getScoreEventsQuery(filter)}}
+
+	sumByAccount := bson.D{{
+		Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$accountId"},
+			{Key: "score", Value: bson.D{{Key: "$sum", Value: "$score"}}},
+			{Key: "responses", Value: bson.D{{Key: "$sum", Value: "$responses"}}},
+		},
+	}}
+
+	sortByScore := bson.D{{
+		Key: "$sort", Value: bson.D{
+			{Key: "score", Value: -1},
+			{Key: "responses", Value: 1},
+			{Key: "_id", Value: 1},
+		},
+	}}
+
+	entries := make([]*models.LeaderboardEntry, 0)
+	err := repo.aggregate(ctx, mongo.Pipeline{match, sumByAccount, sortByScore}, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func getScoreEventsQuery(filter *models.ManyScoreEventsFilter) bson.D {
+	query := bson.D{
+		{Key: "groupId", Value: filter.GroupID},
+		{Key: "season", Value: filter.Season},
+	}
+	if filter.AccountID != "" {
+		query = append(query, bson.E{Key: "accountId", Value: filter.AccountID})
+	}
+	if filter.Since != nil {
+		query = append(query, bson.E{Key: "createdAt", Value: bson.D{{Key: "$gte", Value: *filter.Since}}})
+	}
+	return query
+}
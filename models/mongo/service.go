@@ -47,6 +47,11 @@ func NewDatabase(ctx context.Context, uri string, name string, logger *zap.Logge
 	}, nil
 }
 
+// Ping checks that the primary of the cluster can be reached.
+func (s *Database) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx, readpref.Primary())
+}
+
 // Disconnect the TCP connection to the cluster.
 func (s *Database) Disconnect(ctx context.Context) {
 	if err := s.client.Disconnect(ctx); err != nil {
@@ -0,0 +1,151 @@
+package mongo
+
+import (
+	"context"
+	"notes-service/models"
+	"time"
+
+	"github.com/jaevor/go-nanoid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+type templatesRepository struct {
+	repository
+}
+
+func NewTemplatesRepository(db *mongo.Database, logger *zap.Logger) models.TemplatesRepository {
+	newUUID, err := nanoid.Standard(21)
+	if err != nil {
+		panic(err)
+	}
+
+	return &templatesRepository{
+		repository: repository{
+			logger:  logger.Named("mongo").Named("templates"),
+			coll:    db.Collection("templates"),
+			newUUID: newUUID,
+		},
+	}
+}
+
+func (repo *templatesRepository) CreateTemplate(ctx context.Context, payload *models.CreateTemplatePayload, accountID string) (*models.Template, error) {
+	ctx, span := repo.startSpan(ctx, "CreateTemplate")
+	defer span.End()
+
+	template := &models.Template{
+		ID:              repo.newUUID(),
+		Name:            payload.Name,
+		Description:     payload.Description,
+		Scope:           payload.Scope,
+		AuthorAccountID: accountID,
+		Lang:            payload.Lang,
+		Blocks:          repo.templateBlocks(payload.Blocks),
+		CreatedAt:       time.Now(),
+	}
+	if payload.Scope == models.TemplateScopeGroup {
+		template.GroupID = payload.GroupID
+	}
+
+	err := repo.insertOne(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (repo *templatesRepository) GetTemplateInternal(ctx context.Context, filter *models.OneTemplateFilter) (*models.Template, error) {
+	ctx, span := repo.startSpan(ctx, "GetTemplateInternal")
+	defer span.End()
+
+	template := &models.Template{}
+
+	err := repo.findOne(ctx, bson.D{{Key: "_id", Value: filter.TemplateID}}, template)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (repo *templatesRepository) ListTemplatesInternal(ctx context.Context, filter *models.ManyTemplatesFilter, lo *models.ListOptions) ([]*models.Template, error) {
+	ctx, span := repo.startSpan(ctx, "ListTemplatesInternal")
+	defer span.End()
+
+	templates := make([]*models.Template, 0)
+
+	query := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "scope", Value: models.TemplateScopeGlobal}},
+		bson.D{{Key: "scope", Value: models.TemplateScopeGroup}, {Key: "groupId", Value: filter.GroupID}},
+		bson.D{{Key: "scope", Value: models.TemplateScopeAccount}, {Key: "authorAccountId", Value: filter.AccountID}},
+	}}}
+	opts := options.Find().
+		SetProjection(bson.D{{Key: "blocks", Value: 0}}).
+		SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+
+	err := repo.find(ctx, query, &templates, lo, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (repo *templatesRepository) DeleteTemplateInternal(ctx context.Context, filter *models.OneTemplateFilter) error {
+	ctx, span := repo.startSpan(ctx, "DeleteTemplateInternal")
+	defer span.End()
+
+	return repo.deleteOne(ctx, bson.D{{Key: "_id", Value: filter.TemplateID}})
+}
+
+func (repo *templatesRepository) StoreGlobalTemplatesInternal(ctx context.Context, templates []*models.Template) error {
+	ctx, span := repo.startSpan(ctx, "StoreGlobalTemplatesInternal")
+	defer span.End()
+
+	for _, template := range templates {
+		update := bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "name", Value: template.Name},
+				{Key: "description", Value: template.Description},
+				{Key: "scope", Value: models.TemplateScopeGlobal},
+				{Key: "lang", Value: template.Lang},
+				{Key: "blocks", Value: repo.templateBlocks(template.Blocks)},
+			}},
+			{Key: "$setOnInsert", Value: bson.D{
+				{Key: "createdAt", Value: time.Now()},
+			}},
+		}
+		opts := options.FindOneAndUpdate().SetUpsert(true)
+
+		err := repo.findOneAndUpdate(ctx, bson.D{{Key: "_id", Value: template.ID}}, update, &models.Template{}, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// templateBlocks copies the blocks with new IDs and without their comments
+// nor the assignees of their checklist items, who may not be members of the
+// groups the template is used in.
+func (repo *templatesRepository) templateBlocks(blocks []models.NoteBlock) []models.NoteBlock {
+	copies := make([]models.NoteBlock, len(blocks))
+	for i, block := range blocks {
+		copies[i] = block
+		copies[i].ID = repo.newUUID()
+		copies[i].Thread = nil
+		if block.ChecklistItem != nil {
+			item := *block.ChecklistItem
+			item.AssigneeAccountID = ""
+			copies[i].ChecklistItem = &item
+		}
+		if copies[i].Styles == nil {
+			copies[i].Styles = &[]models.TextStyle{}
+		}
+	}
+	return copies
+}
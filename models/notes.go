@@ -33,20 +33,57 @@ type NoteBlockCode struct {
 	Lang    string `json:"lang" bson:"lang"`
 }
 
+type NoteBlockChecklistItem struct {
+	Text              string `json:"text" bson:"text"`
+	Checked           bool   `json:"checked" bson:"checked"`
+	AssigneeAccountID string `json:"assigneeAccountId,omitempty" bson:"assigneeAccountId,omitempty"`
+}
+
+type NoteBlockTableCell struct {
+	Type  string `json:"type" bson:"type"`
+	Value string `json:"value" bson:"value"`
+}
+
+type NoteBlockTableRow struct {
+	Cells []NoteBlockTableCell `json:"cells" bson:"cells"`
+}
+
+type NoteBlockTable struct {
+	HasHeaderRow bool                `json:"hasHeaderRow" bson:"hasHeaderRow"`
+	Rows         []NoteBlockTableRow `json:"rows" bson:"rows"`
+}
+
+type NoteBlockCallout struct {
+	Tone string `json:"tone" bson:"tone"`
+	Text string `json:"text" bson:"text"`
+}
+
+type NoteBlockEmbed struct {
+	Url   string `json:"url" bson:"url"`
+	Title string `json:"title,omitempty" bson:"title,omitempty"`
+}
+
 type NoteBlockType = string
 
 type NoteBlock struct {
-	ID          string          `json:"id" bson:"id"`
-	Type        NoteBlockType   `json:"type" bson:"type"`
-	Heading     *string         `json:"heading,omitempty" bson:"heading,omitempty"`
-	Paragraph   *string         `json:"paragraph,omitempty" bson:"paragraph,omitempty"`
-	NumberPoint *string         `json:"numberPoint,omitempty" bson:"numberPoint,omitempty"`
-	BulletPoint *string         `json:"bulletPoint,omitempty" bson:"bulletPoint,omitempty"`
-	Math        *string         `json:"math,omitempty" bson:"math,omitempty"`
-	Image       *NoteBlockImage `json:"image,omitempty" bson:"image,omitempty"`
-	Code        *NoteBlockCode  `json:"code,omitempty" bson:"code,omitempty"`
-	Styles      *[]TextStyle    `json:"styles,omitempty" bson:"styles,omitempty"`
-	Thread      *[]BlockComment `json:"thread,omitempty" bson:"thread,omitempty"`
+	ID            string                  `json:"id" bson:"id"`
+	Type          NoteBlockType           `json:"type" bson:"type"`
+	Heading       *string                 `json:"heading,omitempty" bson:"heading,omitempty"`
+	Paragraph     *string                 `json:"paragraph,omitempty" bson:"paragraph,omitempty"`
+	NumberPoint   *string                 `json:"numberPoint,omitempty" bson:"numberPoint,omitempty"`
+	BulletPoint   *string                 `json:"bulletPoint,omitempty" bson:"bulletPoint,omitempty"`
+	Math          *string                 `json:"math,omitempty" bson:"math,omitempty"`
+	Image         *NoteBlockImage         `json:"image,omitempty" bson:"image,omitempty"`
+	Code          *NoteBlockCode          `json:"code,omitempty" bson:"code,omitempty"`
+	Summary       *string                 `json:"summary,omitempty" bson:"summary,omitempty"`
+	ChecklistItem *NoteBlockChecklistItem `json:"checklistItem,omitempty" bson:"checklistItem,omitempty"`
+	Table         *NoteBlockTable         `json:"table,omitempty" bson:"table,omitempty"`
+	Callout       *NoteBlockCallout       `json:"callout,omitempty" bson:"callout,omitempty"`
+	Embed         *NoteBlockEmbed         `json:"embed,omitempty" bson:"embed,omitempty"`
+	Styles        *[]TextStyle            `json:"styles,omitempty" bson:"styles,omitempty"`
+	Thread        *[]BlockComment         `json:"thread,omitempty" bson:"thread,omitempty"`
+	// Incremented every time the content of the block changes.
+	Revision int64 `json:"revision" bson:"revision"`
 }
 
 type BlockComment struct {
@@ -59,6 +96,14 @@ type TextStyle struct {
 	Style    string   `json:"style,omitempty" bson:"style,omitempty"`
 	Position Position `json:"pos,omitempty" bson:"pos,omitempty"`
 	Color    *Color   `json:"color,omitempty" bson:"color,omitempty"`
+	// Target of a STYLE_LINK style.
+	Link *TextStyleLink `json:"link,omitempty" bson:"link,omitempty"`
+}
+
+type TextStyleLink struct {
+	NoteID string `json:"noteId" bson:"noteId"`
+	// (Optional) Block of the note the link points to.
+	BlockID string `json:"blockId,omitempty" bson:"blockId,omitempty"`
 }
 
 type Position struct {
@@ -74,22 +119,6 @@ type Color struct {
 	B int32 `json:"b,omitempty" bson:"b,omitempty"`
 }
 
-const (
-	Unknown      string = "Inconnu"
-	Person       string = "Personne"
-	Location     string = "Lieu"
-	Organization string = "Organisation"
-	Event        string = "Evenement"
-	WorkOfArt    string = "Chef d'oeuvre"
-	ConsumerGood string = "Bien de consommation"
-	Other        string = "Autre"
-	PhoneNumber  string = "Numéro de téléphone"
-	Address      string = "Addresse"
-	Date         string = "Date"
-	Number       string = "Nombre"
-	Price        string = "Prix"
-)
-
 type Keyword struct {
 	Keyword  string `json:"keyword,omitempty" bson:"keyword,omitempty"`
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
@@ -103,6 +132,7 @@ type Note struct {
 	Title                       string       `json:"title" bson:"title"`
 	AuthorAccountID             string       `json:"authorAccountId" bson:"authorAccountId"`
 	GroupID                     string       `json:"groupId" bson:"groupId"`
+	FolderID                    string       `json:"folderId,omitempty" bson:"folderId,omitempty"`
 	CreatedAt                   time.Time    `json:"createdAt" bson:"createdAt"`
 	ModifiedAt                  *time.Time   `json:"modifiedAt" bson:"modifiedAt"`
 	AnalyzedAt                  *time.Time   `json:"analyzedAt" bson:"analyzedAt"`
@@ -111,6 +141,15 @@ type Note struct {
 	Quizs                       *[]Quiz      `json:"quizs" bson:"quizs"`
 	Lang                        string       `json:"lang" bson:"lang"`
 	AccountsWithEditPermissions []string     `json:"accountsWithEditPermissions" bson:"accountsWithEditPermissions"`
+	Summary                     *Summary     `json:"summary,omitempty" bson:"summary,omitempty"`
+	// ID of the note this note was translated from.
+	SourceNoteID string         `json:"sourceNoteId,omitempty" bson:"sourceNoteId,omitempty"`
+	Embedding    *NoteEmbedding `json:"-" bson:"embedding,omitempty"`
+	// Embeddings of the blocks compared to the questions, identified by the
+	// hash of the text of their block.
+	BlockEmbeddings []*NoteEmbedding `json:"-" bson:"blockEmbeddings,omitempty"`
+	// Incremented every time the title or the blocks of the note change.
+	Revision int64 `json:"revision" bson:"revision"`
 }
 
 type Quiz struct {
@@ -121,6 +160,18 @@ type Quiz struct {
 
 type Summary struct {
 	Content string `json:"content,omitempty" bson:"content,omitempty"`
+	// Hash of the note's text the summary was generated from.
+	ContentHash string    `json:"contentHash,omitempty" bson:"contentHash,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type NoteEmbedding struct {
+	// Model which computed the vector, vectors of different models can't be compared.
+	Model  string    `json:"model" bson:"model"`
+	Vector []float32 `json:"vector" bson:"vector"`
+	// Hash of the note's text the vector was computed from.
+	ContentHash string    `json:"contentHash" bson:"contentHash"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
 }
 
 type QuizQuestion struct {
@@ -154,6 +205,7 @@ type CreateNotePayload struct {
 	FolderID        string
 	Lang            string
 	Blocks          []NoteBlock
+	SourceNoteID    string
 }
 
 type InsertNoteBlockPayload struct {
@@ -166,12 +218,60 @@ type ManyNotesFilter struct {
 	GroupID string
 	// (Optional) List notes belonging to account.
 	AuthorAccountID string
+	// (Optional) List notes belonging to folder.
+	FolderID string
+}
+
+type ManyEmbeddingsFilter struct {
+	// List the embeddings of the notes belonging to one of these groups.
+	GroupIDs []string
+	// List the embeddings computed by this model.
+	Model string
+	// Maximum number of embeddings listed, the most recent notes first. No
+	// limit when 0.
+	Limit int64
 }
 
 type UpdateBlockPayload struct {
 	Block NoteBlock
 }
 
+type BlockOperationType string
+
+const (
+	BlockOperationInsert BlockOperationType = "INSERT"
+	BlockOperationUpdate BlockOperationType = "UPDATE"
+	BlockOperationDelete BlockOperationType = "DELETE"
+	BlockOperationMove   BlockOperationType = "MOVE"
+)
+
+type MoveBlockPayload struct {
+	// Position of the first moved block once moved, blocks past the end are
+	// appended.
+	Index uint
+	// (Optional) Number of contiguous blocks moved starting at the block, to
+	// move a heading along with the blocks under it. Only the block is moved
+	// when 0.
+	Count uint
+}
+
+// BlockOperation is one of the changes applied by BatchUpdateBlocks, against
+// the blocks resulting from the previous operations.
+type BlockOperation struct {
+	Type BlockOperationType
+	// Block updated, deleted or moved.
+	BlockID string
+	// Content of the inserted or updated block.
+	Block NoteBlock
+	// Position of the inserted or moved block, blocks past the end are appended.
+	Index uint
+	// Number of blocks moved, starting at the block.
+	Count uint
+	// (Optional) Only apply the operation if the block is at this revision,
+	// ErrConflict is returned otherwise.
+	ExpectedRevision int64
+}
+
 type UpdateNotePayload struct {
 	Title  string       `json:"title,omitempty" bson:"title,omitempty"`
 	Blocks *[]NoteBlock `json:"blocks,omitempty" bson:"blocks,omitempty"`
@@ -184,15 +284,33 @@ type UpdateNoteGroupPayload struct {
 	GroupID string `json:"groupId" bson:"groupId"`
 }
 
+type MoveNotePayload struct {
+	GroupID                     string
+	AccountsWithEditPermissions []string
+	// Remove the comments of the blocks of the note.
+	DropComments bool
+	// Remove the quizzes of the note.
+	DropQuizzes bool
+	// Accounts whose checklist items are unassigned, e.g. the ones outside of
+	// the destination group.
+	UnassignedAccountIDs []string
+}
+
 type OneNoteFilter struct {
 	GroupID string
 	NoteID  string
+	// (Optional) Only modify the note if it is at this revision, ErrConflict
+	// is returned otherwise.
+	ExpectedRevision int64
 }
 
 type OneBlockFilter struct {
 	GroupID string
 	NoteID  string
 	BlockID string
+	// (Optional) Only modify the block if it is at this revision, ErrConflict
+	// is returned otherwise.
+	ExpectedRevision int64
 }
 
 type NotesRepository interface {
@@ -205,11 +323,22 @@ type NotesRepository interface {
 	DeleteNotes(ctx context.Context, filter *ManyNotesFilter) error
 	ListNotesInternal(ctx context.Context, filter *ManyNotesFilter, opts *ListOptions) ([]*Note, error)
 	ListAllNotesInternal(ctx context.Context, filter *ManyNotesFilter) ([]*Note, error)
+	// Stores a complete note, giving new IDs to the note, its blocks, comments and quizzes.
+	ImportNoteInternal(ctx context.Context, note *Note) (*Note, error)
+	// Moves the note of the author to another group, out of its folder.
+	MoveNote(ctx context.Context, filter *OneNoteFilter, payload *MoveNotePayload, accountID string) (*Note, error)
 	StoreNewQuiz(ctx context.Context, filter *OneNoteFilter, payload *Quiz, accountID string) (*Quiz, error)
 	ListQuizs(ctx context.Context, filter *OneNoteFilter, accountID string) (*[]Quiz, error)
 	DeleteQuiz(ctx context.Context, filter *OneNoteFilter, quizID string, accountID string) error
 	DeleteQuizFromIDInternal(ctx context.Context, quizID string) error
 	ListQuizsCreatedDateInternal(ctx context.Context) (*[]Quiz, error)
+	StoreSummaryInternal(ctx context.Context, filter *OneNoteFilter, payload *Summary) (*Summary, error)
+	StoreEmbeddingInternal(ctx context.Context, filter *OneNoteFilter, payload *NoteEmbedding) error
+	// Replaces the block embeddings of the note.
+	StoreBlockEmbeddingsInternal(ctx context.Context, filter *OneNoteFilter, payload []*NoteEmbedding) error
+	// Returns the notes with only their ID, group, title and embedding, the
+	// most recent first.
+	ListEmbeddingsInternal(ctx context.Context, filter *ManyEmbeddingsFilter) ([]*Note, error)
 
 	// Permisions
 	GrantNoteEditPermission(ctx context.Context, filter *OneNoteFilter, AccountID string, RecipientAccountId string) error
@@ -219,6 +348,12 @@ type NotesRepository interface {
 	UpdateBlock(ctx context.Context, filter *OneBlockFilter, payload *UpdateBlockPayload, accountID string) (*NoteBlock, error)
 	GetBlock(ctx context.Context, filter *OneBlockFilter, accountID string) (*NoteBlock, error)
 	DeleteBlock(ctx context.Context, filter *OneBlockFilter, accountID string) error
+	// Applies the operations in order and stores the resulting blocks in a single
+	// update, none of them is applied if one fails.
+	BatchUpdateBlocks(ctx context.Context, filter *OneNoteFilter, operations []BlockOperation, accountID string) (*Note, error)
+	// Moves the block, or the range of blocks starting at it, to the index in a
+	// single update.
+	MoveBlock(ctx context.Context, filter *OneBlockFilter, payload *MoveBlockPayload, accountID string) (*Note, error)
 	CreateBlockComment(ctx context.Context, filter *OneBlockFilter, payload *BlockComment, accountID string) (*BlockComment, error)
 	DeleteBlockComment(ctx context.Context, filter *OneBlockFilter, payload *BlockComment, accountID string) (*BlockComment, error)
 	ListBlockComments(ctx context.Context, filter *OneBlockFilter, lo *ListOptions, accountID string) (*[]BlockComment, error)
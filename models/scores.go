@@ -0,0 +1,58 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// ScoreEvent is an immutable record of a quiz result tracked by a member, the
+// leaderboard is computed from these events. The counters of the members are
+// only updated alongside them and are not recomputed from them.
+type ScoreEvent struct {
+	ID        string    `json:"id" bson:"_id"`
+	GroupID   string    `json:"groupId" bson:"groupId"`
+	AccountID string    `json:"accountId" bson:"accountId"`
+	Season    int       `json:"season" bson:"season"`
+	Score     int       `json:"score" bson:"score"`
+	Responses int       `json:"responses" bson:"responses"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type ScoreEventPayload struct {
+	GroupID   string
+	AccountID string
+	Season    int
+	Score     int
+	Responses int
+}
+
+type ManyScoreEventsFilter struct {
+	GroupID string
+	Season  int
+	// (Optional) Only consider the events of this member.
+	AccountID string
+	// (Optional) Only consider the events created after this date.
+	Since *time.Time
+}
+
+// LeaderboardEntry is the sum of the score events of a member.
+type LeaderboardEntry struct {
+	AccountID string `json:"accountId" bson:"_id"`
+	Score     int    `json:"score" bson:"score"`
+	Responses int    `json:"responses" bson:"responses"`
+}
+
+// Accuracy returns the percentage of correct responses of the entry.
+func (entry *LeaderboardEntry) Accuracy() float32 {
+	if entry.Responses == 0 {
+		return 0
+	}
+	return float32(entry.Score) * 100 / float32(entry.Responses)
+}
+
+// ScoresRepository is the append-only log of the score events of every group.
+type ScoresRepository interface {
+	CreateScoreEventInternal(ctx context.Context, payload *ScoreEventPayload) (*ScoreEvent, error)
+	ListScoreEventsInternal(ctx context.Context, filter *ManyScoreEventsFilter, lo *ListOptions) ([]*ScoreEvent, error)
+	GetLeaderboardInternal(ctx context.Context, filter *ManyScoreEventsFilter) ([]*LeaderboardEntry, error)
+}
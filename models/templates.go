@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type TemplateScope = string
+
+const (
+	// Templates shipped with the service, available to everyone.
+	TemplateScopeGlobal TemplateScope = "TEMPLATE_SCOPE_GLOBAL"
+	// Templates available to the members of a group.
+	TemplateScopeGroup TemplateScope = "TEMPLATE_SCOPE_GROUP"
+	// Templates available to their author only, in any group.
+	TemplateScopeAccount TemplateScope = "TEMPLATE_SCOPE_ACCOUNT"
+)
+
+// Template holds the blocks copied into the notes created from it.
+type Template struct {
+	ID          string        `json:"id" bson:"_id"`
+	Name        string        `json:"name" bson:"name"`
+	Description string        `json:"description" bson:"description"`
+	Scope       TemplateScope `json:"scope" bson:"scope"`
+	// Group of a TemplateScopeGroup template.
+	GroupID         string      `json:"groupId,omitempty" bson:"groupId,omitempty"`
+	AuthorAccountID string      `json:"authorAccountId,omitempty" bson:"authorAccountId,omitempty"`
+	Lang            string      `json:"lang" bson:"lang"`
+	Blocks          []NoteBlock `json:"blocks" bson:"blocks"`
+	CreatedAt       time.Time   `json:"createdAt" bson:"createdAt"`
+}
+
+type CreateTemplatePayload struct {
+	Name        string
+	Description string
+	Scope       TemplateScope
+	GroupID     string
+	Lang        string
+	Blocks      []NoteBlock
+}
+
+type OneTemplateFilter struct {
+	TemplateID string
+}
+
+// ManyTemplatesFilter matches the templates available to an account in a
+// group: the ones of the group, the ones of the account and the global ones.
+type ManyTemplatesFilter struct {
+	GroupID   string
+	AccountID string
+}
+
+// IsAvailable returns whether the account can use the template in the group.
+func (template *Template) IsAvailable(groupID string, accountID string) bool {
+	switch template.Scope {
+	case TemplateScopeGlobal:
+		return true
+	case TemplateScopeGroup:
+		return template.GroupID == groupID
+	case TemplateScopeAccount:
+		return template.AuthorAccountID == accountID
+	}
+	return false
+}
+
+type TemplatesRepository interface {
+	// Stores the template, giving new IDs to its blocks and dropping their comments.
+	CreateTemplate(ctx context.Context, payload *CreateTemplatePayload, accountID string) (*Template, error)
+	GetTemplateInternal(ctx context.Context, filter *OneTemplateFilter) (*Template, error)
+	// Returns the templates without their blocks.
+	ListTemplatesInternal(ctx context.Context, filter *ManyTemplatesFilter, lo *ListOptions) ([]*Template, error)
+	DeleteTemplateInternal(ctx context.Context, filter *OneTemplateFilter) error
+	// Creates or replaces the global templates, identified by their ID.
+	StoreGlobalTemplatesInternal(ctx context.Context, templates []*Template) error
+}
@@ -7,6 +7,7 @@ var (
 	ErrAlreadyExists = errors.New("already exists or conflicts with existing resource")
 	ErrUnknown       = errors.New("unknown error")
 	ErrForbidden     = errors.New("forbidden operation")
+	ErrConflict      = errors.New("modified since the expected revision")
 )
 
 type ListOptions struct {
@@ -2,15 +2,20 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 	"time"
 
 	"notes-service/auth"
+	"notes-service/communication"
 
 	background "github.com/noted-eip/noted/background-service"
 
+	"notes-service/backups"
 	"notes-service/exports"
 	"notes-service/models"
+	accountsv1 "notes-service/protorepo/noted/accounts/v1"
 	notesv1 "notes-service/protorepo/noted/notes/v1"
 	"notes-service/validators"
 
@@ -27,13 +32,21 @@ type notesAPI struct {
 
 	logger *zap.Logger
 
-	auth       auth.Service
-	language   language.Service
-	background background.Service
+	auth           auth.Service
+	language       language.Service
+	background     background.Service
+	accountsClient *communication.AccountsServiceClient
+
+	embeddings      language.EmbeddingProvider
+	languageUsage   *language.Usage
+	accountsLimiter *language.RateLimiter
+	groupsLimiter   *language.RateLimiter
 
 	notes      models.NotesRepository
 	groups     models.GroupsRepository
 	activities models.ActivitiesRepository
+	links      models.LinksRepository
+	templates  models.TemplatesRepository
 }
 
 var _ notesv1.NotesAPIServer = &notesAPI{}
@@ -50,28 +63,61 @@ func (srv *notesAPI) CreateNote(ctx context.Context, req *notesv1.CreateNoteRequ
 	}
 
 	// Check user is part of the group.
-	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
 	if err != nil {
 		return nil, statusFromModelError(err)
 	}
 
-	// Check user can edit the note
-	note, err := srv.notes.CreateNote(ctx, &models.CreateNotePayload{
+	err = checkChecklistAssignees(group, req.Blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := srv.createNote(ctx, &models.CreateNotePayload{
 		GroupID:         req.GroupId,
 		Title:           req.Title,
 		AuthorAccountID: token.AccountID,
 		FolderID:        "",
 		Lang:            req.Lang,
 		Blocks:          protobufBlocksToModelsBlocks(req.Blocks),
-	}, token.AccountID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.CreateNoteResponse{Note: modelsNoteToProtobufNote(note)}, nil
+}
+
+// createNote stores a new note, detecting its language when the payload has
+// none, and starts the work following its creation.
+func (srv *notesAPI) createNote(ctx context.Context, payload *models.CreateNotePayload) (*models.Note, error) {
+	if payload.Lang == "" {
+		payload.Lang = language.DetectLanguage(payload.Title + "\n" + noteModelToString(&models.Note{Blocks: &payload.Blocks}))
+	}
+
+	note, err := srv.notes.CreateNote(ctx, payload, payload.AuthorAccountID)
 	if err != nil {
 		return nil, statusFromModelError(err)
 	}
 
-	err = srv.background.AddProcess(&background.Process{
+	err = srv.noteCreated(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+// noteCreated starts the work following the creation or the import of a note:
+// its links are indexed, its keywords are extracted in the background and its
+// creation is shown in the activities of the group.
+func (srv *notesAPI) noteCreated(ctx context.Context, note *models.Note) error {
+	indexNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.notes, srv.links, note)
+
+	err := srv.background.AddProcess(&background.Process{
 		Identifier: models.NoteIdentifier{Metadata: note.ID, ActionType: models.NoteUpdateKeyword},
 		CallBackFct: func() error {
-			err := srv.UpdateKeywordsByNoteId(note.ID, req.GroupId, token.AccountID)
+			err := srv.UpdateKeywordsByNoteId(note.ID, note.GroupID, note.AuthorAccountID)
 			return err
 		},
 		SecondsToDebounce:             5,
@@ -79,19 +125,50 @@ func (srv *notesAPI) CreateNote(ctx context.Context, req *notesv1.CreateNoteRequ
 		RepeatProcess:                 false,
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	_, err = srv.activities.CreateActivityInternal(ctx, &models.ActivityPayload{
 		GroupID: note.GroupID,
 		Type:    models.NoteAdded,
-		Event:   "<userID:" + note.AuthorAccountID + "> has added the note <noteID:" + note.ID + "> in the folder <folderID:" + "" + ">.",
+		Event:   "<userID:" + note.AuthorAccountID + "> has added the note <noteID:" + note.ID + "> in the folder <folderID:" + note.FolderID + ">.",
 	})
-	if err != nil {
-		return nil, err
+	return err
+}
+
+// checkChecklistAssignees returns an InvalidArgument error when a checklist
+// item of the blocks is assigned to an account outside of the group.
+func checkChecklistAssignees(group *models.Group, blocks []*notesv1.Block) error {
+	for _, block := range blocks {
+		item := block.GetChecklistItem()
+		if item == nil || item.AssigneeAccountId == "" {
+			continue
+		}
+		if group.FindMember(item.AssigneeAccountId) == nil {
+			return status.Errorf(codes.InvalidArgument, "checklist item assignee %s is not a member of the group", item.AssigneeAccountId)
+		}
+	}
+	return nil
+}
+
+// checkGroupChecklistAssignees is checkChecklistAssignees for the RPCs which
+// do not get the group otherwise.
+func (srv *notesAPI) checkGroupChecklistAssignees(ctx context.Context, groupID string, accountID string, blocks []*notesv1.Block) error {
+	assigned := false
+	for _, block := range blocks {
+		if block.GetChecklistItem().GetAssigneeAccountId() != "" {
+			assigned = true
+		}
+	}
+	if !assigned {
+		return nil
 	}
 
-	return &notesv1.CreateNoteResponse{Note: modelsNoteToProtobufNote(note)}, nil
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: groupID}, accountID)
+	if err != nil {
+		return statusFromModelError(err)
+	}
+	return checkChecklistAssignees(group, blocks)
 }
 
 func (srv *notesAPI) GetNote(ctx context.Context, req *notesv1.GetNoteRequest) (*notesv1.GetNoteResponse, error) {
@@ -116,7 +193,18 @@ func (srv *notesAPI) GetNote(ctx context.Context, req *notesv1.GetNoteRequest) (
 		return nil, statusFromModelError(err)
 	}
 
-	return &notesv1.GetNoteResponse{Note: modelsNoteToProtobufNote(note)}, nil
+	protobufNote := modelsNoteToProtobufNote(note)
+
+	// The note is returned without its broken links when the index can't be
+	// read.
+	links, err := srv.links.ListAllNoteLinksInternal(ctx, &models.ManyNoteLinksFilter{SourceNoteID: note.ID})
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to list note links", zap.Error(err), zap.String("noteId", note.ID))
+	} else {
+		flagBrokenLinks(protobufNote, links)
+	}
+
+	return &notesv1.GetNoteResponse{Note: protobufNote}, nil
 }
 
 func (srv *notesAPI) UpdateNote(ctx context.Context, req *notesv1.UpdateNoteRequest) (*notesv1.UpdateNoteResponse, error) {
@@ -131,11 +219,16 @@ func (srv *notesAPI) UpdateNote(ctx context.Context, req *notesv1.UpdateNoteRequ
 	}
 
 	// Check user is part of the group.
-	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
 	if err != nil {
 		return nil, statusFromModelError(err)
 	}
 
+	err = checkChecklistAssignees(group, req.Note.Blocks)
+	if err != nil {
+		return nil, err
+	}
+
 	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
 	if err != nil {
 		return nil, statusFromModelError(err)
@@ -146,16 +239,17 @@ func (srv *notesAPI) UpdateNote(ctx context.Context, req *notesv1.UpdateNoteRequ
 		return nil, status.Error(codes.PermissionDenied, "you do not have edit permissions on this note")
 	}
 	if len(req.Note.Blocks) > 0 {
-		srv.logger.Info("length of styles before", zap.Int("length", len(req.Note.Blocks[0].Styles)))
+		loggerFromContext(ctx, srv.logger).Info("length of styles before", zap.Int("length", len(req.Note.Blocks[0].Styles)))
 	}
-	updatedNote, err := srv.notes.UpdateNote(ctx,
-		&models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId},
-		updateNotePayloadFromUpdateNoteRequest(req),
-		token.AccountID)
+	filter := &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId, ExpectedRevision: req.ExpectedRevision}
+	updatedNote, err := srv.notes.UpdateNote(ctx, filter, updateNotePayloadFromUpdateNoteRequest(req), token.AccountID)
 	if err != nil {
-		return nil, statusFromModelError(err)
+		return nil, srv.noteWriteError(ctx, filter, token.AccountID, err)
 	}
 
+	indexNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.notes, srv.links, updatedNote)
+	breakLinksToBlocks(ctx, loggerFromContext(ctx, srv.logger), srv.links, updatedNote.ID, removedBlockIDs(note.Blocks, updatedNote.Blocks))
+
 	err = srv.background.AddProcess(&background.Process{
 		Identifier: models.NoteIdentifier{Metadata: updatedNote.ID, ActionType: models.NoteUpdateKeyword},
 		CallBackFct: func() error {
@@ -200,13 +294,14 @@ func (srv *notesAPI) DeleteNote(ctx context.Context, req *notesv1.DeleteNoteRequ
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	err = srv.notes.DeleteNote(ctx,
-		&models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId},
-		token.AccountID)
+	filter := &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId, ExpectedRevision: req.ExpectedRevision}
+	err = srv.notes.DeleteNote(ctx, filter, token.AccountID)
 	if err != nil {
-		return nil, statusFromModelError(err)
+		return nil, srv.noteWriteError(ctx, filter, token.AccountID, err)
 	}
 
+	forgetNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.links, req.NoteId)
+
 	return &notesv1.DeleteNoteResponse{}, nil
 }
 
@@ -266,31 +361,119 @@ func (srv *notesAPI) ExportNote(ctx context.Context, req *notesv1.ExportNoteRequ
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	note, err := srv.GetNote(ctx, &notesv1.GetNoteRequest{GroupId: req.GroupId, NoteId: req.NoteId})
+	// Check user is part of the group
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
 	if err != nil {
 		return nil, statusFromModelError(err)
 	}
 
-	// Check user is part of the group
-	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
 	if err != nil {
 		return nil, statusFromModelError(err)
 	}
 
-	formatter, ok := protobufFormatToFormatter[req.ExportFormat]
+	fileBytes, err := srv.exportNote(ctx, note, group, &noteExportOptions{
+		format:          req.ExportFormat,
+		includeComments: req.IncludeComments,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.ExportNoteResponse{File: fileBytes}, nil
+}
+
+type noteExportOptions struct {
+	format          notesv1.NoteExportFormat
+	includeComments bool
+	// (Optional) Fetches the images embedded in PDF files.
+	images exports.ImageFetcher
+	// (Optional) Links replacing the URLs of the images in the other formats.
+	imageLinks map[string]string
+}
+
+// exportNote converts the note to a file of the requested format.
+func (srv *notesAPI) exportNote(ctx context.Context, note *models.Note, group *models.Group, opts *noteExportOptions) ([]byte, error) {
+	// JSON files are backups, the comments are only kept when asked for.
+	if opts.format == notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_JSON {
+		if !opts.includeComments {
+			note = noteWithoutComments(note)
+		}
+		fileBytes, err := backups.NoteToJSON(note)
+		if err != nil {
+			loggerFromContext(ctx, srv.logger).Error("failed to convert note", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to convert note to: %s", opts.format.String())
+		}
+		return fileBytes, nil
+	}
+
+	formatter, ok := protobufFormatToFormatter[opts.format]
 	if !ok {
-		srv.logger.Error("format not recognized", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, "format not recognized : %s", req.ExportFormat.String())
+		loggerFromContext(ctx, srv.logger).Error("format not recognized", zap.String("format", opts.format.String()))
+		return nil, status.Errorf(codes.Internal, "format not recognized : %s", opts.format.String())
 	}
 
-	fileBytes, err := formatter(note.Note)
+	protobufNote := modelsNoteToProtobufNote(note)
+	if opts.includeComments {
+		modelsThreadsToProtobufBlocks(note, protobufNote)
+	}
+
+	var fileBytes []byte
+	var err error
+	if opts.format == notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_PDF {
+		// The title page of the PDF shows who wrote the note and where.
+		fileBytes, err = exports.NoteToPDFWithOptions(ctx, protobufNote, &exports.PDFOptions{
+			AuthorName: srv.getAccountName(ctx, note.AuthorAccountID),
+			GroupName:  group.Name,
+			Images:     opts.images,
+		})
+	} else {
+		for _, block := range protobufNote.Blocks {
+			if image := block.GetImage(); image != nil && opts.imageLinks[image.Url] != "" {
+				image.Url = opts.imageLinks[image.Url]
+			}
+		}
+		fileBytes, err = formatter(protobufNote)
+	}
 
 	if err != nil {
-		srv.logger.Error("failed to convert note", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, "failed to convert note to: %s", req.ExportFormat.String())
+		loggerFromContext(ctx, srv.logger).Error("failed to convert note", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to convert note to: %s", opts.format.String())
 	}
 
-	return &notesv1.ExportNoteResponse{File: fileBytes}, nil
+	return fileBytes, nil
+}
+
+// noteWithoutComments returns a copy of the note whose blocks have no thread.
+func noteWithoutComments(note *models.Note) *models.Note {
+	if note.Blocks == nil {
+		return note
+	}
+
+	copied := *note
+	blocks := make([]models.NoteBlock, len(*note.Blocks))
+	for i, block := range *note.Blocks {
+		block.Thread = nil
+		blocks[i] = block
+	}
+	copied.Blocks = &blocks
+	return &copied
+}
+
+// getAccountName returns the name of the account, or its id when the accounts
+// service can't be reached.
+func (srv *notesAPI) getAccountName(ctx context.Context, accountID string) string {
+	if srv.accountsClient == nil {
+		return accountID
+	}
+
+	res, err := srv.accountsClient.Accounts.GetAccount(ctx, &accountsv1.GetAccountRequest{AccountId: accountID})
+	if err != nil || res.Account == nil || res.Account.Name == "" {
+		loggerFromContext(ctx, srv.logger).Warn("could not get account name", zap.String("account_id", accountID), zap.Error(err))
+		return accountID
+	}
+
+	return res.Account.Name
 }
 
 func (srv *notesAPI) OnAccountDelete(ctx context.Context, req *notesv1.OnAccountDeleteRequest) (*notesv1.OnAccountDeleteResponse, error) {
@@ -299,9 +482,18 @@ func (srv *notesAPI) OnAccountDelete(ctx context.Context, req *notesv1.OnAccount
 		return nil, err
 	}
 
+	notes, err := srv.notes.ListAllNotesInternal(ctx, &models.ManyNotesFilter{AuthorAccountID: token.AccountID})
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
 	err = srv.notes.DeleteNotes(ctx, &models.ManyNotesFilter{AuthorAccountID: token.AccountID})
 	if err != nil {
-		srv.logger.Warn("Could not delete notes of " + token.AccountID + " reason " + err.Error())
+		loggerFromContext(ctx, srv.logger).Warn("Could not delete notes of " + token.AccountID + " reason " + err.Error())
+	}
+
+	for _, note := range notes {
+		forgetNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.links, note.ID)
 	}
 
 	err = srv.notes.RemoveEditPermissions(ctx, nil, token.AccountID)
@@ -383,17 +575,22 @@ func (srv *notesAPI) GenerateQuiz(ctx context.Context, req *notesv1.GenerateQuiz
 		return nil, statusFromModelError(err)
 	}
 
+	err = srv.checkLanguageRateLimits(language.MethodQuiz, token.AccountID, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
 	fullNote := noteModelToString(note)
 
-	quiz, err := srv.language.GenerateQuizFromTextInput(fullNote, note.Lang)
+	quiz, err := srv.language.GenerateQuizFromTextInput(ctx, fullNote, noteLang(note, fullNote))
 	if err != nil {
-		srv.logger.Error("failed to generate quiz", zap.Error(err))
+		loggerFromContext(ctx, srv.logger).Error("failed to generate quiz", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to generate quiz for noteId : %s", note.ID)
 	}
 
 	_, err = srv.notes.StoreNewQuiz(ctx, noteFilter, quiz, token.AccountID)
 	if err != nil {
-		srv.logger.Error("failed to store quizs", zap.Error(err))
+		loggerFromContext(ctx, srv.logger).Error("failed to store quizs", zap.Error(err))
 		return nil, statusFromModelError(err)
 	}
 
@@ -423,6 +620,64 @@ func (srv *notesAPI) GenerateQuiz(ctx context.Context, req *notesv1.GenerateQuiz
 	return &notesv1.GenerateQuizResponse{Quiz: modelsQuizToProtobufQuiz(quiz)}, nil
 }
 
+func (srv *notesAPI) ExportQuiz(ctx context.Context, req *notesv1.ExportQuizRequest) (*notesv1.ExportQuizResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateExportQuizRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	formatter, ok := protobufQuizFormatToFormatter[req.ExportFormat]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "format not recognized : %s", req.ExportFormat.String())
+	}
+
+	// Check user is part of the group.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	deck := &exports.QuizDeck{
+		NoteID:  note.ID,
+		Title:   note.Title,
+		Tags:    []string{note.Title},
+		Quizzes: []*notesv1.Quiz{},
+	}
+	for _, keyword := range note.Keywords {
+		deck.Tags = append(deck.Tags, keyword.Keyword)
+	}
+	if note.Quizs != nil {
+		for i := range *note.Quizs {
+			quiz := &(*note.Quizs)[i]
+			// Export every quiz of the note unless one is asked for.
+			if req.QuizId == "" || quiz.ID == req.QuizId {
+				deck.Quizzes = append(deck.Quizzes, modelsQuizToProtobufQuiz(quiz))
+			}
+		}
+	}
+	if req.QuizId != "" && len(deck.Quizzes) == 0 {
+		return nil, status.Error(codes.NotFound, "quiz not found")
+	}
+
+	fileBytes, err := formatter(deck)
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to convert quizzes", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to convert quizzes to: %s", req.ExportFormat.String())
+	}
+
+	return &notesv1.ExportQuizResponse{File: fileBytes}, nil
+}
+
 func (srv *notesAPI) GenerateSummary(ctx context.Context, req *notesv1.GenerateSummaryRequest) (*notesv1.GenerateSummaryResponse, error) {
 	token, err := srv.authenticate(ctx)
 	if err != nil {
@@ -445,14 +700,96 @@ func (srv *notesAPI) GenerateSummary(ctx context.Context, req *notesv1.GenerateS
 		return nil, statusFromModelError(err)
 	}
 
+	summary, err := srv.getOrGenerateSummary(ctx, note, token.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.GenerateSummaryResponse{Summary: summary.Content}, nil
+}
+
+func (srv *notesAPI) GetSummary(ctx context.Context, req *notesv1.GetSummaryRequest) (*notesv1.GetSummaryResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateGetSummaryRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	summary, err := srv.getOrGenerateSummary(ctx, note, token.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.GetSummaryResponse{
+		Summary:   summary.Content,
+		CreatedAt: timestamppb.New(summary.CreatedAt),
+	}, nil
+}
+
+// getOrGenerateSummary returns the summary stored on the note if the note's
+// text did not change since it was generated. Otherwise a new summary is
+// generated and stored in place of the old one.
+func (srv *notesAPI) getOrGenerateSummary(ctx context.Context, note *models.Note, accountID string) (*models.Summary, error) {
 	fullNote := noteModelToString(note)
-	summary, err := srv.language.GenerateSummaryFromTextInput(fullNote, note.Lang)
+	lang := noteLang(note, fullNote)
+	contentHash := summaryContentHash(fullNote, lang)
+
+	if note.Summary != nil && note.Summary.ContentHash == contentHash {
+		return note.Summary, nil
+	}
+
+	err := srv.checkLanguageRateLimits(language.MethodSummary, accountID, note.GroupID)
 	if err != nil {
-		srv.logger.Error("failed to generate summarry", zap.Error(err))
+		return nil, err
+	}
+
+	summary, err := srv.language.GenerateSummaryFromTextInput(ctx, fullNote, lang)
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to generate summarry", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to generate summarry for noteId : %s", note.ID)
 	}
+	summary.ContentHash = contentHash
 
-	return &notesv1.GenerateSummaryResponse{Summary: summary.Content}, nil
+	summary, err = srv.notes.StoreSummaryInternal(ctx, &models.OneNoteFilter{GroupID: note.GroupID, NoteID: note.ID}, summary)
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to store summary", zap.Error(err))
+		return nil, statusFromModelError(err)
+	}
+
+	return summary, nil
+}
+
+// checkLanguageRateLimits returns a ResourceExhausted error once the account
+// or the group made too many calls to the language service.
+func (srv *notesAPI) checkLanguageRateLimits(method string, accountID string, groupID string) error {
+	if !srv.accountsLimiter.Allow(accountID) {
+		srv.languageUsage.IncRateLimited(method)
+		return status.Error(codes.ResourceExhausted, "too many generation requests, please try again later")
+	}
+
+	if !srv.groupsLimiter.Allow(groupID) {
+		// The call is not made, it must not count against the account.
+		srv.accountsLimiter.Refund(accountID)
+		srv.languageUsage.IncRateLimited(method)
+		return status.Error(codes.ResourceExhausted, "too many generation requests in this group, please try again later")
+	}
+
+	return nil
 }
 
 func (srv *notesAPI) UpdateKeywordsByNoteId(noteId string, groupId string, accountID string) error {
@@ -461,7 +798,6 @@ func (srv *notesAPI) UpdateKeywordsByNoteId(noteId string, groupId string, accou
 		return statusFromModelError(err)
 	}
 
-	// TODO : mettre un timeout sur le call google
 	fullNote := noteModelToString(note)
 
 	// Don't gen keywords if the note has no content
@@ -471,7 +807,14 @@ func (srv *notesAPI) UpdateKeywordsByNoteId(noteId string, groupId string, accou
 		return nil
 	}
 
-	keywords, err := srv.language.GetKeywordsFromTextInput(fullNote, note.Lang)
+	// Embeddings don't depend on google's credentials, refresh them before
+	// the keywords so that they are not blocked by a keywords failure.
+	err = srv.updateEmbedding(context.Background(), note, fullNote)
+	if err != nil {
+		srv.logger.Error("failed to update embedding", zap.Error(err))
+	}
+
+	keywords, err := srv.language.GetKeywordsFromTextInput(context.Background(), fullNote, noteLang(note, fullNote))
 	if err != nil {
 		srv.logger.Error("failed to gen keywords", zap.Error(err))
 		return status.Errorf(codes.Internal, "failed to gen keywords for noteId : %s", note.ID)
@@ -490,6 +833,44 @@ func (srv *notesAPI) UpdateKeywordsByNoteId(noteId string, groupId string, accou
 	return nil
 }
 
+// updateEmbedding computes the embedding of the note used to recommend related
+// notes, unless the stored one is up to date.
+func (srv *notesAPI) updateEmbedding(ctx context.Context, note *models.Note, fullNote string) error {
+	if srv.embeddings == nil {
+		return nil
+	}
+
+	_, err := srv.noteEmbedding(ctx, note, fullNote)
+	return err
+}
+
+// noteEmbedding returns the stored embedding of the note when it is up to
+// date, otherwise it computes and stores a new one.
+func (srv *notesAPI) noteEmbedding(ctx context.Context, note *models.Note, fullNote string) (*models.NoteEmbedding, error) {
+	text := note.Title + "\n" + fullNote
+	contentHash := noteContentHash(text)
+	if note.Embedding != nil && note.Embedding.Model == srv.embeddings.Model() && note.Embedding.ContentHash == contentHash {
+		return note.Embedding, nil
+	}
+
+	vector, err := srv.embeddings.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	embedding := &models.NoteEmbedding{
+		Model:       srv.embeddings.Model(),
+		Vector:      vector,
+		ContentHash: contentHash,
+	}
+	err = srv.notes.StoreEmbeddingInternal(ctx, &models.OneNoteFilter{GroupID: note.GroupID, NoteID: note.ID}, embedding)
+	if err != nil {
+		return nil, err
+	}
+
+	return embedding, nil
+}
+
 func (srv *notesAPI) ChangeNoteEditPermission(ctx context.Context, req *notesv1.ChangeNoteEditPermissionRequest) (*notesv1.ChangeNoteEditPermissionResponse, error) {
 	token, err := srv.authenticate(ctx)
 	if err != nil {
@@ -518,6 +899,7 @@ func (srv *notesAPI) ChangeNoteEditPermission(ctx context.Context, req *notesv1.
 
 	requesterIsAuthor := note.AuthorAccountID == token.AccountID
 	requesterIsRecipient := req.RecipientAccountId == token.AccountID
+	filter := &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId, ExpectedRevision: req.ExpectedRevision}
 
 	switch req.Type {
 	case notesv1.ChangeNoteEditPermissionRequest_ACTION_GRANT:
@@ -527,9 +909,9 @@ func (srv *notesAPI) ChangeNoteEditPermission(ctx context.Context, req *notesv1.
 		}
 
 		// Grant permissions to target
-		err = srv.notes.GrantNoteEditPermission(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID, req.RecipientAccountId)
+		err = srv.notes.GrantNoteEditPermission(ctx, filter, token.AccountID, req.RecipientAccountId)
 		if err != nil {
-			return nil, statusFromModelError(err)
+			return nil, srv.noteWriteError(ctx, filter, token.AccountID, err)
 		}
 	case notesv1.ChangeNoteEditPermissionRequest_ACTION_REMOVE:
 
@@ -544,9 +926,9 @@ func (srv *notesAPI) ChangeNoteEditPermission(ctx context.Context, req *notesv1.
 			return nil, status.Error(codes.PermissionDenied, "as a non-author you can only remove your own editing rights")
 		}
 
-		err = srv.notes.RemoveEditPermissions(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, req.RecipientAccountId)
+		err = srv.notes.RemoveEditPermissions(ctx, filter, req.RecipientAccountId)
 		if err != nil {
-			return nil, statusFromModelError(err)
+			return nil, srv.noteWriteError(ctx, filter, token.AccountID, err)
 		}
 	}
 
@@ -573,16 +955,13 @@ func (srv *notesAPI) CreateBlockComment(ctx context.Context, req *notesv1.Create
 		return nil, status.Error(codes.PermissionDenied, "you don't have the access rights")
 	}
 
-	res, err := srv.notes.CreateBlockComment(ctx, &models.OneBlockFilter{
-		GroupID: req.GroupId,
-		NoteID:  req.NoteId,
-		BlockID: req.BlockId,
-	}, &models.BlockComment{
+	filter := &models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId, ExpectedRevision: req.ExpectedRevision}
+	res, err := srv.notes.CreateBlockComment(ctx, filter, &models.BlockComment{
 		AuthorAccountID: token.AccountID,
 		Content:         req.Comment.Content,
 	}, token.AccountID)
 	if err != nil {
-		return nil, statusFromModelError(err)
+		return nil, srv.blockWriteError(ctx, filter, token.AccountID, err)
 	}
 
 	return &notesv1.CreateBlockCommentResponse{
@@ -601,15 +980,12 @@ func (srv *notesAPI) DeleteBlockComment(ctx context.Context, req *notesv1.Delete
 		return nil, err
 	}
 
-	_, err = srv.notes.DeleteBlockComment(ctx, &models.OneBlockFilter{
-		GroupID: req.GroupId,
-		NoteID:  req.NoteId,
-		BlockID: req.BlockId,
-	}, &models.BlockComment{
+	filter := &models.OneBlockFilter{GroupID: req.GroupId, NoteID: req.NoteId, BlockID: req.BlockId, ExpectedRevision: req.ExpectedRevision}
+	_, err = srv.notes.DeleteBlockComment(ctx, filter, &models.BlockComment{
 		ID: req.CommentId,
 	}, token.AccountID)
 	if err != nil {
-		return nil, statusFromModelError(err)
+		return nil, srv.blockWriteError(ctx, filter, token.AccountID, err)
 	}
 
 	return &notesv1.DeleteBlockCommentResponse{}, nil
@@ -666,7 +1042,7 @@ func hasEditPermission(AccountsWithEditPermissions []string, recipientAccountID
 func (srv *notesAPI) authenticate(ctx context.Context) (*auth.Token, error) {
 	token, err := srv.auth.TokenFromContext(ctx)
 	if err != nil {
-		srv.logger.Debug("could not authenticate request", zap.Error(err))
+		loggerFromContext(ctx, srv.logger).Debug("could not authenticate request", zap.Error(err))
 		return nil, status.Error(codes.Unauthenticated, "invalid token")
 	}
 	return token, nil
@@ -676,7 +1052,7 @@ func noteModelToString(note *models.Note) string {
 	var fullNote string
 
 	for _, block := range *note.Blocks {
-		if block.Type != "TYPE_CODE" && block.Type != "TYPE_IMAGE" {
+		if block.Type != "TYPE_CODE" && block.Type != "TYPE_IMAGE" && block.Type != "TYPE_SUMMARY" {
 			content, ok := GetBlockContent(&block)
 			if ok {
 				fullNote += content + "\n"
@@ -686,9 +1062,37 @@ func noteModelToString(note *models.Note) string {
 	return fullNote
 }
 
+// noteLang returns the language of the note, detecting it from its text for
+// the notes created before the language was validated.
+func noteLang(note *models.Note, fullNote string) string {
+	if _, ok := language.GetLocale(note.Lang); ok {
+		return note.Lang
+	}
+	return language.DetectLanguage(note.Title + "\n" + fullNote)
+}
+
+// noteContentHash identifies the text of a note, as returned by
+// noteModelToString, to know whether generated content is outdated.
+func noteContentHash(fullNote string) string {
+	hash := sha256.Sum256([]byte(fullNote))
+	return hex.EncodeToString(hash[:])
+}
+
+// summaryContentHash identifies the text of a note and the language its
+// summary is written in, a summary is outdated once either changes.
+func summaryContentHash(fullNote string, lang string) string {
+	return noteContentHash(lang + "\x00" + fullNote)
+}
+
 var protobufFormatToFormatter = map[notesv1.NoteExportFormat]func(*notesv1.Note) ([]byte, error){
 	notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_MARKDOWN: exports.NoteToMarkdown,
 	notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_PDF:      exports.NoteToPDF,
+	notesv1.NoteExportFormat_NOTE_EXPORT_FORMAT_HTML:     exports.NoteToHTML,
+}
+
+var protobufQuizFormatToFormatter = map[notesv1.QuizExportFormat]func(*exports.QuizDeck) ([]byte, error){
+	notesv1.QuizExportFormat_QUIZ_EXPORT_FORMAT_ANKI: exports.QuizDeckToAnki,
+	notesv1.QuizExportFormat_QUIZ_EXPORT_FORMAT_CSV:  exports.QuizDeckToCSV,
 }
 
 func protobufBlocksToModelsBlocks(blocks []*notesv1.Block) []models.NoteBlock {
@@ -748,6 +1152,49 @@ func protobufBlockToModelsBlock(block *notesv1.Block) *models.NoteBlock {
 	case notesv1.Block_TYPE_NUMBER_POINT:
 		val := block.GetNumberPoint()
 		modelsBlock.NumberPoint = &val
+	case notesv1.Block_TYPE_SUMMARY:
+		val := block.GetSummary()
+		modelsBlock.Summary = &val
+	case notesv1.Block_TYPE_CHECKLIST_ITEM:
+		modelsBlock.ChecklistItem = &models.NoteBlockChecklistItem{}
+		if item := block.GetChecklistItem(); item != nil {
+			modelsBlock.ChecklistItem = &models.NoteBlockChecklistItem{
+				Text:              item.Text,
+				Checked:           item.Checked,
+				AssigneeAccountID: item.AssigneeAccountId,
+			}
+		}
+	case notesv1.Block_TYPE_TABLE:
+		modelsBlock.Table = &models.NoteBlockTable{Rows: []models.NoteBlockTableRow{}}
+		if table := block.GetTable(); table != nil {
+			modelsBlock.Table.HasHeaderRow = table.HasHeaderRow
+			for _, row := range table.Rows {
+				modelsRow := models.NoteBlockTableRow{Cells: []models.NoteBlockTableCell{}}
+				for _, cell := range row.Cells {
+					modelsRow.Cells = append(modelsRow.Cells, models.NoteBlockTableCell{
+						Type:  cell.Type.String(),
+						Value: cell.Value,
+					})
+				}
+				modelsBlock.Table.Rows = append(modelsBlock.Table.Rows, modelsRow)
+			}
+		}
+	case notesv1.Block_TYPE_CALLOUT:
+		modelsBlock.Callout = &models.NoteBlockCallout{}
+		if callout := block.GetCallout(); callout != nil {
+			modelsBlock.Callout = &models.NoteBlockCallout{
+				Tone: callout.Tone.String(),
+				Text: callout.Text,
+			}
+		}
+	case notesv1.Block_TYPE_EMBED:
+		modelsBlock.Embed = &models.NoteBlockEmbed{}
+		if embed := block.GetEmbed(); embed != nil {
+			modelsBlock.Embed = &models.NoteBlockEmbed{
+				Url:   embed.Url,
+				Title: embed.Title,
+			}
+		}
 	}
 
 	temporaryStyle := []models.TextStyle{}
@@ -766,6 +1213,12 @@ func protobufBlockToModelsBlock(block *notesv1.Block) *models.NoteBlock {
 				B: style.Color.B,
 			}
 		}
+		if link := style.GetLink(); link != nil {
+			modelStyle.Link = &models.TextStyleLink{
+				NoteID:  link.NoteId,
+				BlockID: link.BlockId,
+			}
+		}
 		temporaryStyle = append(temporaryStyle, modelStyle)
 	}
 	modelsBlock.Styles = &temporaryStyle
@@ -827,6 +1280,8 @@ func modelsNoteToProtobufNote(note *models.Note) *notesv1.Note {
 		AnalyzedAt:      protobufTimestampOrNil(note.AnalyzedAt),
 		Blocks:          make([]*notesv1.Block, lenBlocks),
 		Lang:            note.Lang,
+		SourceNoteId:    note.SourceNoteID,
+		Revision:        note.Revision,
 	}
 
 	if note.Blocks == nil {
@@ -846,8 +1301,9 @@ func modelsBlockToProtobufBlock(block *models.NoteBlock) *notesv1.Block {
 		blockType = int32(notesv1.Block_TYPE_INVALID)
 	}
 	ret := &notesv1.Block{
-		Id:   block.ID,
-		Type: notesv1.Block_Type(blockType),
+		Id:       block.ID,
+		Type:     notesv1.Block_Type(blockType),
+		Revision: block.Revision,
 	}
 
 	switch notesv1.Block_Type(blockType) {
@@ -899,6 +1355,57 @@ func modelsBlockToProtobufBlock(block *models.NoteBlock) *notesv1.Block {
 		ret.Data = &notesv1.Block_NumberPoint{
 			NumberPoint: stringPtrValueOrFallback(block.NumberPoint, ""),
 		}
+	case notesv1.Block_TYPE_SUMMARY:
+		ret.Data = &notesv1.Block_Summary{
+			Summary: stringPtrValueOrFallback(block.Summary, ""),
+		}
+	case notesv1.Block_TYPE_CHECKLIST_ITEM:
+		if block.ChecklistItem == nil {
+			break
+		}
+		ret.Data = &notesv1.Block_ChecklistItem_{
+			ChecklistItem: &notesv1.Block_ChecklistItem{
+				Text:              block.ChecklistItem.Text,
+				Checked:           block.ChecklistItem.Checked,
+				AssigneeAccountId: block.ChecklistItem.AssigneeAccountID,
+			},
+		}
+	case notesv1.Block_TYPE_TABLE:
+		if block.Table == nil {
+			break
+		}
+		table := &notesv1.Block_Table{HasHeaderRow: block.Table.HasHeaderRow}
+		for _, row := range block.Table.Rows {
+			protobufRow := &notesv1.Block_Table_Row{}
+			for _, cell := range row.Cells {
+				protobufRow.Cells = append(protobufRow.Cells, &notesv1.Block_Table_Cell{
+					Type:  notesv1.Block_Table_Cell_Type(notesv1.Block_Table_Cell_Type_value[cell.Type]),
+					Value: cell.Value,
+				})
+			}
+			table.Rows = append(table.Rows, protobufRow)
+		}
+		ret.Data = &notesv1.Block_Table_{Table: table}
+	case notesv1.Block_TYPE_CALLOUT:
+		if block.Callout == nil {
+			break
+		}
+		ret.Data = &notesv1.Block_Callout_{
+			Callout: &notesv1.Block_Callout{
+				Tone: notesv1.Block_Callout_Tone(notesv1.Block_Callout_Tone_value[block.Callout.Tone]),
+				Text: block.Callout.Text,
+			},
+		}
+	case notesv1.Block_TYPE_EMBED:
+		if block.Embed == nil {
+			break
+		}
+		ret.Data = &notesv1.Block_Embed_{
+			Embed: &notesv1.Block_Embed{
+				Url:   block.Embed.Url,
+				Title: block.Embed.Title,
+			},
+		}
 	}
 
 	if block.Styles != nil {
@@ -917,6 +1424,12 @@ func modelsBlockToProtobufBlock(block *models.NoteBlock) *notesv1.Block {
 					B: int32(style.Color.B),
 				}
 			}
+			if style.Link != nil {
+				modelStyle.Link = &notesv1.Block_TextStyle_Link{
+					NoteId:  style.Link.NoteID,
+					BlockId: style.Link.BlockID,
+				}
+			}
 
 			ret.Styles = append(ret.Styles, modelStyle)
 		}
@@ -932,6 +1445,22 @@ func stringPtrValueOrFallback(ptr *string, fallback string) string {
 	return fallback
 }
 
+// modelsThreadsToProtobufBlocks copies the comment threads of the blocks of the
+// note to the blocks of its protobuf counterpart.
+func modelsThreadsToProtobufBlocks(note *models.Note, protobufNote *notesv1.Note) {
+	if note.Blocks == nil {
+		return
+	}
+	for i, block := range *note.Blocks {
+		if block.Thread == nil {
+			continue
+		}
+		for j := range *block.Thread {
+			protobufNote.Blocks[i].Thread = append(protobufNote.Blocks[i].Thread, modelsCommentToProtobufComment(&(*block.Thread)[j]))
+		}
+	}
+}
+
 func modelsCommentToProtobufComment(cmt *models.BlockComment) *notesv1.Block_Comment {
 	protobufComment := &notesv1.Block_Comment{
 		Id:       cmt.ID,
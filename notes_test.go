@@ -1,6 +1,8 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"notes-service/models"
 	notesv1 "notes-service/protorepo/noted/notes/v1"
@@ -77,6 +79,31 @@ func TestNotesSuite(t *testing.T) {
 		require.Nil(t, res)
 	})
 
+	t.Run("cannot-create-note-with-unsupported-lang", func(t *testing.T) {
+		res, err := tu.notes.CreateNote(edouard.Context, &notesv1.CreateNoteRequest{
+			GroupId: edouardGroup.ID,
+			Title:   "My New Note",
+			Lang:    "klingon",
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("create-note-without-lang-detects-it", func(t *testing.T) {
+		res, err := tu.notes.CreateNote(edouard.Context, &notesv1.CreateNoteRequest{
+			GroupId: edouardGroup.ID,
+			Title:   "La révolution",
+			Blocks: []*notesv1.Block{
+				{
+					Type: notesv1.Block_TYPE_PARAGRAPH,
+					Data: &notesv1.Block_Paragraph{Paragraph: "Elle est le point de départ de la fin de la monarchie et des privilèges."},
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "fr", res.Note.Lang)
+	})
+
 	t.Run("member-can-create-note-with-blocks", func(t *testing.T) {
 		res, err := tu.notes.CreateNote(edouard.Context, &notesv1.CreateNoteRequest{
 			GroupId: edouardGroup.ID,
@@ -275,6 +302,135 @@ func TestNotesSuite(t *testing.T) {
 
 	edouardNote := newTestNote(t, tu, edouardGroup, edouard, []*notesv1.Block{})
 
+	t.Run("member-can-create-note-with-structured-blocks", func(t *testing.T) {
+		res, err := tu.notes.CreateNote(edouard.Context, &notesv1.CreateNoteRequest{
+			GroupId: edouardGroup.ID,
+			Title:   "My Structured Note",
+			Blocks: []*notesv1.Block{
+				{
+					Type: notesv1.Block_TYPE_CHECKLIST_ITEM,
+					Data: &notesv1.Block_ChecklistItem_{
+						ChecklistItem: &notesv1.Block_ChecklistItem{Text: "Sample Task", Checked: true, AssigneeAccountId: maxime.ID},
+					},
+				},
+				{
+					Type: notesv1.Block_TYPE_TABLE,
+					Data: &notesv1.Block_Table_{
+						Table: &notesv1.Block_Table{
+							HasHeaderRow: true,
+							Rows: []*notesv1.Block_Table_Row{
+								{Cells: []*notesv1.Block_Table_Cell{
+									{Type: notesv1.Block_Table_Cell_TYPE_TEXT, Value: "Name"},
+									{Type: notesv1.Block_Table_Cell_TYPE_TEXT, Value: "Score"},
+								}},
+								{Cells: []*notesv1.Block_Table_Cell{
+									{Type: notesv1.Block_Table_Cell_TYPE_TEXT, Value: "Ada"},
+									{Type: notesv1.Block_Table_Cell_TYPE_NUMBER, Value: "18.5"},
+								}},
+							},
+						},
+					},
+				},
+				{
+					Type: notesv1.Block_TYPE_CALLOUT,
+					Data: &notesv1.Block_Callout_{
+						Callout: &notesv1.Block_Callout{Tone: notesv1.Block_Callout_TONE_TIP, Text: "Sample Callout"},
+					},
+				},
+				{
+					Type: notesv1.Block_TYPE_DIVIDER,
+				},
+				{
+					Type: notesv1.Block_TYPE_EMBED,
+					Data: &notesv1.Block_Embed_{
+						Embed: &notesv1.Block_Embed{Url: "https://noted.fr", Title: "Noted"},
+					},
+				},
+			},
+			Lang: "en",
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		require.Len(t, res.Note.Blocks, 5)
+		require.Equal(t, notesv1.Block_TYPE_CHECKLIST_ITEM, res.Note.Blocks[0].Type)
+		require.Equal(t, "Sample Task", res.Note.Blocks[0].GetChecklistItem().Text)
+		require.True(t, res.Note.Blocks[0].GetChecklistItem().Checked)
+		require.Equal(t, maxime.ID, res.Note.Blocks[0].GetChecklistItem().AssigneeAccountId)
+		require.Equal(t, notesv1.Block_TYPE_TABLE, res.Note.Blocks[1].Type)
+		require.True(t, res.Note.Blocks[1].GetTable().HasHeaderRow)
+		require.Len(t, res.Note.Blocks[1].GetTable().Rows, 2)
+		require.Equal(t, notesv1.Block_Table_Cell_TYPE_NUMBER, res.Note.Blocks[1].GetTable().Rows[1].Cells[1].Type)
+		require.Equal(t, "18.5", res.Note.Blocks[1].GetTable().Rows[1].Cells[1].Value)
+		require.Equal(t, notesv1.Block_TYPE_CALLOUT, res.Note.Blocks[2].Type)
+		require.Equal(t, notesv1.Block_Callout_TONE_TIP, res.Note.Blocks[2].GetCallout().Tone)
+		require.Equal(t, "Sample Callout", res.Note.Blocks[2].GetCallout().Text)
+		require.Equal(t, notesv1.Block_TYPE_DIVIDER, res.Note.Blocks[3].Type)
+		require.Nil(t, res.Note.Blocks[3].Data)
+		require.Equal(t, notesv1.Block_TYPE_EMBED, res.Note.Blocks[4].Type)
+		require.Equal(t, "https://noted.fr", res.Note.Blocks[4].GetEmbed().Url)
+		require.Equal(t, "Noted", res.Note.Blocks[4].GetEmbed().Title)
+	})
+
+	t.Run("cannot-create-note-with-invalid-structured-blocks", func(t *testing.T) {
+		invalidBlocks := map[string]*notesv1.Block{
+			"checklist-item-without-data": {Type: notesv1.Block_TYPE_CHECKLIST_ITEM},
+			"table-with-ragged-rows": {Type: notesv1.Block_TYPE_TABLE, Data: &notesv1.Block_Table_{Table: &notesv1.Block_Table{
+				Rows: []*notesv1.Block_Table_Row{
+					{Cells: []*notesv1.Block_Table_Cell{{Type: notesv1.Block_Table_Cell_TYPE_TEXT}, {Type: notesv1.Block_Table_Cell_TYPE_TEXT}}},
+					{Cells: []*notesv1.Block_Table_Cell{{Type: notesv1.Block_Table_Cell_TYPE_TEXT}}},
+				},
+			}}},
+			"table-with-invalid-number": {Type: notesv1.Block_TYPE_TABLE, Data: &notesv1.Block_Table_{Table: &notesv1.Block_Table{
+				Rows: []*notesv1.Block_Table_Row{
+					{Cells: []*notesv1.Block_Table_Cell{{Type: notesv1.Block_Table_Cell_TYPE_NUMBER, Value: "douze"}}},
+				},
+			}}},
+			"callout-without-tone": {Type: notesv1.Block_TYPE_CALLOUT, Data: &notesv1.Block_Callout_{Callout: &notesv1.Block_Callout{Text: "Sample"}}},
+			"divider-with-data":    {Type: notesv1.Block_TYPE_DIVIDER, Data: &notesv1.Block_Paragraph{Paragraph: "Sample"}},
+			"embed-without-scheme": {Type: notesv1.Block_TYPE_EMBED, Data: &notesv1.Block_Embed_{Embed: &notesv1.Block_Embed{Url: "noted.fr"}}},
+		}
+
+		for name, block := range invalidBlocks {
+			block := block
+			t.Run(name, func(t *testing.T) {
+				res, err := tu.notes.CreateNote(edouard.Context, &notesv1.CreateNoteRequest{
+					GroupId: edouardGroup.ID,
+					Title:   "Sample Title",
+					Blocks:  []*notesv1.Block{block},
+					Lang:    "en",
+				})
+				requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+				require.Nil(t, res)
+			})
+		}
+	})
+
+	t.Run("cannot-assign-checklist-item-outside-of-group", func(t *testing.T) {
+		block := &notesv1.Block{
+			Type: notesv1.Block_TYPE_CHECKLIST_ITEM,
+			Data: &notesv1.Block_ChecklistItem_{
+				ChecklistItem: &notesv1.Block_ChecklistItem{Text: "Sample Task", AssigneeAccountId: stranger.ID},
+			},
+		}
+
+		res, err := tu.notes.CreateNote(edouard.Context, &notesv1.CreateNoteRequest{
+			GroupId: edouardGroup.ID,
+			Title:   "Sample Title",
+			Blocks:  []*notesv1.Block{block},
+			Lang:    "en",
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+		require.Nil(t, res)
+
+		inserted, err := tu.notes.InsertBlock(edouard.Context, &notesv1.InsertBlockRequest{
+			GroupId: edouardGroup.ID,
+			NoteId:  edouardNote.ID,
+			Block:   block,
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+		require.Nil(t, inserted)
+	})
+
 	t.Run("owner-can-update-note-title", func(t *testing.T) {
 		before := time.Now()
 		res, err := tu.notes.UpdateNote(edouard.Context, &notesv1.UpdateNoteRequest{
@@ -694,6 +850,57 @@ func TestNotesSuite(t *testing.T) {
 		quizIDContainer = res.Quiz.Id
 	})
 
+	//
+	//
+	// Summary tests
+	//
+	//
+
+	t.Run("get-summary-returns-stored-summary-when-note-unchanged", func(t *testing.T) {
+		noteFilter := &models.OneNoteFilter{GroupID: note.Group.ID, NoteID: note.ID}
+		storedNote, err := tu.notesRepository.GetNote(testUser.Context, noteFilter, testUser.ID)
+		require.NoError(t, err)
+
+		_, err = tu.notesRepository.StoreSummaryInternal(testUser.Context, noteFilter, &models.Summary{
+			Content:     "- Stored summary",
+			ContentHash: summaryContentHash(noteModelToString(storedNote), noteLang(storedNote, noteModelToString(storedNote))),
+		})
+		require.NoError(t, err)
+
+		res, err := tu.notes.GetSummary(testUser.Context, &notesv1.GetSummaryRequest{
+			GroupId: note.Group.ID,
+			NoteId:  note.ID,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "- Stored summary", res.Summary)
+	})
+
+	t.Run("stranger-cannot-get-summary", func(t *testing.T) {
+		res, err := tu.notes.GetSummary(stranger.Context, &notesv1.GetSummaryRequest{
+			GroupId: note.Group.ID,
+			NoteId:  note.ID,
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("summary-blocks-are-ignored-by-content-hash", func(t *testing.T) {
+		paragraph := "Some text"
+		summary := "- Some summary"
+		withoutSummary := &models.Note{Blocks: &[]models.NoteBlock{
+			{Type: "TYPE_PARAGRAPH", Paragraph: &paragraph},
+		}}
+		withSummary := &models.Note{Blocks: &[]models.NoteBlock{
+			{Type: "TYPE_PARAGRAPH", Paragraph: &paragraph},
+			{Type: "TYPE_SUMMARY", Summary: &summary},
+		}}
+		require.Equal(t, noteContentHash(noteModelToString(withoutSummary)), noteContentHash(noteModelToString(withSummary)))
+	})
+
+	t.Run("summary-content-hash-depends-on-the-language", func(t *testing.T) {
+		require.NotEqual(t, summaryContentHash("Some text", "en"), summaryContentHash("Some text", "fr"))
+	})
+
 	t.Run("quiz-stored-after-generated-quiz-and-author-can-list-quizs", func(t *testing.T) {
 		res, err := tu.notes.ListQuizs(note.Author.Context, &notesv1.ListQuizsRequest{
 			GroupId: note.Group.ID,
@@ -714,6 +921,67 @@ func TestNotesSuite(t *testing.T) {
 		require.Nil(t, res)
 	})
 
+	t.Run("author-can-export-quiz-as-csv", func(t *testing.T) {
+		quiz, err := tu.notesRepository.StoreNewQuiz(note.Author.Context, &models.OneNoteFilter{
+			GroupID: note.Group.ID,
+			NoteID:  note.ID,
+		}, &models.Quiz{QuizQuestions: []models.QuizQuestion{{
+			Question:  "Qui a écrit le premier programme ?",
+			Answers:   []string{"Ada Lovelace", "Charles Babbage"},
+			Solutions: []string{"Ada Lovelace"},
+		}}}, note.Author.ID)
+		require.NoError(t, err)
+
+		res, err := tu.notes.ExportQuiz(note.Author.Context, &notesv1.ExportQuizRequest{
+			GroupId:      note.Group.ID,
+			NoteId:       note.ID,
+			QuizId:       quiz.ID,
+			ExportFormat: notesv1.QuizExportFormat_QUIZ_EXPORT_FORMAT_CSV,
+		})
+		require.NoError(t, err)
+		require.Contains(t, string(res.File), "Qui a écrit le premier programme ?,\"Ada Lovelace\nCharles Babbage\",Ada Lovelace,")
+
+		err = tu.notesRepository.DeleteQuiz(note.Author.Context, &models.OneNoteFilter{
+			GroupID: note.Group.ID,
+			NoteID:  note.ID,
+		}, quiz.ID, note.Author.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("author-can-export-quizs-as-anki-package", func(t *testing.T) {
+		res, err := tu.notes.ExportQuiz(note.Author.Context, &notesv1.ExportQuizRequest{
+			GroupId:      note.Group.ID,
+			NoteId:       note.ID,
+			ExportFormat: notesv1.QuizExportFormat_QUIZ_EXPORT_FORMAT_ANKI,
+		})
+		require.NoError(t, err)
+
+		archive, err := zip.NewReader(bytes.NewReader(res.File), int64(len(res.File)))
+		require.NoError(t, err)
+		require.Len(t, archive.File, 2)
+	})
+
+	t.Run("cannot-export-unknown-quiz", func(t *testing.T) {
+		res, err := tu.notes.ExportQuiz(note.Author.Context, &notesv1.ExportQuizRequest{
+			GroupId:      note.Group.ID,
+			NoteId:       note.ID,
+			QuizId:       "unknown",
+			ExportFormat: notesv1.QuizExportFormat_QUIZ_EXPORT_FORMAT_CSV,
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("stranger-cannot-export-quiz", func(t *testing.T) {
+		res, err := tu.notes.ExportQuiz(stranger.Context, &notesv1.ExportQuizRequest{
+			GroupId:      note.Group.ID,
+			NoteId:       note.ID,
+			ExportFormat: notesv1.QuizExportFormat_QUIZ_EXPORT_FORMAT_CSV,
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Nil(t, res)
+	})
+
 	t.Run("stranger-cannot-delete-quiz", func(t *testing.T) {
 		err := tu.notesRepository.DeleteQuiz(stranger.Context, &models.OneNoteFilter{
 			GroupID: note.Group.ID,
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"notes-service/language"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"notes-service/validators"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Maximum number of notes whose blocks are compared to the question.
+	maxQuestionNotes = 5
+	// Maximum number of blocks given to the language service to answer.
+	maxQuestionPassages = 8
+	// Maximum length of the text of the blocks given to the language service.
+	maxQuestionPassagesLength = 12000
+)
+
+// questionPassage is a block retrieved to answer a question.
+type questionPassage struct {
+	note  *models.Note
+	block *models.NoteBlock
+	text  string
+	score float32
+}
+
+func (srv *notesAPI) AskNotes(req *notesv1.AskNotesRequest, stream notesv1.NotesAPI_AskNotesServer) error {
+	ctx := stream.Context()
+
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validators.ValidateAskNotesRequest(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return statusFromModelError(err)
+	}
+
+	if srv.embeddings == nil {
+		return status.Error(codes.Unavailable, "questions are not available without an embedding provider")
+	}
+
+	// Retrieving the passages calls the providers too, the limits are checked
+	// before.
+	err = srv.checkLanguageRateLimits(language.MethodAnswer, token.AccountID, req.GroupId)
+	if err != nil {
+		return err
+	}
+
+	notes, err := srv.listQuestionNotes(ctx, req, token.AccountID)
+	if err != nil {
+		return statusFromModelError(err)
+	}
+
+	passages, err := srv.retrieveQuestionPassages(ctx, req.Question, notes)
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to retrieve question passages", zap.Error(err))
+		return status.Errorf(codes.Internal, "failed to answer question for groupId : %s", req.GroupId)
+	}
+	if len(passages) == 0 {
+		return status.Error(codes.NotFound, "no note content to answer the question from")
+	}
+
+	excerpts := make([]string, len(passages))
+	for i, passage := range passages {
+		excerpts[i] = passage.note.Title + " : " + passage.text
+	}
+
+	answer, err := srv.language.AnswerQuestion(ctx, req.Question, excerpts, language.DetectLanguage(req.Question), func(chunk string) error {
+		return stream.Send(&notesv1.AskNotesResponse{Answer: chunk})
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled {
+			return status.Error(codes.Canceled, "question canceled")
+		}
+		loggerFromContext(ctx, srv.logger).Error("failed to answer question", zap.Error(err))
+		return status.Errorf(codes.Internal, "failed to answer question for groupId : %s", req.GroupId)
+	}
+
+	return stream.Send(&notesv1.AskNotesResponse{Citations: citationsFromAnswer(answer, passages)})
+}
+
+// listQuestionNotes returns the notes in the scope of the question: a note, a
+// folder or the whole group.
+func (srv *notesAPI) listQuestionNotes(ctx context.Context, req *notesv1.AskNotesRequest, accountID string) ([]*models.Note, error) {
+	if req.NoteId != "" {
+		note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, accountID)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Note{note}, nil
+	}
+
+	return srv.notes.ListAllNotesInternal(ctx, &models.ManyNotesFilter{GroupID: req.GroupId, FolderID: req.FolderId})
+}
+
+// retrieveQuestionPassages returns the blocks of the notes which are the most
+// relevant to the question, the most relevant first.
+//
+// The notes are first ranked with their stored embedding, only the blocks of
+// the closest notes are then embedded and compared to the question.
+func (srv *notesAPI) retrieveQuestionPassages(ctx context.Context, question string, notes []*models.Note) ([]*questionPassage, error) {
+	questionVector, err := srv.embeddings.Embed(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+
+	type rankedNote struct {
+		note  *models.Note
+		score float32
+	}
+	ranked := []*rankedNote{}
+	for _, note := range notes {
+		if note.Blocks == nil {
+			continue
+		}
+		fullNote := noteModelToString(note)
+		if strings.TrimSpace(fullNote) == "" {
+			continue
+		}
+		embedding, err := srv.noteEmbedding(ctx, note, fullNote)
+		if err != nil {
+			return nil, err
+		}
+		ranked = append(ranked, &rankedNote{note: note, score: language.CosineSimilarity(questionVector, embedding.Vector)})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+	if len(ranked) > maxQuestionNotes {
+		ranked = ranked[:maxQuestionNotes]
+	}
+
+	passages := []*questionPassage{}
+	for _, r := range ranked {
+		notePassages, err := srv.notePassages(ctx, r.note, questionVector)
+		if err != nil {
+			return nil, err
+		}
+		passages = append(passages, notePassages...)
+	}
+
+	sort.SliceStable(passages, func(i, j int) bool {
+		return passages[i].score > passages[j].score
+	})
+
+	length := 0
+	for i, passage := range passages {
+		length += len(passage.text)
+		if i >= maxQuestionPassages || (i > 0 && length > maxQuestionPassagesLength) {
+			return passages[:i], nil
+		}
+	}
+
+	return passages, nil
+}
+
+// notePassages compares the blocks of the note to the question. The block
+// embeddings are stored on the note by content hash, only the blocks which
+// changed since the last question are embedded, all in one call.
+func (srv *notesAPI) notePassages(ctx context.Context, note *models.Note, questionVector []float32) ([]*questionPassage, error) {
+	model := srv.embeddings.Model()
+	stored := make(map[string]*models.NoteEmbedding)
+	for _, embedding := range note.BlockEmbeddings {
+		if embedding.Model == model {
+			stored[embedding.ContentHash] = embedding
+		}
+	}
+
+	passages := []*questionPassage{}
+	passageEmbeddings := []*models.NoteEmbedding{}
+	used := []*models.NoteEmbedding{}
+	usedHashes := make(map[string]bool)
+	missing := []*models.NoteEmbedding{}
+	inputs := []string{}
+	for i := range *note.Blocks {
+		block := &(*note.Blocks)[i]
+		if block.Type == "TYPE_SUMMARY" {
+			continue
+		}
+		text, ok := GetBlockContent(block)
+		if !ok || strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		// The title gives context to short blocks such as bullet points.
+		input := note.Title + "\n" + text
+		contentHash := noteContentHash(input)
+		embedding, ok := stored[contentHash]
+		if !ok {
+			embedding = &models.NoteEmbedding{Model: model, ContentHash: contentHash, UpdatedAt: time.Now()}
+			stored[contentHash] = embedding
+			missing = append(missing, embedding)
+			inputs = append(inputs, input)
+		}
+		if !usedHashes[contentHash] {
+			usedHashes[contentHash] = true
+			used = append(used, embedding)
+		}
+
+		passages = append(passages, &questionPassage{note: note, block: block, text: text})
+		passageEmbeddings = append(passageEmbeddings, embedding)
+	}
+
+	if len(missing) > 0 {
+		vectors, err := srv.embeddings.EmbedAll(ctx, inputs)
+		if err != nil {
+			return nil, err
+		}
+		for i, vector := range vectors {
+			missing[i].Vector = vector
+		}
+
+		// The embeddings of the blocks which are gone are dropped.
+		err = srv.notes.StoreBlockEmbeddingsInternal(ctx, &models.OneNoteFilter{GroupID: note.GroupID, NoteID: note.ID}, used)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, passage := range passages {
+		passage.score = language.CosineSimilarity(questionVector, passageEmbeddings[i].Vector)
+	}
+
+	return passages, nil
+}
+
+var citationReference = regexp.MustCompile(`\[(\d+)\]`)
+
+// citationsFromAnswer returns the passages referenced as [n] in the answer, in
+// their order of appearance.
+func citationsFromAnswer(answer string, passages []*questionPassage) []*notesv1.AskNotesCitation {
+	citations := []*notesv1.AskNotesCitation{}
+	seen := map[int]bool{}
+
+	for _, match := range citationReference.FindAllStringSubmatch(answer, -1) {
+		index, err := strconv.Atoi(match[1])
+		if err != nil || index < 1 || index > len(passages) || seen[index] {
+			continue
+		}
+		seen[index] = true
+
+		passage := passages[index-1]
+		citations = append(citations, &notesv1.AskNotesCitation{
+			Index:     int32(index),
+			NoteId:    passage.note.ID,
+			BlockId:   passage.block.ID,
+			NoteTitle: passage.note.Title,
+		})
+	}
+
+	return citations
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"notes-service/language"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// citingAnswerer answers every question by citing its first excerpt.
+type citingAnswerer struct {
+	language.Service
+	excerpts []string
+}
+
+func (s *citingAnswerer) AnswerQuestion(ctx context.Context, question string, excerpts []string, lang string, onChunk func(chunk string) error) (string, error) {
+	s.excerpts = excerpts
+	for _, chunk := range []string{"Les plantes ", "utilisent la lumière [1]."} {
+		err := onChunk(chunk)
+		if err != nil {
+			return "", err
+		}
+	}
+	return "Les plantes utilisent la lumière [1].", nil
+}
+
+// countingEmbeddings counts the texts embedded by the provider it wraps.
+type countingEmbeddings struct {
+	language.EmbeddingProvider
+	embedded int
+}
+
+func (p *countingEmbeddings) Embed(ctx context.Context, input string) ([]float32, error) {
+	p.embedded++
+	return p.EmbeddingProvider.Embed(ctx, input)
+}
+
+func (p *countingEmbeddings) EmbedAll(ctx context.Context, inputs []string) ([][]float32, error) {
+	p.embedded += len(inputs)
+	return p.EmbeddingProvider.EmbedAll(ctx, inputs)
+}
+
+type askNotesServerStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses []*notesv1.AskNotesResponse
+}
+
+func (s *askNotesServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *askNotesServerStream) Send(res *notesv1.AskNotesResponse) error {
+	s.responses = append(s.responses, res)
+	return nil
+}
+
+func TestQuestionsSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	answerer := &citingAnswerer{}
+	tu.notes.(*notesAPI).language = answerer
+	edouard := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, edouard)
+	newTestNote(t, tu, group, edouard, []*notesv1.Block{
+		{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Napoléon fut sacré empereur en 1804."}},
+	})
+	plants := newTestNote(t, tu, group, edouard, []*notesv1.Block{
+		{Type: notesv1.Block_TYPE_HEADING_1, Data: &notesv1.Block_Heading{Heading: "Photosynthèse"}},
+		{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Les plantes captent la lumière grâce à la chlorophylle."}},
+	})
+
+	t.Run("member-can-ask-question-to-group", func(t *testing.T) {
+		stream := &askNotesServerStream{ctx: edouard.Context}
+		err := tu.notes.AskNotes(&notesv1.AskNotesRequest{
+			GroupId:  group.ID,
+			Question: "Comment les plantes captent-elles la lumière ?",
+		}, stream)
+		require.NoError(t, err)
+
+		require.Len(t, stream.responses, 3)
+		require.Equal(t, "Les plantes ", stream.responses[0].Answer)
+		require.Equal(t, "utilisent la lumière [1].", stream.responses[1].Answer)
+		require.Contains(t, answerer.excerpts[0], "chlorophylle", "the most relevant block should come first")
+
+		note, err := tu.notes.GetNote(edouard.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: plants.ID})
+		require.NoError(t, err)
+
+		citations := stream.responses[2].Citations
+		require.Len(t, citations, 1)
+		require.Equal(t, int32(1), citations[0].Index)
+		require.Equal(t, plants.ID, citations[0].NoteId)
+		require.Equal(t, note.Note.Blocks[1].Id, citations[0].BlockId)
+	})
+
+	t.Run("question-stores-note-embeddings", func(t *testing.T) {
+		note, err := tu.notesRepository.GetNote(context.TODO(), &models.OneNoteFilter{GroupID: group.ID, NoteID: plants.ID}, edouard.ID)
+		require.NoError(t, err)
+		require.NotNil(t, note.Embedding, "the embedding computed to rank the note should be stored")
+		require.Equal(t, tu.notes.(*notesAPI).embeddings.Model(), note.Embedding.Model)
+	})
+
+	t.Run("question-reuses-block-embeddings", func(t *testing.T) {
+		note, err := tu.notesRepository.GetNote(context.TODO(), &models.OneNoteFilter{GroupID: group.ID, NoteID: plants.ID}, edouard.ID)
+		require.NoError(t, err)
+		require.Len(t, note.BlockEmbeddings, 2, "the embeddings of the blocks compared to the question should be stored")
+
+		api := tu.notes.(*notesAPI)
+		embeddings := &countingEmbeddings{EmbeddingProvider: api.embeddings}
+		api.embeddings = embeddings
+		defer func() { api.embeddings = embeddings.EmbeddingProvider }()
+
+		stream := &askNotesServerStream{ctx: edouard.Context}
+		err = tu.notes.AskNotes(&notesv1.AskNotesRequest{
+			GroupId:  group.ID,
+			NoteId:   plants.ID,
+			Question: "Que captent les plantes ?",
+		}, stream)
+		require.NoError(t, err)
+		require.Equal(t, 1, embeddings.embedded, "only the question should be embedded")
+	})
+
+	t.Run("rate-limited-question-does-not-call-the-providers", func(t *testing.T) {
+		api := tu.notes.(*notesAPI)
+		embeddings := &countingEmbeddings{EmbeddingProvider: api.embeddings}
+		api.embeddings = embeddings
+		limiter := api.accountsLimiter
+		api.accountsLimiter = language.NewRateLimiter(1, time.Hour)
+		api.accountsLimiter.Allow(edouard.ID)
+		defer func() {
+			api.embeddings = embeddings.EmbeddingProvider
+			api.accountsLimiter = limiter
+		}()
+
+		stream := &askNotesServerStream{ctx: edouard.Context}
+		err := tu.notes.AskNotes(&notesv1.AskNotesRequest{
+			GroupId:  group.ID,
+			Question: "Comment les plantes captent-elles la lumière ?",
+		}, stream)
+		requireErrorHasGRPCCode(t, codes.ResourceExhausted, err)
+		require.Zero(t, embeddings.embedded)
+	})
+
+	t.Run("question-rejected-by-group-limit-is-not-counted-for-the-account", func(t *testing.T) {
+		api := tu.notes.(*notesAPI)
+		accountsLimiter, groupsLimiter := api.accountsLimiter, api.groupsLimiter
+		api.accountsLimiter = language.NewRateLimiter(1, time.Hour)
+		api.groupsLimiter = language.NewRateLimiter(1, time.Hour)
+		api.groupsLimiter.Allow(group.ID)
+		defer func() {
+			api.accountsLimiter, api.groupsLimiter = accountsLimiter, groupsLimiter
+		}()
+
+		stream := &askNotesServerStream{ctx: edouard.Context}
+		err := tu.notes.AskNotes(&notesv1.AskNotesRequest{
+			GroupId:  group.ID,
+			Question: "Comment les plantes captent-elles la lumière ?",
+		}, stream)
+		requireErrorHasGRPCCode(t, codes.ResourceExhausted, err)
+		require.True(t, api.accountsLimiter.Allow(edouard.ID), "the account should still have its call")
+	})
+
+	t.Run("member-can-ask-question-to-note", func(t *testing.T) {
+		stream := &askNotesServerStream{ctx: edouard.Context}
+		err := tu.notes.AskNotes(&notesv1.AskNotesRequest{
+			GroupId:  group.ID,
+			NoteId:   plants.ID,
+			Question: "Quand Napoléon fut-il sacré ?",
+		}, stream)
+		require.NoError(t, err)
+		for _, excerpt := range answerer.excerpts {
+			require.NotContains(t, excerpt, "Napoléon", "only the blocks of the note should be retrieved")
+		}
+	})
+
+	t.Run("question-cannot-target-note-and-folder", func(t *testing.T) {
+		stream := &askNotesServerStream{ctx: edouard.Context}
+		err := tu.notes.AskNotes(&notesv1.AskNotesRequest{
+			GroupId:  group.ID,
+			NoteId:   plants.ID,
+			FolderId: "folder",
+			Question: "Comment les plantes captent-elles la lumière ?",
+		}, stream)
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+	})
+
+	t.Run("stranger-cannot-ask-question", func(t *testing.T) {
+		stream := &askNotesServerStream{ctx: stranger.Context}
+		err := tu.notes.AskNotes(&notesv1.AskNotesRequest{
+			GroupId:  group.ID,
+			Question: "Comment les plantes captent-elles la lumière ?",
+		}, stream)
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Empty(t, stream.responses)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"notes-service/models"
 	notesv1 "notes-service/protorepo/noted/notes/v1"
 	"notes-service/validators"
+	"sort"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -21,8 +22,21 @@ type recommendationsAPI struct {
 	auth     auth.Service
 	language language.Service
 	notes    models.NotesRepository
+	groups   models.GroupsRepository
 }
 
+const (
+	// Maximum number of related notes recommended for a note.
+	maxRelatedNotes = 5
+	// Minimum similarity for a note to be recommended.
+	minRelatedNoteSimilarity = 0.3
+	// Maximum number of embeddings compared to the note's, the most recent
+	// notes are compared first.
+	maxRelatedNoteCandidates = 1000
+	// Number of groups listed per page when looking for the caller's groups.
+	relatedGroupsPageSize = 100
+)
+
 var _ notesv1.RecommendationsAPIServer = &recommendationsAPI{}
 
 // Generate companion component data
@@ -39,7 +53,7 @@ func (srv *recommendationsAPI) GenerateWidgets(ctx context.Context, req *notesv1
 
 	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{NoteID: req.NoteId, GroupID: req.GroupId}, token.AccountID)
 	if err != nil {
-		srv.logger.Error("failed to get note", zap.Error(err))
+		loggerFromContext(ctx, srv.logger).Error("failed to get note", zap.Error(err))
 		return nil, status.Error(codes.NotFound, "could not get note.")
 	}
 
@@ -63,9 +77,93 @@ func (srv *recommendationsAPI) GenerateWidgets(ctx context.Context, req *notesv1
 		})
 	}
 
+	relatedNotes, err := srv.listRelatedNotes(ctx, note, token.AccountID)
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to list related notes", zap.Error(err))
+	}
+
+	for _, related := range relatedNotes {
+		widgets = append(widgets, &notesv1.Widget{
+			Type: &notesv1.Widget_RelatedNoteWidget{
+				RelatedNoteWidget: &notesv1.RelatedNoteWidget{
+					NoteId:     related.note.ID,
+					GroupId:    related.note.GroupID,
+					Title:      related.note.Title,
+					Similarity: related.similarity,
+				},
+			},
+		})
+	}
+
 	return &notesv1.GenerateWidgetsResponse{Widgets: widgets}, nil
 }
 
+type relatedNote struct {
+	note       *models.Note
+	similarity float32
+}
+
+// listRelatedNotes returns the notes of the caller's groups whose embedding
+// is the closest to the note's, the most similar first.
+func (srv *recommendationsAPI) listRelatedNotes(ctx context.Context, note *models.Note, accountID string) ([]*relatedNote, error) {
+	if note.Embedding == nil {
+		return nil, nil
+	}
+
+	groupIDs, err := srv.listGroupIDs(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := srv.notes.ListEmbeddingsInternal(ctx, &models.ManyEmbeddingsFilter{
+		GroupIDs: groupIDs,
+		Model:    note.Embedding.Model,
+		Limit:    maxRelatedNoteCandidates,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	related := []*relatedNote{}
+	for _, other := range notes {
+		if other.ID == note.ID || other.Embedding == nil {
+			continue
+		}
+		similarity := language.CosineSimilarity(note.Embedding.Vector, other.Embedding.Vector)
+		if similarity < minRelatedNoteSimilarity {
+			continue
+		}
+		related = append(related, &relatedNote{note: other, similarity: similarity})
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		return related[i].similarity > related[j].similarity
+	})
+
+	if len(related) > maxRelatedNotes {
+		related = related[:maxRelatedNotes]
+	}
+
+	return related, nil
+}
+
+// listGroupIDs returns the IDs of every group the account is a member of.
+func (srv *recommendationsAPI) listGroupIDs(ctx context.Context, accountID string) ([]string, error) {
+	groupIDs := []string{}
+	for offset := int32(0); ; offset += relatedGroupsPageSize {
+		groups, err := srv.groups.ListGroupsInternal(ctx, &models.ManyGroupsFilter{AccountID: accountID}, &models.ListOptions{Limit: relatedGroupsPageSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			groupIDs = append(groupIDs, group.ID)
+		}
+		if len(groups) < relatedGroupsPageSize {
+			return groupIDs, nil
+		}
+	}
+}
+
 func (srv *recommendationsAPI) authenticate(ctx context.Context) (*auth.Token, error) {
 	token, err := srv.auth.TokenFromContext(ctx)
 	if err != nil {
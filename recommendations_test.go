@@ -0,0 +1,62 @@
+package main
+
+import (
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestRecommendationsSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	edouard := newTestAccount(t, tu)
+	maxime := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, edouard, maxime)
+	strangerGroup := newTestGroup(t, tu, stranger)
+
+	paragraph := func(text string) []*notesv1.Block {
+		return []*notesv1.Block{{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: text}}}
+	}
+	// The keywords job fails without google's credentials but the embedding
+	// is computed beforehand, so its error is ignored.
+	newEmbeddedNote := func(t *testing.T, group *testGroup, author *testAccount, text string) *testNote {
+		note := newTestNote(t, tu, group, author, paragraph(text))
+		_ = tu.notes.(*notesAPI).UpdateKeywordsByNoteId(note.ID, group.ID, author.ID)
+		return note
+	}
+
+	photosynthesis := newEmbeddedNote(t, group, edouard, "La photosynthèse permet aux plantes de produire du glucose grâce à la lumière et à la chlorophylle.")
+	chlorophyll := newEmbeddedNote(t, group, maxime, "La chlorophylle absorbe la lumière, les plantes produisent ensuite du glucose.")
+	newEmbeddedNote(t, group, maxime, "Napoléon Bonaparte fut sacré empereur en 1804 à Notre-Dame.")
+	newEmbeddedNote(t, strangerGroup, stranger, "La chlorophylle des plantes absorbe la lumière pour produire du glucose.")
+
+	t.Run("widgets-contain-related-notes-of-caller-groups", func(t *testing.T) {
+		res, err := tu.recommendations.GenerateWidgets(edouard.Context, &notesv1.GenerateWidgetsRequest{
+			GroupId: group.ID,
+			NoteId:  photosynthesis.ID,
+		})
+		require.NoError(t, err)
+
+		related := []*notesv1.RelatedNoteWidget{}
+		for _, widget := range res.Widgets {
+			if w := widget.GetRelatedNoteWidget(); w != nil {
+				related = append(related, w)
+			}
+		}
+		require.Len(t, related, 1)
+		require.Equal(t, chlorophyll.ID, related[0].NoteId)
+		require.Equal(t, group.ID, related[0].GroupId)
+		require.Greater(t, related[0].Similarity, float32(0.3))
+	})
+
+	t.Run("stranger-cannot-generate-widgets", func(t *testing.T) {
+		res, err := tu.recommendations.GenerateWidgets(stranger.Context, &notesv1.GenerateWidgetsRequest{
+			GroupId: group.ID,
+			NoteId:  photosynthesis.ID,
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Nil(t, res)
+	})
+}
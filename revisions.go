@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"notes-service/models"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// noteWriteError converts the error of a write on the note. A write made at an
+// outdated revision is aborted with the current note in the status details so
+// that the client can merge its changes and retry.
+func (srv *notesAPI) noteWriteError(ctx context.Context, filter *models.OneNoteFilter, accountID string, err error) error {
+	if !errors.Is(err, models.ErrConflict) {
+		return statusFromModelError(err)
+	}
+
+	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: filter.GroupID, NoteID: filter.NoteID}, accountID)
+	if err != nil {
+		return statusFromModelError(err)
+	}
+
+	st, err := status.New(codes.Aborted, "the note was modified since the expected revision").WithDetails(modelsNoteToProtobufNote(note))
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to attach note to conflict status", zap.Error(err))
+		return statusFromModelError(models.ErrConflict)
+	}
+
+	return st.Err()
+}
+
+// blockWriteError converts the error of a write on the block. A write made at
+// an outdated revision is aborted with the current block in the status details.
+func (srv *notesAPI) blockWriteError(ctx context.Context, filter *models.OneBlockFilter, accountID string, err error) error {
+	if !errors.Is(err, models.ErrConflict) {
+		return statusFromModelError(err)
+	}
+
+	block, err := srv.notes.GetBlock(ctx, &models.OneBlockFilter{GroupID: filter.GroupID, NoteID: filter.NoteID, BlockID: filter.BlockID}, accountID)
+	if err != nil {
+		return statusFromModelError(err)
+	}
+	if block == nil {
+		return status.Error(codes.NotFound, "not found")
+	}
+
+	st, err := status.New(codes.Aborted, "the block was modified since the expected revision").WithDetails(modelsBlockToProtobufBlock(block))
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to attach block to conflict status", zap.Error(err))
+		return statusFromModelError(models.ErrConflict)
+	}
+
+	return st.Err()
+}
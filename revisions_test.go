@@ -0,0 +1,229 @@
+package main
+
+import (
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestRevisionsSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	maxime := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, maxime)
+
+	getNote := func(t *testing.T, note *testNote) *notesv1.Note {
+		res, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		return res.Note
+	}
+
+	t.Run("mutations-increment-revisions", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+		created := getNote(t, note)
+		require.Equal(t, int64(1), created.Revision)
+		require.Equal(t, int64(1), created.Blocks[0].Revision)
+
+		inserted, err := tu.notes.InsertBlock(maxime.Context, &notesv1.InsertBlockRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			Block:            &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Mitose"}},
+			ExpectedRevision: created.Revision,
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), inserted.Block.Revision)
+
+		updated, err := tu.notes.UpdateBlock(maxime.Context, &notesv1.UpdateBlockRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			BlockId:          inserted.Block.Id,
+			Block:            &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Méiose"}},
+			ExpectedRevision: inserted.Block.Revision,
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), updated.Block.Revision)
+		require.Equal(t, int64(3), getNote(t, note).Revision)
+	})
+
+	t.Run("update-note-at-stale-revision-is-aborted-with-current-note", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+		stale := getNote(t, note)
+
+		res, err := tu.notes.UpdateNote(maxime.Context, &notesv1.UpdateNoteRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			Note:             &notesv1.Note{Title: "Cellule"},
+			UpdateMask:       &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			ExpectedRevision: stale.Revision,
+		})
+		require.NoError(t, err)
+		require.Equal(t, stale.Revision+1, res.Note.Revision)
+
+		_, err = tu.notes.UpdateNote(maxime.Context, &notesv1.UpdateNoteRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			Note:             &notesv1.Note{Title: "Noyau"},
+			UpdateMask:       &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			ExpectedRevision: stale.Revision,
+		})
+		requireErrorHasGRPCCode(t, codes.Aborted, err)
+
+		details := status.Convert(err).Details()
+		require.Len(t, details, 1)
+		current, ok := details[0].(*notesv1.Note)
+		require.True(t, ok)
+		require.Equal(t, "Cellule", current.Title)
+		require.Equal(t, res.Note.Revision, current.Revision)
+	})
+
+	t.Run("update-block-at-stale-revision-is-aborted-with-current-block", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+		block := getNote(t, note).Blocks[0]
+
+		_, err := tu.notes.UpdateBlock(maxime.Context, &notesv1.UpdateBlockRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			BlockId:          block.Id,
+			Block:            &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Ribosome"}},
+			ExpectedRevision: block.Revision,
+		})
+		require.NoError(t, err)
+
+		_, err = tu.notes.DeleteBlock(maxime.Context, &notesv1.DeleteBlockRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			BlockId:          block.Id,
+			ExpectedRevision: block.Revision,
+		})
+		requireErrorHasGRPCCode(t, codes.Aborted, err)
+
+		details := status.Convert(err).Details()
+		require.Len(t, details, 1)
+		current, ok := details[0].(*notesv1.Block)
+		require.True(t, ok)
+		require.Equal(t, "Ribosome", current.GetParagraph())
+		require.Len(t, getNote(t, note).Blocks, 1)
+	})
+
+	t.Run("insert-block-at-stale-revision-is-aborted", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+		stale := getNote(t, note)
+		note.InsertBlock(t, tu, &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "ADN"}}, 1)
+
+		_, err := tu.notes.InsertBlock(maxime.Context, &notesv1.InsertBlockRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			Block:            &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "ARN"}},
+			ExpectedRevision: stale.Revision,
+		})
+		requireErrorHasGRPCCode(t, codes.Aborted, err)
+		require.Len(t, getNote(t, note).Blocks, 2)
+	})
+
+	t.Run("update-note-increments-revision-of-changed-blocks-only", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Prophase"}},
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Métaphase"}},
+		})
+		blocks := getNote(t, note).Blocks
+
+		res, err := tu.notes.UpdateNote(maxime.Context, &notesv1.UpdateNoteRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			Note: &notesv1.Note{Blocks: []*notesv1.Block{
+				blocks[0],
+				{Id: blocks[1].Id, Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Anaphase"}},
+			}},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"blocks"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, blocks[0].Revision, res.Note.Blocks[0].Revision)
+		require.Equal(t, blocks[1].Revision+1, res.Note.Blocks[1].Revision)
+	})
+
+	t.Run("comments-increment-block-revision", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+		block := getNote(t, note).Blocks[0]
+
+		created, err := tu.notes.CreateBlockComment(maxime.Context, &notesv1.CreateBlockCommentRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			BlockId:          block.Id,
+			Comment:          &notesv1.Block_Comment{Content: "Et la cytocinèse ?"},
+			ExpectedRevision: block.Revision,
+		})
+		require.NoError(t, err)
+		require.Equal(t, block.Revision+1, getNote(t, note).Blocks[0].Revision)
+
+		_, err = tu.notes.DeleteBlockComment(maxime.Context, &notesv1.DeleteBlockCommentRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			BlockId:          block.Id,
+			CommentId:        created.Comment.Id,
+			ExpectedRevision: block.Revision,
+		})
+		requireErrorHasGRPCCode(t, codes.Aborted, err)
+
+		_, err = tu.notes.DeleteBlockComment(maxime.Context, &notesv1.DeleteBlockCommentRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			BlockId:          block.Id,
+			CommentId:        created.Comment.Id,
+			ExpectedRevision: block.Revision + 1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, block.Revision+2, getNote(t, note).Blocks[0].Revision)
+	})
+
+	t.Run("move-note-at-stale-revision-is-aborted", func(t *testing.T) {
+		destination := newTestGroup(t, tu, maxime)
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+		stale := getNote(t, note)
+		note.InsertBlock(t, tu, &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Chromosome"}}, 0)
+
+		_, err := tu.notes.MoveNote(maxime.Context, &notesv1.MoveNoteRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			DestinationGroupId: destination.ID,
+			ExpectedRevision:   stale.Revision,
+		})
+		requireErrorHasGRPCCode(t, codes.Aborted, err)
+		getNote(t, note)
+	})
+
+	t.Run("grant-edit-permission-at-stale-revision-is-aborted", func(t *testing.T) {
+		edouard := newTestAccount(t, tu)
+		sharedGroup := newTestGroup(t, tu, maxime, edouard)
+		note := newTestNote(t, tu, sharedGroup, maxime, []*notesv1.Block{})
+		res, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: sharedGroup.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		stale := res.Note
+		note.InsertBlock(t, tu, &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Centromère"}}, 0)
+
+		_, err = tu.notes.ChangeNoteEditPermission(maxime.Context, &notesv1.ChangeNoteEditPermissionRequest{
+			GroupId:            sharedGroup.ID,
+			NoteId:             note.ID,
+			RecipientAccountId: edouard.ID,
+			Type:               notesv1.ChangeNoteEditPermissionRequest_ACTION_GRANT,
+			ExpectedRevision:   stale.Revision,
+		})
+		requireErrorHasGRPCCode(t, codes.Aborted, err)
+	})
+
+	t.Run("delete-note-at-stale-revision-is-aborted", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+		stale := getNote(t, note)
+		note.InsertBlock(t, tu, &notesv1.Block{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "Enzyme"}}, 0)
+
+		_, err := tu.notes.DeleteNote(maxime.Context, &notesv1.DeleteNoteRequest{
+			GroupId:          group.ID,
+			NoteId:           note.ID,
+			ExpectedRevision: stale.Revision,
+		})
+		requireErrorHasGRPCCode(t, codes.Aborted, err)
+		getNote(t, note)
+	})
+}
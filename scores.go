@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"notes-service/validators"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (srv *groupsAPI) GetLeaderboard(ctx context.Context, req *notesv1.GetLeaderboardRequest) (*notesv1.GetLeaderboardResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateGetLeaderboardRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	entries, err := srv.scores.GetLeaderboardInternal(ctx, &models.ManyScoreEventsFilter{
+		GroupID: req.GroupId,
+		Season:  group.ScoreSeason,
+		Since:   leaderboardWindowStart(req.Window, time.Now()),
+	})
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	return &notesv1.GetLeaderboardResponse{
+		Season:  int32(group.ScoreSeason),
+		Entries: modelsLeaderboardToProtobufLeaderboard(rankLeaderboard(group, entries)),
+	}, nil
+}
+
+func (srv *groupsAPI) ListScoreEvents(ctx context.Context, req *notesv1.ListScoreEventsRequest) (*notesv1.ListScoreEventsResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateListScoreEventsRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	// Members can audit their own history, admins can audit everyone's.
+	member := group.FindMember(token.AccountID)
+	if req.AccountId != token.AccountID && (member == nil || !member.IsAdmin) {
+		return nil, status.Error(codes.PermissionDenied, "only admins can list the score events of other members")
+	}
+
+	events, err := srv.scores.ListScoreEventsInternal(ctx, &models.ManyScoreEventsFilter{
+		GroupID:   req.GroupId,
+		Season:    group.ScoreSeason,
+		AccountID: req.AccountId,
+	}, listOptionsFromLimitOffset(req.Limit, req.Offset))
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	return &notesv1.ListScoreEventsResponse{Events: modelsScoreEventsToProtobufScoreEvents(events)}, nil
+}
+
+func (srv *groupsAPI) ResetGroupScores(ctx context.Context, req *notesv1.ResetGroupScoresRequest) (*notesv1.ResetGroupScoresResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateResetGroupScoresRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	member := group.FindMember(token.AccountID)
+	if member == nil || !member.IsAdmin {
+		return nil, status.Error(codes.PermissionDenied, "only admins can reset the scores of the group")
+	}
+
+	group, err = srv.groups.ResetGroupScores(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	return &notesv1.ResetGroupScoresResponse{Season: int32(group.ScoreSeason)}, nil
+}
+
+// leaderboardWindowStart returns the date from which score events are taken
+// into account, nil meaning the whole season.
+func leaderboardWindowStart(window notesv1.LeaderboardWindow, now time.Time) *time.Time {
+	var since time.Time
+
+	switch window {
+	case notesv1.LeaderboardWindow_LEADERBOARD_WINDOW_WEEK:
+		since = now.AddDate(0, 0, -7)
+	case notesv1.LeaderboardWindow_LEADERBOARD_WINDOW_MONTH:
+		since = now.AddDate(0, -1, 0)
+	default:
+		return nil
+	}
+
+	return &since
+}
+
+type rankedLeaderboardEntry struct {
+	models.LeaderboardEntry
+	Rank int
+}
+
+// rankLeaderboard adds the members who did not track any score yet and sorts
+// the entries by score then accuracy. Members with the same score and accuracy
+// share the same rank.
+func rankLeaderboard(group *models.Group, entries []*models.LeaderboardEntry) []*rankedLeaderboardEntry {
+	ranked := make([]*rankedLeaderboardEntry, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+
+	for _, entry := range entries {
+		// Ignore the events of accounts which left the group.
+		if group.FindMember(entry.AccountID) == nil {
+			continue
+		}
+		seen[entry.AccountID] = true
+		ranked = append(ranked, &rankedLeaderboardEntry{LeaderboardEntry: *entry})
+	}
+
+	if group.Members != nil {
+		for _, member := range *group.Members {
+			if !seen[member.AccountID] {
+				ranked = append(ranked, &rankedLeaderboardEntry{LeaderboardEntry: models.LeaderboardEntry{AccountID: member.AccountID}})
+			}
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		if ranked[i].Accuracy() != ranked[j].Accuracy() {
+			return ranked[i].Accuracy() > ranked[j].Accuracy()
+		}
+		return ranked[i].AccountID < ranked[j].AccountID
+	})
+
+	for i := range ranked {
+		if i > 0 && ranked[i].Score == ranked[i-1].Score && ranked[i].Accuracy() == ranked[i-1].Accuracy() {
+			ranked[i].Rank = ranked[i-1].Rank
+		} else {
+			ranked[i].Rank = i + 1
+		}
+	}
+
+	return ranked
+}
+
+func modelsLeaderboardToProtobufLeaderboard(entries []*rankedLeaderboardEntry) []*notesv1.LeaderboardEntry {
+	protoEntries := make([]*notesv1.LeaderboardEntry, len(entries))
+
+	for i, entry := range entries {
+		protoEntries[i] = &notesv1.LeaderboardEntry{
+			AccountId: entry.AccountID,
+			Rank:      int32(entry.Rank),
+			Score:     int32(entry.Score),
+			Responses: int32(entry.Responses),
+			Accuracy:  entry.Accuracy(),
+		}
+	}
+
+	return protoEntries
+}
+
+func modelsScoreEventsToProtobufScoreEvents(events []*models.ScoreEvent) []*notesv1.ScoreEvent {
+	protoEvents := make([]*notesv1.ScoreEvent, len(events))
+
+	for i, event := range events {
+		protoEvents[i] = &notesv1.ScoreEvent{
+			Id:        event.ID,
+			GroupId:   event.GroupID,
+			AccountId: event.AccountID,
+			Season:    int32(event.Season),
+			Score:     int32(event.Score),
+			Responses: int32(event.Responses),
+			CreatedAt: timestamppb.New(event.CreatedAt),
+		}
+	}
+
+	return protoEvents
+}
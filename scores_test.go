@@ -0,0 +1,121 @@
+package main
+
+import (
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestScoresSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	admin := newTestAccount(t, tu)
+	alice := newTestAccount(t, tu)
+	bob := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, admin, alice, bob)
+
+	trackScore := func(t *testing.T, account *testAccount, score int32, responses int32) {
+		res, err := tu.groups.TrackScore(account.Context, &notesv1.TrackScoreRequest{
+			GroupId:   group.ID,
+			Score:     score,
+			Responses: responses,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+	}
+
+	t.Run("leaderboard-ranks-members-by-score", func(t *testing.T) {
+		trackScore(t, alice, 4, 5)
+		trackScore(t, bob, 5, 5)
+		trackScore(t, alice, 3, 5)
+
+		res, err := tu.groups.GetLeaderboard(admin.Context, &notesv1.GetLeaderboardRequest{
+			GroupId: group.ID,
+			Window:  notesv1.LeaderboardWindow_LEADERBOARD_WINDOW_ALL_TIME,
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Entries, 3)
+		require.Equal(t, alice.ID, res.Entries[0].AccountId)
+		require.Equal(t, int32(1), res.Entries[0].Rank)
+		require.Equal(t, int32(7), res.Entries[0].Score)
+		require.Equal(t, int32(10), res.Entries[0].Responses)
+		require.InDelta(t, 70, res.Entries[0].Accuracy, 0.01)
+		require.Equal(t, bob.ID, res.Entries[1].AccountId)
+		require.Equal(t, int32(2), res.Entries[1].Rank)
+		require.Equal(t, admin.ID, res.Entries[2].AccountId)
+		require.Zero(t, res.Entries[2].Score)
+	})
+
+	t.Run("leaderboard-week-window-includes-recent-events", func(t *testing.T) {
+		res, err := tu.groups.GetLeaderboard(bob.Context, &notesv1.GetLeaderboardRequest{
+			GroupId: group.ID,
+			Window:  notesv1.LeaderboardWindow_LEADERBOARD_WINDOW_WEEK,
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(7), res.Entries[0].Score)
+	})
+
+	t.Run("stranger-cannot-get-leaderboard", func(t *testing.T) {
+		res, err := tu.groups.GetLeaderboard(stranger.Context, &notesv1.GetLeaderboardRequest{
+			GroupId: group.ID,
+			Window:  notesv1.LeaderboardWindow_LEADERBOARD_WINDOW_ALL_TIME,
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("member-can-list-own-score-events", func(t *testing.T) {
+		res, err := tu.groups.ListScoreEvents(alice.Context, &notesv1.ListScoreEventsRequest{
+			GroupId:   group.ID,
+			AccountId: alice.ID,
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Events, 2)
+	})
+
+	t.Run("member-cannot-list-score-events-of-others", func(t *testing.T) {
+		res, err := tu.groups.ListScoreEvents(alice.Context, &notesv1.ListScoreEventsRequest{
+			GroupId:   group.ID,
+			AccountId: bob.ID,
+		})
+		requireErrorHasGRPCCode(t, codes.PermissionDenied, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("member-cannot-reset-scores", func(t *testing.T) {
+		res, err := tu.groups.ResetGroupScores(alice.Context, &notesv1.ResetGroupScoresRequest{GroupId: group.ID})
+		requireErrorHasGRPCCode(t, codes.PermissionDenied, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("stranger-cannot-reset-scores", func(t *testing.T) {
+		res, err := tu.groups.ResetGroupScores(stranger.Context, &notesv1.ResetGroupScoresRequest{GroupId: group.ID})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("admin-can-reset-scores", func(t *testing.T) {
+		res, err := tu.groups.ResetGroupScores(admin.Context, &notesv1.ResetGroupScoresRequest{GroupId: group.ID})
+		require.NoError(t, err)
+		require.Equal(t, int32(1), res.Season)
+
+		leaderboard, err := tu.groups.GetLeaderboard(admin.Context, &notesv1.GetLeaderboardRequest{
+			GroupId: group.ID,
+			Window:  notesv1.LeaderboardWindow_LEADERBOARD_WINDOW_ALL_TIME,
+		})
+		require.NoError(t, err)
+		for _, entry := range leaderboard.Entries {
+			require.Zero(t, entry.Score)
+			require.Equal(t, int32(1), entry.Rank)
+		}
+
+		// Ensure member counters are reset too.
+		g, err := tu.groupsRepository.GetGroup(admin.Context, &models.OneGroupFilter{GroupID: group.ID}, admin.ID)
+		require.NoError(t, err)
+		require.Zero(t, g.FindMember(alice.ID).Score)
+		require.Zero(t, g.FindMember(alice.ID).QuizTotal)
+	})
+}
@@ -15,17 +15,22 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"notes-service/models"
 	notesv1 "notes-service/protorepo/noted/notes/v1"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"notes-service/models/mongo"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
@@ -35,26 +40,40 @@ type server struct {
 	slogger *zap.SugaredLogger
 
 	authService       auth.Service
-	backgroundService background.Service
+	backgroundService *stoppableBackgroundService
 	mailingService    mailing.Service
 	languageService   language.Service // NOTE: Could put directly service typed as NaturalAPIService, remove Init() from interface and just put it in NaturalAPIService
 	accountsClient    *communication.AccountsServiceClient
 
+	embeddingProvider language.EmbeddingProvider
+	languageUsage     *language.Usage
+	accountsLimiter   *language.RateLimiter
+	groupsLimiter     *language.RateLimiter
+
 	mongoDB *mongo.Database
 
 	notesRepository      models.NotesRepository
 	groupsRepository     models.GroupsRepository
 	activitiesRepository models.ActivitiesRepository
+	scoresRepository     models.ScoresRepository
+	linksRepository      models.LinksRepository
+	templatesRepository  models.TemplatesRepository
 
 	notesAPI           notesv1.NotesAPIServer
 	groupsAPI          notesv1.GroupsAPIServer
 	recommendationsAPI notesv1.RecommendationsAPIServer
 
-	grpcServer *grpc.Server
+	grpcServer     *grpc.Server
+	healthServer   *health.Server
+	metricsServer  *http.Server
+	gatewayServer  *http.Server
+	gatewayCancel  context.CancelFunc
+	tracerProvider *sdktrace.TracerProvider
 }
 
 func (s *server) Init(opt ...grpc.ServerOption) {
 	s.initLogger()
+	s.initTracing()
 	s.initAuthService()
 	s.initRepositories()
 	s.initLanguageService()
@@ -64,55 +83,85 @@ func (s *server) Init(opt ...grpc.ServerOption) {
 	s.initGroupsAPI()
 	s.initNotesAPI()
 	s.initRecommendationsAPI()
+	s.initHealthServer()
 	s.initgrpcServer(opt...)
 
 	s.validateOldBackgroundService()
+	s.logLanguageUsage()
 }
 
+// Run serves the APIs until the process receives SIGINT or SIGTERM.
 func (s *server) Run() {
 	lis, err := net.Listen("tcp", fmt.Sprint(":", *port))
 	must(err, "failed to create tcp listener")
 	reflection.Register(s.grpcServer)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go s.watchHealth(ctx, *healthCheckInterval)
+	s.serveMetrics()
+	s.serveGateway()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.grpcServer.Serve(lis)
+	}()
 	s.slogger.Infof("service running on : %d", *port)
-	err = s.grpcServer.Serve(lis)
-	must(err, "failed to run grpc server")
-}
 
-func (s *server) Close() {
-	s.logger.Info("shutdown")
-	s.mongoDB.Disconnect(context.Background())
-	s.logger.Sync()
+	select {
+	case err = <-served:
+		must(err, "failed to run grpc server")
+	case <-ctx.Done():
+		s.logger.Info("received shutdown signal")
+	}
 }
 
-func (s *server) LoggerUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	start := time.Now()
-	res, err := handler(ctx, req)
-	end := time.Now()
-
-	method := info.FullMethod[strings.LastIndexByte(info.FullMethod, '/')+1:]
+// Budgets of the steps of the shutdown following the drain of the rpcs, they
+// don't depend on how long the drain took.
+const (
+	backgroundFlushTimeout = 20 * time.Second
+	mongoDisconnectTimeout = 5 * time.Second
+	tracingFlushTimeout    = 5 * time.Second
+)
 
-	if err != nil {
-		var displayErr = err
-		st, ok := status.FromError(err)
-		if ok {
-			displayErr = errors.New(st.Message())
-		}
-		s.logger.Warn("failed rpc",
-			zap.String("code", status.Code(err).String()),
-			zap.String("method", method),
-			zap.Duration("duration", end.Sub(start)),
-			zap.Error(displayErr),
-		)
-		return res, err
+// Close stops the server within the shutdown timeout: in-flight RPCs are given
+// a chance to complete, then the background processes are flushed before mongo
+// is disconnected.
+func (s *server) Close() {
+	s.logger.Info("shutdown")
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	// Tell the orchestrator to stop sending traffic.
+	s.healthServer.Shutdown()
+	// The gateway is a client of the grpc server, its requests complete first.
+	s.closeGateway(ctx)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.logger.Warn("shutdown timeout exceeded, cancelling in-flight rpcs")
+		s.grpcServer.Stop()
 	}
 
-	s.logger.Info("rpc",
-		zap.String("code", status.Code(err).String()),
-		zap.String("method", method),
-		zap.Duration("duration", end.Sub(start)),
-	)
+	s.closeMetrics(ctx)
+	withTimeout(backgroundFlushTimeout, s.backgroundService.Stop)
+	withTimeout(mongoDisconnectTimeout, s.mongoDB.Disconnect)
+	withTimeout(tracingFlushTimeout, s.closeTracing)
+	s.logger.Sync()
+}
 
-	return res, nil
+// withTimeout runs step with a context of its own, done after timeout.
+func withTimeout(timeout time.Duration, step func(ctx context.Context)) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	step(ctx)
 }
 
 func (s *server) initLogger() {
@@ -127,7 +176,13 @@ func (s *server) initLogger() {
 }
 
 func (s *server) initLanguageService() {
-	s.languageService = &language.NotedLanguageService{}
+	s.languageUsage = language.NewUsage()
+	s.embeddingProvider = language.NewEmbeddingProvider(s.languageUsage, *languageTimeout)
+	s.accountsLimiter = language.NewRateLimiter(*languageAccountRateLimit, time.Hour)
+	s.groupsLimiter = language.NewRateLimiter(*languageGroupRateLimit, time.Hour)
+	s.languageService = language.NewTracedLanguageService(
+		language.NewCachedLanguageService(&language.NotedLanguageService{}, s.languageUsage, *languageCacheTTL, *languageTimeout),
+	)
 	err := s.languageService.Init(s.logger)
 	must(err, "unable to instantiate language service")
 }
@@ -141,7 +196,7 @@ func (s *server) initAuthService() {
 }
 
 func (s *server) initBackgroundService() {
-	s.backgroundService = background.NewService(s.logger)
+	s.backgroundService = newStoppableBackgroundService(background.NewService(s.logger), s.logger)
 }
 
 func (s *server) initMailingService() {
@@ -166,6 +221,8 @@ func (s *server) initGroupsAPI() {
 		notes:          s.notesRepository,
 		groups:         s.groupsRepository,
 		activities:     s.activitiesRepository,
+		scores:         s.scoresRepository,
+		links:          s.linksRepository,
 		background:     s.backgroundService,
 		mailing:        s.mailingService,
 		accountsClient: s.accountsClient,
@@ -179,8 +236,16 @@ func (s *server) initNotesAPI() {
 		notes:      s.notesRepository,
 		groups:     s.groupsRepository,
 		activities: s.activitiesRepository,
+		links:      s.linksRepository,
+		templates:  s.templatesRepository,
 		language:   s.languageService,
 		background: s.backgroundService,
+
+		accountsClient:  s.accountsClient,
+		embeddings:      s.embeddingProvider,
+		languageUsage:   s.languageUsage,
+		accountsLimiter: s.accountsLimiter,
+		groupsLimiter:   s.groupsLimiter,
 	}
 }
 
@@ -189,6 +254,7 @@ func (s *server) initRecommendationsAPI() {
 		auth:     s.authService,
 		logger:   s.logger,
 		notes:    s.notesRepository,
+		groups:   s.groupsRepository,
 		language: s.languageService,
 	}
 }
@@ -198,6 +264,7 @@ func (s *server) initgrpcServer(opt ...grpc.ServerOption) {
 	notesv1.RegisterNotesAPIServer(s.grpcServer, s.notesAPI)
 	notesv1.RegisterGroupsAPIServer(s.grpcServer, s.groupsAPI)
 	notesv1.RegisterRecommendationsAPIServer(s.grpcServer, s.recommendationsAPI)
+	healthpb.RegisterHealthServer(s.grpcServer, s.healthServer)
 }
 
 func (s *server) initRepositories() {
@@ -207,6 +274,12 @@ func (s *server) initRepositories() {
 	s.notesRepository = mongo.NewNotesRepository(s.mongoDB.DB, s.logger)
 	s.groupsRepository = mongo.NewGroupsRepository(s.mongoDB.DB, s.logger)
 	s.activitiesRepository = mongo.NewActivitiesRepository(s.mongoDB.DB, s.logger)
+	s.scoresRepository = mongo.NewScoresRepository(s.mongoDB.DB, s.logger)
+	s.linksRepository = mongo.NewLinksRepository(s.mongoDB.DB, s.logger)
+	s.templatesRepository = mongo.NewTemplatesRepository(s.mongoDB.DB, s.logger)
+
+	err = s.templatesRepository.StoreGlobalTemplatesInternal(context.Background(), globalTemplates())
+	must(err, "could not store global templates")
 }
 
 func (s *server) validateOldBackgroundService() {
@@ -246,6 +319,29 @@ func (s *server) validateQuizsExpiration() {
 	}
 }
 
+// logLanguageUsage logs the language calls counters every hour.
+func (s *server) logLanguageUsage() {
+	err := s.backgroundService.AddProcess(&background.Process{
+		Identifier: "language-usage",
+		CallBackFct: func() error {
+			for method, stats := range s.languageUsage.Snapshot() {
+				s.logger.Info("language usage",
+					zap.String("method", method),
+					zap.Uint64("calls", stats.Calls),
+					zap.Uint64("errors", stats.Errors),
+					zap.Uint64("cache_hits", stats.CacheHits),
+					zap.Uint64("rate_limited", stats.RateLimited),
+				)
+			}
+			return nil
+		},
+		CancelProcessOnSameIdentifier: true,
+		RepeatProcess:                 true,
+		SecondsToDebounce:             uint32(time.Hour / time.Second),
+	})
+	must(err, "couldn't schedule language usage logging")
+}
+
 func must(err error, msg string) {
 	if err != nil {
 		panic(fmt.Errorf("%s: %v", msg, err))
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"notes-service/validators"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (srv *notesAPI) CreateTemplate(ctx context.Context, req *notesv1.CreateTemplateRequest) (*notesv1.CreateTemplateResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateCreateTemplateRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	// The templates of the group are offered to every member.
+	member := group.FindMember(token.AccountID)
+	if req.Scope == notesv1.TemplateScope_TEMPLATE_SCOPE_GROUP && (member == nil || !member.IsAdmin) {
+		return nil, status.Error(codes.PermissionDenied, "only admins can create the templates of the group")
+	}
+
+	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	blocks := []models.NoteBlock{}
+	if note.Blocks != nil {
+		blocks = *note.Blocks
+	}
+
+	template, err := srv.templates.CreateTemplate(ctx, &models.CreateTemplatePayload{
+		Name:        req.Name,
+		Description: req.Description,
+		Scope:       req.Scope.String(),
+		GroupID:     req.GroupId,
+		Lang:        note.Lang,
+		Blocks:      blocks,
+	}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	return &notesv1.CreateTemplateResponse{Template: modelsTemplateToProtobufTemplate(template)}, nil
+}
+
+func (srv *notesAPI) ListTemplates(ctx context.Context, req *notesv1.ListTemplatesRequest) (*notesv1.ListTemplatesResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateListTemplatesRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	templates, err := srv.templates.ListTemplatesInternal(ctx,
+		&models.ManyTemplatesFilter{GroupID: req.GroupId, AccountID: token.AccountID},
+		listOptionsFromLimitOffset(req.Limit, req.Offset))
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	protobufTemplates := make([]*notesv1.Template, len(templates))
+	for i, template := range templates {
+		protobufTemplates[i] = modelsTemplateToProtobufTemplate(template)
+	}
+
+	return &notesv1.ListTemplatesResponse{Templates: protobufTemplates}, nil
+}
+
+func (srv *notesAPI) DeleteTemplate(ctx context.Context, req *notesv1.DeleteTemplateRequest) (*notesv1.DeleteTemplateResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateDeleteTemplateRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	template, err := srv.templates.GetTemplateInternal(ctx, &models.OneTemplateFilter{TemplateID: req.TemplateId})
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	// The templates of a group can also be deleted by its admins.
+	if template.AuthorAccountID != token.AccountID {
+		if template.Scope != models.TemplateScopeGroup {
+			return nil, status.Error(codes.NotFound, "not found")
+		}
+		group, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: template.GroupID}, token.AccountID)
+		if err != nil {
+			return nil, statusFromModelError(err)
+		}
+		member := group.FindMember(token.AccountID)
+		if member == nil || !member.IsAdmin {
+			return nil, status.Error(codes.NotFound, "not found")
+		}
+	}
+
+	err = srv.templates.DeleteTemplateInternal(ctx, &models.OneTemplateFilter{TemplateID: template.ID})
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	return &notesv1.DeleteTemplateResponse{}, nil
+}
+
+func (srv *notesAPI) CreateNoteFromTemplate(ctx context.Context, req *notesv1.CreateNoteFromTemplateRequest) (*notesv1.CreateNoteFromTemplateResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateCreateNoteFromTemplateRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	template, err := srv.templates.GetTemplateInternal(ctx, &models.OneTemplateFilter{TemplateID: req.TemplateId})
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+	if !template.IsAvailable(req.GroupId, token.AccountID) {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+
+	lang := req.Lang
+	if lang == "" {
+		lang = template.Lang
+	}
+
+	// The repository gives new IDs to the copied blocks.
+	note, err := srv.createNote(ctx, &models.CreateNotePayload{
+		GroupID:         req.GroupId,
+		Title:           req.Title,
+		AuthorAccountID: token.AccountID,
+		Lang:            lang,
+		Blocks:          template.Blocks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.CreateNoteFromTemplateResponse{Note: modelsNoteToProtobufNote(note)}, nil
+}
+
+func modelsTemplateToProtobufTemplate(template *models.Template) *notesv1.Template {
+	protobufTemplate := &notesv1.Template{
+		Id:              template.ID,
+		Name:            template.Name,
+		Description:     template.Description,
+		Scope:           notesv1.TemplateScope(notesv1.TemplateScope_value[template.Scope]),
+		GroupId:         template.GroupID,
+		AuthorAccountId: template.AuthorAccountID,
+		Lang:            template.Lang,
+		CreatedAt:       timestamppb.New(template.CreatedAt),
+	}
+
+	for i := range template.Blocks {
+		protobufTemplate.Blocks = append(protobufTemplate.Blocks, modelsBlockToProtobufBlock(&template.Blocks[i]))
+	}
+
+	return protobufTemplate
+}
+
+// globalTemplates returns the templates available in every group.
+func globalTemplates() []*models.Template {
+	heading := func(level int, text string) models.NoteBlock {
+		types := []string{"TYPE_HEADING_1", "TYPE_HEADING_2", "TYPE_HEADING_3"}
+		return models.NoteBlock{Type: types[level-1], Heading: &text}
+	}
+	paragraph := func(text string) models.NoteBlock {
+		return models.NoteBlock{Type: "TYPE_PARAGRAPH", Paragraph: &text}
+	}
+	bulletPoint := func(text string) models.NoteBlock {
+		return models.NoteBlock{Type: "TYPE_BULLET_POINT", BulletPoint: &text}
+	}
+	numberPoint := func(text string) models.NoteBlock {
+		return models.NoteBlock{Type: "TYPE_NUMBER_POINT", NumberPoint: &text}
+	}
+	checklistItem := func(text string) models.NoteBlock {
+		return models.NoteBlock{Type: "TYPE_CHECKLIST_ITEM", ChecklistItem: &models.NoteBlockChecklistItem{Text: text}}
+	}
+	callout := func(tone string, text string) models.NoteBlock {
+		return models.NoteBlock{Type: "TYPE_CALLOUT", Callout: &models.NoteBlockCallout{Tone: tone, Text: text}}
+	}
+	math := func(text string) models.NoteBlock {
+		return models.NoteBlock{Type: "TYPE_MATH", Math: &text}
+	}
+
+	return []*models.Template{
+		{
+			ID:          "global-lab-report",
+			Name:        "Lab report",
+			Description: "Objective, protocol, results and conclusion of an experiment.",
+			Lang:        "en",
+			Blocks: []models.NoteBlock{
+				heading(1, "Objective"),
+				paragraph("What is the experiment trying to show?"),
+				heading(1, "Hypothesis"),
+				paragraph("What do you expect to observe, and why?"),
+				heading(1, "Materials"),
+				bulletPoint("Equipment and products used"),
+				heading(1, "Protocol"),
+				numberPoint("First step of the experiment"),
+				heading(1, "Results"),
+				paragraph("Measurements and observations."),
+				heading(1, "Conclusion"),
+				paragraph("Do the results confirm the hypothesis?"),
+			},
+		},
+		{
+			ID:          "global-lecture-note",
+			Name:        "Lecture note",
+			Description: "Key ideas, definitions and open questions of a lecture.",
+			Lang:        "en",
+			Blocks: []models.NoteBlock{
+				heading(1, "Lecture"),
+				paragraph("Course, teacher and date."),
+				heading(2, "Key ideas"),
+				bulletPoint("Main idea of the lecture"),
+				heading(2, "Definitions"),
+				bulletPoint("Term: definition"),
+				heading(2, "Questions"),
+				checklistItem("Question to ask at the next lecture"),
+				heading(2, "Summary"),
+				paragraph("The lecture in a few sentences."),
+			},
+		},
+		{
+			ID:          "global-exam-revision-sheet",
+			Name:        "Exam revision sheet",
+			Description: "Topics, formulas and common mistakes to review before an exam.",
+			Lang:        "en",
+			Blocks: []models.NoteBlock{
+				heading(1, "Topics to review"),
+				checklistItem("Topic"),
+				heading(1, "Formulas"),
+				math("E = mc^2"),
+				heading(1, "Common mistakes"),
+				callout("TONE_WARNING", "Mistake to avoid"),
+				heading(1, "Practice questions"),
+				numberPoint("Question"),
+			},
+		},
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestTemplatesSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	teacher := newTestAccount(t, tu)
+	student := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, teacher, student)
+	otherGroup := newTestGroup(t, tu, stranger, student)
+
+	labReport := newTestNote(t, tu, group, teacher, []*notesv1.Block{
+		{Type: notesv1.Block_TYPE_HEADING_1, Data: &notesv1.Block_Heading{Heading: "Objective"}},
+		{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "What is the experiment trying to show?"}},
+	})
+
+	createTemplate := func(t *testing.T, account *testAccount, name string, scope notesv1.TemplateScope) *notesv1.Template {
+		res, err := tu.notes.CreateTemplate(account.Context, &notesv1.CreateTemplateRequest{
+			GroupId: group.ID,
+			NoteId:  labReport.ID,
+			Name:    name,
+			Scope:   scope,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		return res.Template
+	}
+
+	listTemplateNames := func(t *testing.T, account *testAccount, group *testGroup) []string {
+		res, err := tu.notes.ListTemplates(account.Context, &notesv1.ListTemplatesRequest{GroupId: group.ID, Limit: 100})
+		require.NoError(t, err)
+		names := []string{}
+		for _, template := range res.Templates {
+			names = append(names, template.Name)
+		}
+		return names
+	}
+
+	groupTemplate := createTemplate(t, teacher, "Chemistry lab report", notesv1.TemplateScope_TEMPLATE_SCOPE_GROUP)
+	accountTemplate := createTemplate(t, teacher, "My lab report", notesv1.TemplateScope_TEMPLATE_SCOPE_ACCOUNT)
+
+	t.Run("template-copies-the-blocks-of-the-note", func(t *testing.T) {
+		require.Equal(t, group.ID, groupTemplate.GroupId)
+		require.Equal(t, teacher.ID, groupTemplate.AuthorAccountId)
+		require.Len(t, groupTemplate.Blocks, 2)
+		require.Equal(t, "Objective", groupTemplate.Blocks[0].GetHeading())
+
+		note, err := tu.notes.GetNote(teacher.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: labReport.ID})
+		require.NoError(t, err)
+		require.NotEqual(t, note.Note.Blocks[0].Id, groupTemplate.Blocks[0].Id)
+	})
+
+	t.Run("template-does-not-copy-checklist-assignees", func(t *testing.T) {
+		checklist := newTestNote(t, tu, group, teacher, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_CHECKLIST_ITEM, Data: &notesv1.Block_ChecklistItem_{
+				ChecklistItem: &notesv1.Block_ChecklistItem{Text: "Wear safety goggles", AssigneeAccountId: student.ID},
+			}},
+		})
+		res, err := tu.notes.CreateTemplate(teacher.Context, &notesv1.CreateTemplateRequest{
+			GroupId: group.ID,
+			NoteId:  checklist.ID,
+			Name:    "Safety checklist",
+			Scope:   notesv1.TemplateScope_TEMPLATE_SCOPE_ACCOUNT,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Wear safety goggles", res.Template.Blocks[0].GetChecklistItem().Text)
+		require.Empty(t, res.Template.Blocks[0].GetChecklistItem().AssigneeAccountId)
+
+		note, err := tu.notes.GetNote(teacher.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: checklist.ID})
+		require.NoError(t, err)
+		require.Equal(t, student.ID, note.Note.Blocks[0].GetChecklistItem().AssigneeAccountId, "the note should keep its assignees")
+	})
+
+	t.Run("member-lists-group-and-global-templates", func(t *testing.T) {
+		names := listTemplateNames(t, student, group)
+		require.Contains(t, names, "Chemistry lab report")
+		require.Contains(t, names, "Lecture note")
+		require.NotContains(t, names, "My lab report")
+
+		names = listTemplateNames(t, teacher, group)
+		require.Contains(t, names, "Chemistry lab report")
+		require.Contains(t, names, "My lab report")
+
+		names = listTemplateNames(t, student, otherGroup)
+		require.NotContains(t, names, "Chemistry lab report")
+		require.Contains(t, names, "Exam revision sheet")
+	})
+
+	t.Run("member-can-create-note-from-template", func(t *testing.T) {
+		res, err := tu.notes.CreateNoteFromTemplate(student.Context, &notesv1.CreateNoteFromTemplateRequest{
+			GroupId:    group.ID,
+			TemplateId: groupTemplate.Id,
+			Title:      "Titration",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Titration", res.Note.Title)
+		require.Equal(t, student.ID, res.Note.AuthorAccountId)
+		require.Len(t, res.Note.Blocks, 2)
+		require.Equal(t, "What is the experiment trying to show?", res.Note.Blocks[1].GetParagraph())
+		require.NotEqual(t, groupTemplate.Blocks[0].Id, res.Note.Blocks[0].Id)
+
+		other, err := tu.notes.CreateNoteFromTemplate(student.Context, &notesv1.CreateNoteFromTemplateRequest{
+			GroupId:    group.ID,
+			TemplateId: groupTemplate.Id,
+			Title:      "Distillation",
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, res.Note.Blocks[0].Id, other.Note.Blocks[0].Id)
+	})
+
+	t.Run("member-can-create-note-from-global-template", func(t *testing.T) {
+		res, err := tu.notes.CreateNoteFromTemplate(stranger.Context, &notesv1.CreateNoteFromTemplateRequest{
+			GroupId:    otherGroup.ID,
+			TemplateId: "global-lecture-note",
+			Title:      "Thermodynamics",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "en", res.Note.Lang)
+		require.NotEmpty(t, res.Note.Blocks)
+	})
+
+	t.Run("cannot-use-group-template-in-another-group", func(t *testing.T) {
+		_, err := tu.notes.CreateNoteFromTemplate(student.Context, &notesv1.CreateNoteFromTemplateRequest{
+			GroupId:    otherGroup.ID,
+			TemplateId: groupTemplate.Id,
+			Title:      "Titration",
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+	})
+
+	t.Run("cannot-use-template-of-another-account", func(t *testing.T) {
+		_, err := tu.notes.CreateNoteFromTemplate(student.Context, &notesv1.CreateNoteFromTemplateRequest{
+			GroupId:    group.ID,
+			TemplateId: accountTemplate.Id,
+			Title:      "Titration",
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+	})
+
+	t.Run("cannot-create-global-template", func(t *testing.T) {
+		_, err := tu.notes.CreateTemplate(teacher.Context, &notesv1.CreateTemplateRequest{
+			GroupId: group.ID,
+			NoteId:  labReport.ID,
+			Name:    "Lab report",
+			Scope:   notesv1.TemplateScope_TEMPLATE_SCOPE_GLOBAL,
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+	})
+
+	t.Run("stranger-cannot-create-template", func(t *testing.T) {
+		_, err := tu.notes.CreateTemplate(stranger.Context, &notesv1.CreateTemplateRequest{
+			GroupId: group.ID,
+			NoteId:  labReport.ID,
+			Name:    "Stolen lab report",
+			Scope:   notesv1.TemplateScope_TEMPLATE_SCOPE_ACCOUNT,
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+	})
+
+	t.Run("only-admins-can-create-group-template", func(t *testing.T) {
+		_, err := tu.notes.CreateTemplate(student.Context, &notesv1.CreateTemplateRequest{
+			GroupId: group.ID,
+			NoteId:  labReport.ID,
+			Name:    "Student lab report",
+			Scope:   notesv1.TemplateScope_TEMPLATE_SCOPE_GROUP,
+		})
+		requireErrorHasGRPCCode(t, codes.PermissionDenied, err)
+
+		createTemplate(t, student, "Student lab report", notesv1.TemplateScope_TEMPLATE_SCOPE_ACCOUNT)
+	})
+
+	t.Run("admin-can-delete-group-template", func(t *testing.T) {
+		otherAdmin := newTestAccount(t, tu)
+		otherAdmin.AcceptInvite(t, tu, teacher.SendInvite(t, tu, otherAdmin, group))
+		_, err := tu.groups.UpdateMember(teacher.Context, &notesv1.UpdateMemberRequest{
+			GroupId:    group.ID,
+			AccountId:  otherAdmin.ID,
+			Member:     &notesv1.GroupMember{IsAdmin: true},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"is_admin"}},
+		})
+		require.NoError(t, err)
+		template := createTemplate(t, otherAdmin, "Physics lab report", notesv1.TemplateScope_TEMPLATE_SCOPE_GROUP)
+
+		_, err = tu.notes.DeleteTemplate(teacher.Context, &notesv1.DeleteTemplateRequest{TemplateId: template.Id})
+		require.NoError(t, err)
+		require.NotContains(t, listTemplateNames(t, student, group), "Physics lab report")
+	})
+
+	t.Run("only-author-can-delete-template", func(t *testing.T) {
+		_, err := tu.notes.DeleteTemplate(student.Context, &notesv1.DeleteTemplateRequest{TemplateId: groupTemplate.Id})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+
+		_, err = tu.notes.DeleteTemplate(teacher.Context, &notesv1.DeleteTemplateRequest{TemplateId: groupTemplate.Id})
+		require.NoError(t, err)
+		require.NotContains(t, listTemplateNames(t, student, group), "Chemistry lab report")
+	})
+}
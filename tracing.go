@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// initTracing exports the spans to the otlp endpoint. Without an endpoint the
+// global no-op tracer provider is kept: spans are not recorded but the trace
+// context of the callers is still forwarded to the accounts service.
+func (s *server) initTracing() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if *otlpEndpoint == "" {
+		return
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(*otlpEndpoint)}
+	if *otlpInsecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	must(err, "unable to instantiate otlp trace exporter")
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", "notes-service"),
+		attribute.String("deployment.environment", *environment),
+	))
+	must(err, "unable to describe the traced resource")
+
+	s.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*traceSampleRatio))),
+	)
+	otel.SetTracerProvider(s.tracerProvider)
+	s.slogger.Infof("traces exported to %s", *otlpEndpoint)
+}
+
+// closeTracing exports the spans still buffered.
+func (s *server) closeTracing(ctx context.Context) {
+	if s.tracerProvider == nil {
+		return
+	}
+
+	err := s.tracerProvider.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error("failed to shutdown tracer provider", zap.Error(err))
+	}
+}
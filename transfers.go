@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"notes-service/validators"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (srv *notesAPI) DuplicateNote(ctx context.Context, req *notesv1.DuplicateNoteRequest) (*notesv1.DuplicateNoteResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateDuplicateNoteRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// The note is duplicated in its own group unless another one is given.
+	destinationGroupID := req.DestinationGroupId
+	if destinationGroupID == "" {
+		destinationGroupID = req.GroupId
+	}
+
+	// Check user is part of both groups.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+	destination, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: destinationGroupID}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+	sourceNoteID := note.ID
+
+	// The copy belongs to the member who made it.
+	note.GroupID = destination.ID
+	note.FolderID = ""
+	note.AuthorAccountID = token.AccountID
+	note.CreatedAt = time.Now()
+	note.ModifiedAt = nil
+	note.SourceNoteID = ""
+	note.AccountsWithEditPermissions = transferredEditPermissions(note, destination, req.KeepEditPermissions)
+	if req.Title != "" {
+		note.Title = req.Title
+	}
+	if !req.KeepComments && note.Blocks != nil {
+		for i := range *note.Blocks {
+			(*note.Blocks)[i].Thread = nil
+		}
+	}
+	if !req.KeepQuizzes {
+		note.Quizs = nil
+	}
+	if note.Blocks != nil {
+		for i := range *note.Blocks {
+			item := (*note.Blocks)[i].ChecklistItem
+			if item != nil && destination.FindMember(item.AssigneeAccountID) == nil {
+				item.AssigneeAccountID = ""
+			}
+		}
+	}
+
+	// The repository gives new IDs to the note, its blocks, comments and quizzes.
+	duplicate, err := srv.notes.ImportNoteInternal(ctx, note)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	indexNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.notes, srv.links, duplicate)
+
+	if destination.ID != req.GroupId {
+		_, err = srv.activities.CreateActivityInternal(ctx, &models.ActivityPayload{
+			GroupID: req.GroupId,
+			Type:    models.NoteDuplicated,
+			Event:   "<userID:" + token.AccountID + "> has duplicated the note <noteID:" + sourceNoteID + "> in the group <groupID:" + destination.ID + ">.",
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = srv.activities.CreateActivityInternal(ctx, &models.ActivityPayload{
+		GroupID: duplicate.GroupID,
+		Type:    models.NoteAdded,
+		Event:   "<userID:" + duplicate.AuthorAccountID + "> has added the note <noteID:" + duplicate.ID + "> in the folder <folderID:" + duplicate.FolderID + ">.",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.DuplicateNoteResponse{Note: modelsNoteToProtobufNote(duplicate)}, nil
+}
+
+func (srv *notesAPI) MoveNote(ctx context.Context, req *notesv1.MoveNoteRequest) (*notesv1.MoveNoteResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateMoveNoteRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of both groups.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+	destination, err := srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.DestinationGroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	// Moving the note takes it away from the other members of the group.
+	if note.AuthorAccountID != token.AccountID {
+		return nil, status.Error(codes.PermissionDenied, "only the author can move the note")
+	}
+
+	filter := &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId, ExpectedRevision: req.ExpectedRevision}
+	movedNote, err := srv.notes.MoveNote(ctx,
+		filter,
+		&models.MoveNotePayload{
+			GroupID:                     destination.ID,
+			AccountsWithEditPermissions: transferredEditPermissions(note, destination, req.KeepEditPermissions),
+			DropComments:                !req.KeepComments,
+			DropQuizzes:                 !req.KeepQuizzes,
+			UnassignedAccountIDs:        transferredUnassignedAccounts(note, destination),
+		}, token.AccountID)
+	if err != nil {
+		return nil, srv.noteWriteError(ctx, filter, token.AccountID, err)
+	}
+
+	// The note is out of reach of its previous group.
+	breakLinksToNote(ctx, loggerFromContext(ctx, srv.logger), srv.links, movedNote.ID, destination.ID)
+	indexNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.notes, srv.links, movedNote)
+
+	_, err = srv.activities.CreateActivityInternal(ctx, &models.ActivityPayload{
+		GroupID: req.GroupId,
+		Type:    models.NoteMoved,
+		Event:   "<userID:" + token.AccountID + "> has moved the note <noteID:" + movedNote.ID + "> to the group <groupID:" + destination.ID + ">.",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = srv.activities.CreateActivityInternal(ctx, &models.ActivityPayload{
+		GroupID: destination.ID,
+		Type:    models.NoteMoved,
+		Event:   "<userID:" + token.AccountID + "> has moved the note <noteID:" + movedNote.ID + "> from the group <groupID:" + req.GroupId + ">.",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.MoveNoteResponse{Note: modelsNoteToProtobufNote(movedNote)}, nil
+}
+
+// transferredEditPermissions returns the accounts allowed to edit the note once
+// in the group: its author and, when kept, the members of the group who could
+// already edit it.
+func transferredEditPermissions(note *models.Note, group *models.Group, keep bool) []string {
+	permissions := []string{note.AuthorAccountID}
+	if !keep {
+		return permissions
+	}
+
+	for _, accountID := range note.AccountsWithEditPermissions {
+		if accountID != note.AuthorAccountID && group.FindMember(accountID) != nil {
+			permissions = append(permissions, accountID)
+		}
+	}
+
+	return permissions
+}
+
+// transferredUnassignedAccounts returns the assignees of the checklist items of
+// the note who are not members of the group, their items are unassigned once
+// the note is in the group.
+func transferredUnassignedAccounts(note *models.Note, group *models.Group) []string {
+	unassigned := []string{}
+	if note.Blocks == nil {
+		return unassigned
+	}
+
+	for _, block := range *note.Blocks {
+		item := block.ChecklistItem
+		if item != nil && item.AssigneeAccountID != "" && group.FindMember(item.AssigneeAccountID) == nil {
+			unassigned = append(unassigned, item.AssigneeAccountID)
+		}
+	}
+
+	return unassigned
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestTransfersSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	maxime := newTestAccount(t, tu)
+	edouard := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, maxime, edouard)
+	otherGroup := newTestGroup(t, tu, maxime, edouard)
+	strangerGroup := newTestGroup(t, tu, stranger)
+
+	newCommentedNote := func(t *testing.T) *testNote {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{
+			{Type: notesv1.Block_TYPE_PARAGRAPH, Data: &notesv1.Block_Paragraph{Paragraph: "La photosynthèse"}},
+		})
+		res, err := tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: note.ID})
+		require.NoError(t, err)
+
+		_, err = tu.notes.CreateBlockComment(edouard.Context, &notesv1.CreateBlockCommentRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			BlockId: res.Note.Blocks[0].Id,
+			Comment: &notesv1.Block_Comment{AuthorId: edouard.ID, Content: "Et la chlorophylle ?"},
+		})
+		require.NoError(t, err)
+
+		_, err = tu.notesRepository.StoreNewQuiz(context.TODO(), &models.OneNoteFilter{GroupID: group.ID, NoteID: note.ID}, &models.Quiz{
+			QuizQuestions: []models.QuizQuestion{{Question: "Où a lieu la photosynthèse ?", Answers: []string{"Chloroplaste", "Noyau"}, Solutions: []string{"Chloroplaste"}}},
+		}, maxime.ID)
+		require.NoError(t, err)
+
+		_, err = tu.notes.ChangeNoteEditPermission(maxime.Context, &notesv1.ChangeNoteEditPermissionRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			RecipientAccountId: edouard.ID,
+			Type:               notesv1.ChangeNoteEditPermissionRequest_ACTION_GRANT,
+		})
+		require.NoError(t, err)
+
+		return note
+	}
+
+	getStoredNote := func(t *testing.T, groupID string, noteID string) *models.Note {
+		note, err := tu.notesRepository.GetNote(context.TODO(), &models.OneNoteFilter{GroupID: groupID, NoteID: noteID}, maxime.ID)
+		require.NoError(t, err)
+		return note
+	}
+
+	requireActivity := func(t *testing.T, groupID string, activityType models.ActivityType) {
+		activities, err := tu.activitiesRepository.ListActivitiesInternal(context.TODO(), &models.ManyActivitiesFilter{GroupID: groupID}, &models.ListOptions{Limit: 100})
+		require.NoError(t, err)
+		for _, activity := range activities {
+			if activity.Type == string(activityType) {
+				return
+			}
+		}
+		require.Fail(t, "missing activity", "no %s activity in group", activityType)
+	}
+
+	t.Run("member-can-duplicate-note", func(t *testing.T) {
+		note := newCommentedNote(t)
+
+		res, err := tu.notes.DuplicateNote(edouard.Context, &notesv1.DuplicateNoteRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			Title:   "Copie",
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, note.ID, res.Note.Id)
+		require.Equal(t, group.ID, res.Note.GroupId)
+		require.Equal(t, edouard.ID, res.Note.AuthorAccountId)
+		require.Equal(t, "Copie", res.Note.Title)
+		require.Len(t, res.Note.Blocks, 1)
+		require.Equal(t, "La photosynthèse", res.Note.Blocks[0].GetParagraph())
+
+		original := getStoredNote(t, group.ID, note.ID)
+		duplicate := getStoredNote(t, group.ID, res.Note.Id)
+		require.NotEqual(t, (*original.Blocks)[0].ID, (*duplicate.Blocks)[0].ID)
+		require.Empty(t, *(*duplicate.Blocks)[0].Thread)
+		require.Empty(t, *duplicate.Quizs)
+		require.Equal(t, []string{edouard.ID}, duplicate.AccountsWithEditPermissions)
+	})
+
+	t.Run("duplicate-keeps-comments-quizzes-and-permissions-when-asked", func(t *testing.T) {
+		note := newCommentedNote(t)
+
+		res, err := tu.notes.DuplicateNote(maxime.Context, &notesv1.DuplicateNoteRequest{
+			GroupId:             group.ID,
+			NoteId:              note.ID,
+			DestinationGroupId:  otherGroup.ID,
+			KeepComments:        true,
+			KeepQuizzes:         true,
+			KeepEditPermissions: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, otherGroup.ID, res.Note.GroupId)
+		require.Equal(t, "Default Title", res.Note.Title)
+
+		original := getStoredNote(t, group.ID, note.ID)
+		duplicate := getStoredNote(t, otherGroup.ID, res.Note.Id)
+		require.Len(t, *(*duplicate.Blocks)[0].Thread, 1)
+		require.NotEqual(t, (*(*original.Blocks)[0].Thread)[0].ID, (*(*duplicate.Blocks)[0].Thread)[0].ID)
+		require.Len(t, *duplicate.Quizs, 1)
+		require.NotEqual(t, (*original.Quizs)[0].ID, (*duplicate.Quizs)[0].ID)
+		require.ElementsMatch(t, []string{maxime.ID, edouard.ID}, duplicate.AccountsWithEditPermissions)
+
+		requireActivity(t, group.ID, models.NoteDuplicated)
+		requireActivity(t, otherGroup.ID, models.NoteAdded)
+	})
+
+	t.Run("cannot-duplicate-note-to-group-of-stranger", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+
+		_, err := tu.notes.DuplicateNote(maxime.Context, &notesv1.DuplicateNoteRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			DestinationGroupId: strangerGroup.ID,
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+	})
+
+	t.Run("author-can-move-note", func(t *testing.T) {
+		note := newCommentedNote(t)
+		before := getStoredNote(t, group.ID, note.ID)
+
+		res, err := tu.notes.MoveNote(maxime.Context, &notesv1.MoveNoteRequest{
+			GroupId:             group.ID,
+			NoteId:              note.ID,
+			DestinationGroupId:  otherGroup.ID,
+			KeepEditPermissions: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, note.ID, res.Note.Id)
+		require.Equal(t, otherGroup.ID, res.Note.GroupId)
+
+		_, err = tu.notes.GetNote(maxime.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: note.ID})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+
+		moved := getStoredNote(t, otherGroup.ID, note.ID)
+		require.Empty(t, *(*moved.Blocks)[0].Thread)
+		require.Empty(t, *moved.Quizs)
+		require.ElementsMatch(t, []string{maxime.ID, edouard.ID}, moved.AccountsWithEditPermissions)
+		require.Equal(t, before.Revision+1, moved.Revision)
+
+		requireActivity(t, group.ID, models.NoteMoved)
+		requireActivity(t, otherGroup.ID, models.NoteMoved)
+	})
+
+	t.Run("move-keeps-comments-and-quizzes-when-asked", func(t *testing.T) {
+		note := newCommentedNote(t)
+
+		_, err := tu.notes.MoveNote(maxime.Context, &notesv1.MoveNoteRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			DestinationGroupId: otherGroup.ID,
+			KeepComments:       true,
+			KeepQuizzes:        true,
+		})
+		require.NoError(t, err)
+
+		moved := getStoredNote(t, otherGroup.ID, note.ID)
+		require.Len(t, *(*moved.Blocks)[0].Thread, 1)
+		require.Len(t, *moved.Quizs, 1)
+		require.Equal(t, []string{maxime.ID}, moved.AccountsWithEditPermissions)
+	})
+
+	t.Run("transfers-unassign-checklist-items-outside-of-the-destination", func(t *testing.T) {
+		soloGroup := newTestGroup(t, tu, maxime)
+		newAssignedNote := func() *testNote {
+			return newTestNote(t, tu, group, maxime, []*notesv1.Block{
+				{Type: notesv1.Block_TYPE_CHECKLIST_ITEM, Data: &notesv1.Block_ChecklistItem_{
+					ChecklistItem: &notesv1.Block_ChecklistItem{Text: "Arroser les plantes", AssigneeAccountId: edouard.ID},
+				}},
+				{Type: notesv1.Block_TYPE_CHECKLIST_ITEM, Data: &notesv1.Block_ChecklistItem_{
+					ChecklistItem: &notesv1.Block_ChecklistItem{Text: "Tailler les rosiers", AssigneeAccountId: maxime.ID},
+				}},
+			})
+		}
+
+		note := newAssignedNote()
+		duplicate, err := tu.notes.DuplicateNote(maxime.Context, &notesv1.DuplicateNoteRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			DestinationGroupId: soloGroup.ID,
+		})
+		require.NoError(t, err)
+		require.Empty(t, duplicate.Note.Blocks[0].GetChecklistItem().AssigneeAccountId)
+		require.Equal(t, maxime.ID, duplicate.Note.Blocks[1].GetChecklistItem().AssigneeAccountId)
+
+		note = newAssignedNote()
+		before := getStoredNote(t, group.ID, note.ID)
+		_, err = tu.notes.MoveNote(maxime.Context, &notesv1.MoveNoteRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			DestinationGroupId: soloGroup.ID,
+			KeepComments:       true,
+		})
+		require.NoError(t, err)
+
+		moved := getStoredNote(t, soloGroup.ID, note.ID)
+		require.Empty(t, (*moved.Blocks)[0].ChecklistItem.AssigneeAccountID)
+		require.Equal(t, (*before.Blocks)[0].Revision+1, (*moved.Blocks)[0].Revision)
+		require.Equal(t, maxime.ID, (*moved.Blocks)[1].ChecklistItem.AssigneeAccountID)
+		require.Equal(t, (*before.Blocks)[1].Revision, (*moved.Blocks)[1].Revision)
+	})
+
+	t.Run("member-cannot-move-note-of-another-member", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+
+		_, err := tu.notes.MoveNote(edouard.Context, &notesv1.MoveNoteRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			DestinationGroupId: otherGroup.ID,
+		})
+		requireErrorHasGRPCCode(t, codes.PermissionDenied, err)
+	})
+
+	t.Run("cannot-move-note-to-group-of-stranger", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+
+		_, err := tu.notes.MoveNote(maxime.Context, &notesv1.MoveNoteRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			DestinationGroupId: strangerGroup.ID,
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+	})
+
+	t.Run("cannot-move-note-to-its-own-group", func(t *testing.T) {
+		note := newTestNote(t, tu, group, maxime, []*notesv1.Block{})
+
+		_, err := tu.notes.MoveNote(maxime.Context, &notesv1.MoveNoteRequest{
+			GroupId:            group.ID,
+			NoteId:             note.ID,
+			DestinationGroupId: group.ID,
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+	})
+}
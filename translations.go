@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"notes-service/language"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"notes-service/validators"
+
+	background "github.com/noted-eip/noted/background-service"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (srv *notesAPI) TranslateNote(ctx context.Context, req *notesv1.TranslateNoteRequest) (*notesv1.TranslateNoteResponse, error) {
+	token, err := srv.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validators.ValidateTranslateNoteRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check user is part of the group.
+	_, err = srv.groups.GetGroup(ctx, &models.OneGroupFilter{GroupID: req.GroupId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	note, err := srv.notes.GetNote(ctx, &models.OneNoteFilter{GroupID: req.GroupId, NoteID: req.NoteId}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	if noteLang(note, noteModelToString(note)) == req.Lang {
+		return nil, status.Errorf(codes.InvalidArgument, "note is already written in %s", req.Lang)
+	}
+
+	err = srv.checkLanguageRateLimits(language.MethodTranslation, token.AccountID, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	title, blocks, err := srv.translateNote(ctx, note, req.Lang)
+	if err != nil {
+		loggerFromContext(ctx, srv.logger).Error("failed to translate note", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to translate note for noteId : %s", note.ID)
+	}
+
+	if req.Preview {
+		return &notesv1.TranslateNoteResponse{Note: modelsNoteToProtobufNote(&models.Note{
+			Title:           title,
+			AuthorAccountID: token.AccountID,
+			GroupID:         note.GroupID,
+			CreatedAt:       time.Now(),
+			Blocks:          &blocks,
+			Lang:            req.Lang,
+			SourceNoteID:    note.ID,
+		})}, nil
+	}
+
+	translatedNote, err := srv.notes.CreateNote(ctx, &models.CreateNotePayload{
+		GroupID:         note.GroupID,
+		Title:           title,
+		AuthorAccountID: token.AccountID,
+		FolderID:        "",
+		Lang:            req.Lang,
+		Blocks:          blocks,
+		SourceNoteID:    note.ID,
+	}, token.AccountID)
+	if err != nil {
+		return nil, statusFromModelError(err)
+	}
+
+	err = srv.background.AddProcess(&background.Process{
+		Identifier: models.NoteIdentifier{Metadata: translatedNote.ID, ActionType: models.NoteUpdateKeyword},
+		CallBackFct: func() error {
+			err := srv.UpdateKeywordsByNoteId(translatedNote.ID, translatedNote.GroupID, token.AccountID)
+			return err
+		},
+		SecondsToDebounce:             5,
+		CancelProcessOnSameIdentifier: true,
+		RepeatProcess:                 false,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = srv.activities.CreateActivityInternal(ctx, &models.ActivityPayload{
+		GroupID: translatedNote.GroupID,
+		Type:    models.NoteAdded,
+		Event:   "<userID:" + translatedNote.AuthorAccountID + "> has added the note <noteID:" + translatedNote.ID + "> in the folder <folderID:" + "" + ">.",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &notesv1.TranslateNoteResponse{Note: modelsNoteToProtobufNote(translatedNote)}, nil
+}
+
+// translatedCell is the position of a text cell of a table in the blocks.
+type translatedCell struct {
+	block, row, cell int
+}
+
+// translateNote translates the title and the blocks of the note in a single
+// call to the language service. The returned blocks have no ID nor comments,
+// code, math, images other than their caption and table cells other than the
+// text ones are left untouched.
+func (srv *notesAPI) translateNote(ctx context.Context, note *models.Note, lang string) (string, []models.NoteBlock, error) {
+	blocks := []models.NoteBlock{}
+	if note.Blocks != nil {
+		blocks = make([]models.NoteBlock, len(*note.Blocks))
+		copy(blocks, *note.Blocks)
+	}
+
+	inputs := []string{note.Title}
+	translatedBlocks := []int{}
+	cellInputs := []string{}
+	translatedCells := []translatedCell{}
+
+	for i := range blocks {
+		blocks[i].ID = ""
+		blocks[i].Thread = nil
+
+		if blocks[i].Type == "TYPE_TABLE" && blocks[i].Table != nil {
+			blocks[i].Table = copyTable(blocks[i].Table)
+			for r, row := range blocks[i].Table.Rows {
+				for c, cell := range row.Cells {
+					if cell.Type != "TYPE_TEXT" || cell.Value == "" {
+						continue
+					}
+					cellInputs = append(cellInputs, cell.Value)
+					translatedCells = append(translatedCells, translatedCell{block: i, row: r, cell: c})
+				}
+			}
+			continue
+		}
+
+		text, ok := translatableText(&blocks[i])
+		if !ok {
+			continue
+		}
+		if blocks[i].Styles != nil && blocks[i].Type != "TYPE_IMAGE" {
+			text = embedStyleMarkers(text, *blocks[i].Styles)
+		}
+		inputs = append(inputs, text)
+		translatedBlocks = append(translatedBlocks, i)
+	}
+
+	// The cells are translated after the blocks so that the translations of the
+	// blocks keep their indexes.
+	inputs = append(inputs, cellInputs...)
+
+	translations, err := srv.language.TranslateTextInputs(ctx, inputs, lang)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(translations) != len(inputs) {
+		return "", nil, fmt.Errorf("expected %d translations, got %d", len(inputs), len(translations))
+	}
+
+	for i, blockIndex := range translatedBlocks {
+		block := &blocks[blockIndex]
+		translation := translations[i+1]
+
+		if block.Styles != nil && block.Type != "TYPE_IMAGE" {
+			source, _ := translatableText(block)
+			var styles []models.TextStyle
+			translation, styles = extractStyleMarkers(translation, *block.Styles, utf8.RuneCountInString(source))
+			block.Styles = &styles
+		}
+		setTranslatableText(block, translation)
+	}
+
+	offset := 1 + len(translatedBlocks)
+	for i, position := range translatedCells {
+		blocks[position.block].Table.Rows[position.row].Cells[position.cell].Value = translations[offset+i]
+	}
+
+	return truncateRunes(translations[0], 64), blocks, nil
+}
+
+// copyTable copies the rows of the table so that its cells can be modified
+// without modifying the block it was copied from.
+func copyTable(table *models.NoteBlockTable) *models.NoteBlockTable {
+	rows := make([]models.NoteBlockTableRow, len(table.Rows))
+	for i, row := range table.Rows {
+		rows[i].Cells = make([]models.NoteBlockTableCell, len(row.Cells))
+		copy(rows[i].Cells, row.Cells)
+	}
+	return &models.NoteBlockTable{HasHeaderRow: table.HasHeaderRow, Rows: rows}
+}
+
+// translatableText returns the text of the block which should be translated.
+func translatableText(block *models.NoteBlock) (string, bool) {
+	switch block.Type {
+	case "TYPE_HEADING_1", "TYPE_HEADING_2", "TYPE_HEADING_3":
+		return stringPtrValueOrFallback(block.Heading, ""), true
+	case "TYPE_PARAGRAPH":
+		return stringPtrValueOrFallback(block.Paragraph, ""), true
+	case "TYPE_BULLET_POINT":
+		return stringPtrValueOrFallback(block.BulletPoint, ""), true
+	case "TYPE_NUMBER_POINT":
+		return stringPtrValueOrFallback(block.NumberPoint, ""), true
+	case "TYPE_SUMMARY":
+		return stringPtrValueOrFallback(block.Summary, ""), true
+	case "TYPE_IMAGE":
+		if block.Image == nil {
+			return "", false
+		}
+		return block.Image.Caption, true
+	case "TYPE_CHECKLIST_ITEM":
+		if block.ChecklistItem == nil {
+			return "", false
+		}
+		return block.ChecklistItem.Text, true
+	case "TYPE_CALLOUT":
+		if block.Callout == nil {
+			return "", false
+		}
+		return block.Callout.Text, true
+	default:
+		return "", false
+	}
+}
+
+// setTranslatableText replaces the text of the block without modifying the
+// values it shares with the block it was copied from.
+func setTranslatableText(block *models.NoteBlock, text string) {
+	switch block.Type {
+	case "TYPE_HEADING_1", "TYPE_HEADING_2", "TYPE_HEADING_3":
+		block.Heading = &text
+	case "TYPE_PARAGRAPH":
+		block.Paragraph = &text
+	case "TYPE_BULLET_POINT":
+		block.BulletPoint = &text
+	case "TYPE_NUMBER_POINT":
+		block.NumberPoint = &text
+	case "TYPE_SUMMARY":
+		block.Summary = &text
+	case "TYPE_IMAGE":
+		block.Image = &models.NoteBlockImage{Url: block.Image.Url, Caption: text}
+	case "TYPE_CHECKLIST_ITEM":
+		item := *block.ChecklistItem
+		item.Text = text
+		block.ChecklistItem = &item
+	case "TYPE_CALLOUT":
+		block.Callout = &models.NoteBlockCallout{Tone: block.Callout.Tone, Text: text}
+	}
+}
+
+var styleMarker = regexp.MustCompile(`</?s(\d+)>`)
+
+// embedStyleMarkers surrounds the text of the n-th style with <sn> and </sn>
+// so that the styled words can be found back once translated.
+func embedStyleMarkers(text string, styles []models.TextStyle) string {
+	runes := []rune(text)
+	builder := strings.Builder{}
+
+	for i := 0; i <= len(runes); i++ {
+		for n, style := range styles {
+			if style.Position.Length > 0 && int(style.Position.Start+style.Position.Length) == i {
+				builder.WriteString("</s" + strconv.Itoa(n) + ">")
+			}
+		}
+		for n, style := range styles {
+			if style.Position.Length > 0 && int(style.Position.Start) == i {
+				builder.WriteString("<s" + strconv.Itoa(n) + ">")
+			}
+		}
+		if i < len(runes) {
+			builder.WriteRune(runes[i])
+		}
+	}
+
+	return builder.String()
+}
+
+// extractStyleMarkers removes the markers added by embedStyleMarkers from the
+// translated text and returns the styles at their new positions. Styles whose
+// markers were lost during the translation are scaled to the new text length.
+func extractStyleMarkers(translated string, styles []models.TextStyle, sourceLength int) (string, []models.TextStyle) {
+	starts := map[int]int{}
+	ends := map[int]int{}
+	builder := strings.Builder{}
+	length := 0
+	last := 0
+
+	for _, loc := range styleMarker.FindAllStringSubmatchIndex(translated, -1) {
+		chunk := translated[last:loc[0]]
+		builder.WriteString(chunk)
+		length += utf8.RuneCountInString(chunk)
+		last = loc[1]
+
+		n, err := strconv.Atoi(translated[loc[2]:loc[3]])
+		if err != nil {
+			continue
+		}
+		if translated[loc[0]+1] == '/' {
+			ends[n] = length
+		} else {
+			starts[n] = length
+		}
+	}
+	builder.WriteString(translated[last:])
+	length += utf8.RuneCountInString(translated[last:])
+
+	translatedStyles := make([]models.TextStyle, len(styles))
+	for n, style := range styles {
+		translatedStyles[n] = style
+
+		start, hasStart := starts[n]
+		end, hasEnd := ends[n]
+		if hasStart && hasEnd && end >= start {
+			translatedStyles[n].Position = models.Position{Start: int64(start), Length: int64(end - start)}
+		} else {
+			translatedStyles[n].Position = scalePosition(style.Position, sourceLength, length)
+		}
+	}
+
+	return builder.String(), translatedStyles
+}
+
+func scalePosition(pos models.Position, sourceLength int, length int) models.Position {
+	if sourceLength == 0 {
+		return models.Position{}
+	}
+
+	start := pos.Start * int64(length) / int64(sourceLength)
+	end := (pos.Start + pos.Length) * int64(length) / int64(sourceLength)
+	if end > int64(length) {
+		end = int64(length)
+	}
+	if start > end {
+		start = end
+	}
+
+	return models.Position{Start: start, Length: end - start}
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"notes-service/language"
+	"notes-service/models"
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// prefixTranslator "translates" texts by prefixing them with the target lang.
+type prefixTranslator struct {
+	language.Service
+}
+
+func (s *prefixTranslator) TranslateTextInputs(ctx context.Context, inputs []string, lang string) ([]string, error) {
+	translations := make([]string, len(inputs))
+	for i, input := range inputs {
+		translations[i] = "[" + lang + "] " + input
+	}
+	return translations, nil
+}
+
+func TestTranslationsSuite(t *testing.T) {
+	tu := newTestUtilsOrDie(t)
+	tu.notes.(*notesAPI).language = &prefixTranslator{}
+	edouard := newTestAccount(t, tu)
+	stranger := newTestAccount(t, tu)
+	group := newTestGroup(t, tu, edouard)
+	note := newTestNote(t, tu, group, edouard, []*notesv1.Block{
+		{
+			Type: notesv1.Block_TYPE_PARAGRAPH,
+			Data: &notesv1.Block_Paragraph{Paragraph: "Le chat est noir"},
+			Styles: []*notesv1.Block_TextStyle{
+				{
+					Style: notesv1.Block_TextStyle_STYLE_BOLD,
+					Pos:   &notesv1.Block_TextStyle_Position{Start: 3, Length: 4},
+				},
+			},
+		},
+		{
+			Type: notesv1.Block_TYPE_CODE,
+			Data: &notesv1.Block_Code_{Code: &notesv1.Block_Code{Snippet: "fmt.Println(\"chat\")", Lang: "go"}},
+		},
+		{
+			Type: notesv1.Block_TYPE_IMAGE,
+			Data: &notesv1.Block_Image_{Image: &notesv1.Block_Image{Url: "https://noted.fr/chat.png", Caption: "Un chat"}},
+		},
+		{
+			Type: notesv1.Block_TYPE_TABLE,
+			Data: &notesv1.Block_Table_{Table: &notesv1.Block_Table{
+				HasHeaderRow: true,
+				Rows: []*notesv1.Block_Table_Row{
+					{Cells: []*notesv1.Block_Table_Cell{
+						{Type: notesv1.Block_Table_Cell_TYPE_TEXT, Value: "Race"},
+						{Type: notesv1.Block_Table_Cell_TYPE_TEXT, Value: "Poids"},
+					}},
+					{Cells: []*notesv1.Block_Table_Cell{
+						{Type: notesv1.Block_Table_Cell_TYPE_TEXT, Value: "Siamois"},
+						{Type: notesv1.Block_Table_Cell_TYPE_NUMBER, Value: "4.5"},
+					}},
+				},
+			}},
+		},
+	})
+
+	t.Run("stranger-cannot-translate-note", func(t *testing.T) {
+		res, err := tu.notes.TranslateNote(stranger.Context, &notesv1.TranslateNoteRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			Lang:    "en",
+		})
+		requireErrorHasGRPCCode(t, codes.NotFound, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("cannot-translate-note-to-unsupported-lang", func(t *testing.T) {
+		res, err := tu.notes.TranslateNote(edouard.Context, &notesv1.TranslateNoteRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			Lang:    "klingon",
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("cannot-translate-note-to-its-own-lang", func(t *testing.T) {
+		res, err := tu.notes.TranslateNote(edouard.Context, &notesv1.TranslateNoteRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			Lang:    "fr",
+		})
+		requireErrorHasGRPCCode(t, codes.InvalidArgument, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("preview-does-not-create-note", func(t *testing.T) {
+		res, err := tu.notes.TranslateNote(edouard.Context, &notesv1.TranslateNoteRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			Lang:    "en",
+			Preview: true,
+		})
+		require.NoError(t, err)
+		require.Empty(t, res.Note.Id)
+		require.Equal(t, note.ID, res.Note.SourceNoteId)
+		require.Equal(t, "[en] Le chat est noir", res.Note.Blocks[0].GetParagraph())
+
+		notes, err := tu.notes.ListNotes(edouard.Context, &notesv1.ListNotesRequest{GroupId: group.ID})
+		require.NoError(t, err)
+		require.Len(t, notes.Notes, 1)
+	})
+
+	t.Run("member-can-translate-note", func(t *testing.T) {
+		res, err := tu.notes.TranslateNote(edouard.Context, &notesv1.TranslateNoteRequest{
+			GroupId: group.ID,
+			NoteId:  note.ID,
+			Lang:    "en",
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, res.Note.Id)
+		require.Equal(t, "en", res.Note.Lang)
+		require.Equal(t, note.ID, res.Note.SourceNoteId)
+		require.Equal(t, "[en] Default Title", res.Note.Title)
+
+		paragraph := res.Note.Blocks[0]
+		require.Equal(t, "[en] Le chat est noir", paragraph.GetParagraph())
+		require.Equal(t, int64(8), paragraph.Styles[0].Pos.Start, "the style should follow the translated word")
+		require.Equal(t, int64(4), paragraph.Styles[0].Pos.Length)
+
+		require.Equal(t, "fmt.Println(\"chat\")", res.Note.Blocks[1].GetCode().Snippet)
+		require.Equal(t, "https://noted.fr/chat.png", res.Note.Blocks[2].GetImage().Url)
+		require.Equal(t, "[en] Un chat", res.Note.Blocks[2].GetImage().Caption)
+
+		rows := res.Note.Blocks[3].GetTable().Rows
+		require.Equal(t, "[en] Race", rows[0].Cells[0].Value)
+		require.Equal(t, "[en] Poids", rows[0].Cells[1].Value)
+		require.Equal(t, "[en] Siamois", rows[1].Cells[0].Value)
+		require.Equal(t, "4.5", rows[1].Cells[1].Value, "only the text cells should be translated")
+
+		source, err := tu.notes.GetNote(edouard.Context, &notesv1.GetNoteRequest{GroupId: group.ID, NoteId: note.ID})
+		require.NoError(t, err)
+		require.Equal(t, "Race", source.Note.Blocks[3].GetTable().Rows[0].Cells[0].Value, "the source note should be left untouched")
+	})
+}
+
+func TestExtractStyleMarkers(t *testing.T) {
+	styles := []models.TextStyle{
+		{Style: "STYLE_BOLD", Position: models.Position{Start: 3, Length: 4}},
+		{Style: "STYLE_ITALIC", Position: models.Position{Start: 12, Length: 4}},
+	}
+
+	embedded := embedStyleMarkers("Le chat est noir", styles)
+	require.Equal(t, "Le <s0>chat</s0> est <s1>noir</s1>", embedded)
+
+	text, translated := extractStyleMarkers("The <s1>black</s1> <s0>cat</s0>", styles, 16)
+	require.Equal(t, "The black cat", text)
+	require.Equal(t, models.Position{Start: 10, Length: 3}, translated[0].Position)
+	require.Equal(t, models.Position{Start: 4, Length: 5}, translated[1].Position)
+
+	// Styles whose markers were dropped are scaled to the translated text.
+	text, translated = extractStyleMarkers("The black cat", styles, 16)
+	require.Equal(t, "The black cat", text)
+	require.Equal(t, models.Position{Start: 2, Length: 3}, translated[0].Position)
+}
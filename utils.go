@@ -7,6 +7,7 @@ import (
 	"notes-service/language"
 	"notes-service/models"
 	"notes-service/models/mongo"
+	"strings"
 
 	background "github.com/noted-eip/noted/background-service"
 
@@ -39,6 +40,9 @@ func statusFromModelError(err error) error {
 	if errors.Is(err, models.ErrForbidden) {
 		return status.Error(codes.PermissionDenied, "forbidden operation")
 	}
+	if errors.Is(err, models.ErrConflict) {
+		return status.Error(codes.Aborted, "modified since the expected revision")
+	}
 	return status.Error(codes.Internal, "internal error")
 }
 
@@ -56,8 +60,12 @@ type testUtils struct {
 	notesRepository      models.NotesRepository
 	groupsRepository     models.GroupsRepository
 	activitiesRepository models.ActivitiesRepository
+	scoresRepository     models.ScoresRepository
+	linksRepository      models.LinksRepository
+	templatesRepository  models.TemplatesRepository
 	notes                notesv1.NotesAPIServer
 	groups               notesv1.GroupsAPIServer
+	recommendations      notesv1.RecommendationsAPIServer
 	newUUID              func() string
 }
 
@@ -77,6 +85,12 @@ func newTestUtilsOrDie(t *testing.T) *testUtils {
 	notesRepository := mongo.NewNotesRepository(db.DB, logger)
 	groupsRepository := mongo.NewGroupsRepository(db.DB, logger)
 	activitiesRepository := mongo.NewActivitiesRepository(db.DB, logger)
+	scoresRepository := mongo.NewScoresRepository(db.DB, logger)
+	linksRepository := mongo.NewLinksRepository(db.DB, logger)
+	templatesRepository := mongo.NewTemplatesRepository(db.DB, logger)
+	require.NoError(t, templatesRepository.StoreGlobalTemplatesInternal(context.TODO(), globalTemplates()))
+	languageUsage := language.NewUsage()
+	embeddings := language.NewHashingEmbeddingProvider()
 	language := &language.NotedLanguageService{}
 	err = language.Init(logger)
 	require.NoError(t, err, "Error on language intialization")
@@ -93,14 +107,22 @@ func newTestUtilsOrDie(t *testing.T) *testUtils {
 		notesRepository:      notesRepository,
 		groupsRepository:     groupsRepository,
 		activitiesRepository: activitiesRepository,
+		scoresRepository:     scoresRepository,
+		linksRepository:      linksRepository,
+		templatesRepository:  templatesRepository,
 		notes: &notesAPI{
 			logger:     logger,
 			auth:       auth,
 			notes:      notesRepository,
 			groups:     groupsRepository,
 			activities: activitiesRepository,
+			links:      linksRepository,
+			templates:  templatesRepository,
 			language:   language,
 			background: background,
+
+			embeddings:    embeddings,
+			languageUsage: languageUsage,
 		},
 		groups: &groupsAPI{
 			logger:     logger,
@@ -108,8 +130,17 @@ func newTestUtilsOrDie(t *testing.T) *testUtils {
 			notes:      notesRepository,
 			groups:     groupsRepository,
 			activities: activitiesRepository,
+			scores:     scoresRepository,
+			links:      linksRepository,
 			background: background,
 		},
+		recommendations: &recommendationsAPI{
+			logger:   logger,
+			auth:     auth,
+			notes:    notesRepository,
+			groups:   groupsRepository,
+			language: language,
+		},
 	}
 }
 
@@ -287,6 +318,35 @@ func GetBlockContent(block *models.NoteBlock) (string, bool) {
 		return *block.BulletPoint, true
 	case "TYPE_NUMBER_POINT":
 		return *block.NumberPoint, true
+	case "TYPE_CHECKLIST_ITEM":
+		if block.ChecklistItem == nil {
+			return "", false
+		}
+		return block.ChecklistItem.Text, true
+	case "TYPE_CALLOUT":
+		if block.Callout == nil {
+			return "", false
+		}
+		return block.Callout.Text, true
+	case "TYPE_TABLE":
+		if block.Table == nil {
+			return "", false
+		}
+		rows := []string{}
+		for _, row := range block.Table.Rows {
+			cells := []string{}
+			for _, cell := range row.Cells {
+				cells = append(cells, cell.Value)
+			}
+			rows = append(rows, strings.Join(cells, " | "))
+		}
+		return strings.Join(rows, "\n"), true
+	case "TYPE_EMBED":
+		// Only the title of an embed tells what it is about.
+		if block.Embed == nil || block.Embed.Title == "" {
+			return "", false
+		}
+		return block.Embed.Title, true
 	default:
 		return "", false
 	}
@@ -304,6 +364,11 @@ func (srv *groupsAPI) moveNotesToUserWorkspaceOrDeleteThem(ctx context.Context,
 		return errors.New("specify a user in order to move notes")
 	}
 
+	notes, err := srv.notes.ListAllNotesInternal(ctx, filter)
+	if err != nil {
+		return statusFromModelError(err)
+	}
+
 	memberWorkspace, err := srv.groups.GetWorkspaceInternal(ctx, filter.AuthorAccountID)
 	if err == nil {
 		_, err = srv.notes.UpdateNotesInternal(
@@ -314,11 +379,20 @@ func (srv *groupsAPI) moveNotesToUserWorkspaceOrDeleteThem(ctx context.Context,
 		if err != nil {
 			return statusFromModelError(err)
 		}
+		// The moved notes are out of reach of their old group.
+		for _, note := range notes {
+			breakLinksToNote(ctx, loggerFromContext(ctx, srv.logger), srv.links, note.ID, memberWorkspace.ID)
+			note.GroupID = memberWorkspace.ID
+			indexNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.notes, srv.links, note)
+		}
 	} else if err == models.ErrNotFound {
 		err = srv.notes.DeleteNotes(ctx, filter)
 		if err != nil && err != models.ErrNotFound {
 			return statusFromModelError(err)
 		}
+		for _, note := range notes {
+			forgetNoteLinks(ctx, loggerFromContext(ctx, srv.logger), srv.links, note.ID)
+		}
 	} else {
 		return statusFromModelError(err)
 	}
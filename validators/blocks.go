@@ -1,6 +1,11 @@
 package validators
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
 	notespb "notes-service/protorepo/noted/notes/v1"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -13,15 +18,23 @@ func ValidateInsertBlockRequest(req *notespb.InsertBlockRequest) error {
 	if err != nil {
 		return err
 	}
-	return validation.Validate(req.Block, validation.Required)
+	err = validation.Validate(req.Block, validation.Required)
+	if err != nil {
+		return err
+	}
+	return ValidateBlock(req.Block)
 }
 
 func ValidateUpdateBlockRequest(req *notespb.UpdateBlockRequest) error {
-	return validation.ValidateStruct(req,
+	err := validation.ValidateStruct(req,
 		validation.Field(&req.GroupId, validation.Required),
 		validation.Field(&req.NoteId, validation.Required),
 		validation.Field(&req.BlockId, validation.Required),
 	)
+	if err != nil {
+		return err
+	}
+	return ValidateBlock(req.Block)
 }
 
 func ValidateUpdateBlockIndexRequest(req *notespb.UpdateBlockIndexRequest) error {
@@ -29,7 +42,7 @@ func ValidateUpdateBlockIndexRequest(req *notespb.UpdateBlockIndexRequest) error
 		validation.Field(&req.GroupId, validation.Required),
 		validation.Field(&req.NoteId, validation.Required),
 		validation.Field(&req.BlockId, validation.Required),
-		validation.Field(&req.Index, validation.Required),
+		// Index 0 moves the block to the top of the note, it can't be required.
 	)
 }
 
@@ -40,3 +53,278 @@ func ValidateDeleteBlockRequest(req *notespb.DeleteBlockRequest) error {
 		validation.Field(&req.BlockId, validation.Required),
 	)
 }
+
+// Maximum number of operations of a BatchUpdateBlocks request.
+const maxBlockOperations = 500
+
+func ValidateBatchUpdateBlocksRequest(req *notespb.BatchUpdateBlocksRequest) error {
+	err := validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.NoteId, validation.Required),
+		validation.Field(&req.Operations, validation.Required, validation.Length(1, maxBlockOperations)),
+	)
+	if err != nil {
+		return err
+	}
+
+	errs := validation.Errors{}
+	for i, operation := range req.Operations {
+		if err := validateBlockOperation(operation); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+	}
+	return validation.Errors{"operations": errs.Filter()}.Filter()
+}
+
+func validateBlockOperation(operation *notespb.BlockOperation) error {
+	switch {
+	case operation.GetInsert() != nil:
+		insert := operation.GetInsert()
+		err := validation.Validate(insert.Block, validation.Required)
+		if err != nil {
+			return validation.Errors{"block": err}
+		}
+		return validation.Errors{"block": ValidateBlock(insert.Block)}.Filter()
+	case operation.GetUpdate() != nil:
+		update := operation.GetUpdate()
+		err := validation.ValidateStruct(update,
+			validation.Field(&update.BlockId, validation.Required),
+			validation.Field(&update.Block, validation.Required),
+		)
+		if err != nil {
+			return err
+		}
+		return validation.Errors{"block": ValidateBlock(update.Block)}.Filter()
+	case operation.GetDelete() != nil:
+		deletion := operation.GetDelete()
+		return validation.ValidateStruct(deletion,
+			validation.Field(&deletion.BlockId, validation.Required),
+		)
+	case operation.GetMove() != nil:
+		move := operation.GetMove()
+		return validation.ValidateStruct(move,
+			validation.Field(&move.BlockId, validation.Required),
+		)
+	}
+	return errors.New("must be an insert, update, delete or move")
+}
+
+// Maximum size of the grid of a table block.
+const (
+	maxTableRows    = 500
+	maxTableColumns = 50
+)
+
+// ValidateBlock checks the content of the block types holding more than a
+// text. The content of the other types is not checked.
+func ValidateBlock(block *notespb.Block) error {
+	if block == nil {
+		return nil
+	}
+
+	err := validateStyles(block.Styles)
+	if err != nil {
+		return validation.Errors{"styles": err}
+	}
+
+	switch block.Type {
+	case notespb.Block_TYPE_CHECKLIST_ITEM:
+		return validation.Errors{
+			"checklist_item": validation.Validate(block.GetChecklistItem(), validation.Required),
+		}.Filter()
+	case notespb.Block_TYPE_TABLE:
+		return validation.Errors{
+			"table": validation.Validate(block.GetTable(), validation.Required, validation.By(validateTable)),
+		}.Filter()
+	case notespb.Block_TYPE_CALLOUT:
+		return validation.Errors{
+			"callout": validation.Validate(block.GetCallout(), validation.Required, validation.By(validateCallout)),
+		}.Filter()
+	case notespb.Block_TYPE_DIVIDER:
+		if block.Data != nil {
+			return validation.Errors{"divider": errors.New("must be blank")}
+		}
+	case notespb.Block_TYPE_EMBED:
+		return validation.Errors{
+			"embed": validation.Validate(block.GetEmbed(), validation.Required, validation.By(validateEmbed)),
+		}.Filter()
+	}
+
+	return nil
+}
+
+// ValidateRestoredBlock checks a block restored from a backup. Unlike the
+// blocks of the update requests it holds its whole content, so on top of the
+// checks of ValidateBlock its styles must stay within its text.
+func ValidateRestoredBlock(block *notespb.Block) error {
+	err := validation.Validate(block, validation.Required)
+	if err != nil {
+		return err
+	}
+	err = ValidateBlock(block)
+	if err != nil {
+		return err
+	}
+
+	length := int64(len([]rune(blockText(block))))
+	errs := validation.Errors{}
+	for i, style := range block.Styles {
+		pos := style.GetPos()
+		if pos == nil {
+			continue
+		}
+		if pos.Start < 0 || pos.Length < 0 || pos.Start+pos.Length > length {
+			errs[strconv.Itoa(i)] = errors.New("must be within the text of the block")
+		}
+	}
+	return validation.Errors{"styles": errs.Filter()}.Filter()
+}
+
+// blockText returns the text the styles of the block apply to.
+func blockText(block *notespb.Block) string {
+	switch block.Type {
+	case notespb.Block_TYPE_HEADING_1, notespb.Block_TYPE_HEADING_2, notespb.Block_TYPE_HEADING_3:
+		return block.GetHeading()
+	case notespb.Block_TYPE_PARAGRAPH:
+		return block.GetParagraph()
+	case notespb.Block_TYPE_BULLET_POINT:
+		return block.GetBulletPoint()
+	case notespb.Block_TYPE_NUMBER_POINT:
+		return block.GetNumberPoint()
+	case notespb.Block_TYPE_CHECKLIST_ITEM:
+		return block.GetChecklistItem().GetText()
+	case notespb.Block_TYPE_CALLOUT:
+		return block.GetCallout().GetText()
+	}
+	return ""
+}
+
+// validateBlocks checks the content of each block of a note.
+func validateBlocks(value interface{}) error {
+	blocks, _ := value.([]*notespb.Block)
+	errs := validation.Errors{}
+	for i, block := range blocks {
+		if err := ValidateBlock(block); err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+	}
+	return errs.Filter()
+}
+
+// validateStyles checks that the links of the text point to a note, other
+// styles don't hold a link.
+func validateStyles(styles []*notespb.Block_TextStyle) error {
+	errs := validation.Errors{}
+	for i, style := range styles {
+		var err error
+		if style.GetStyle() == notespb.Block_TextStyle_STYLE_LINK {
+			err = validation.Errors{
+				"link": validation.Validate(style.GetLink(), validation.Required, validation.By(validateLink)),
+			}.Filter()
+		} else if style.GetLink() != nil {
+			err = validation.Errors{"link": errors.New("must be blank")}
+		}
+		if err != nil {
+			errs[strconv.Itoa(i)] = err
+		}
+	}
+	return errs.Filter()
+}
+
+func validateLink(value interface{}) error {
+	link := value.(*notespb.Block_TextStyle_Link)
+	return validation.ValidateStruct(link,
+		validation.Field(&link.NoteId, validation.Required),
+	)
+}
+
+func validateTable(value interface{}) error {
+	table := value.(*notespb.Block_Table)
+	err := validation.ValidateStruct(table,
+		validation.Field(&table.Rows, validation.Required, validation.Length(1, maxTableRows)),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Every row must have as many cells for the table to be a grid.
+	columns := len(table.Rows[0].GetCells())
+	errs := validation.Errors{}
+	for i, row := range table.Rows {
+		err := validation.Validate(row.GetCells(),
+			validation.Required,
+			validation.Length(columns, columns),
+			validation.Length(1, maxTableColumns),
+		)
+		if err != nil {
+			errs[fmt.Sprintf("rows[%d]", i)] = err
+			continue
+		}
+		for j, cell := range row.Cells {
+			err := validation.Validate(cell, validation.Required, validation.By(validateTableCell))
+			if err != nil {
+				errs[fmt.Sprintf("rows[%d].cells[%d]", i, j)] = err
+			}
+		}
+	}
+
+	return errs.Filter()
+}
+
+func validateTableCell(value interface{}) error {
+	cell := value.(*notespb.Block_Table_Cell)
+	return validation.ValidateStruct(cell,
+		validation.Field(&cell.Type, validation.Required, validation.In(
+			notespb.Block_Table_Cell_TYPE_TEXT,
+			notespb.Block_Table_Cell_TYPE_NUMBER,
+			notespb.Block_Table_Cell_TYPE_BOOLEAN,
+			notespb.Block_Table_Cell_TYPE_DATE,
+		)),
+		// An empty cell is valid whatever its type.
+		validation.Field(&cell.Value,
+			validation.When(cell.Type == notespb.Block_Table_Cell_TYPE_NUMBER, validation.By(isNumber)),
+			validation.When(cell.Type == notespb.Block_Table_Cell_TYPE_BOOLEAN, validation.In("true", "false")),
+			validation.When(cell.Type == notespb.Block_Table_Cell_TYPE_DATE, validation.Date("2006-01-02")),
+		),
+	)
+}
+
+func validateCallout(value interface{}) error {
+	callout := value.(*notespb.Block_Callout)
+	return validation.ValidateStruct(callout,
+		validation.Field(&callout.Tone, validation.Required, validation.In(
+			notespb.Block_Callout_TONE_INFO,
+			notespb.Block_Callout_TONE_WARNING,
+			notespb.Block_Callout_TONE_TIP,
+		)),
+	)
+}
+
+func validateEmbed(value interface{}) error {
+	embed := value.(*notespb.Block_Embed)
+	return validation.ValidateStruct(embed,
+		validation.Field(&embed.Url, validation.Required, validation.By(isWebURL)),
+		validation.Field(&embed.Title, validation.Length(0, 256)),
+	)
+}
+
+func isNumber(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.New("must be a number")
+	}
+	return nil
+}
+
+func isWebURL(value interface{}) error {
+	s, _ := value.(string)
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an http or https url")
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package validators
+
+import (
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func ValidateListBacklinksRequest(req *notesv1.ListBacklinksRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.NoteId, validation.Required),
+	)
+}
@@ -1,6 +1,7 @@
 package validators
 
 import (
+	"notes-service/language"
 	notespb "notes-service/protorepo/noted/notes/v1"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -10,9 +11,58 @@ func ValidateCreateNoteRequest(req *notespb.CreateNoteRequest) error {
 	return validation.ValidateStruct(req,
 		validation.Field(&req.GroupId, validation.Required),
 		validation.Field(&req.Title, validation.Required, validation.Length(1, 64)),
+		// An empty lang lets the service detect the note's language.
+		validation.Field(&req.Lang, validation.In(supportedLangs()...)),
+		validation.Field(&req.Blocks, validation.By(validateBlocks)),
 	)
 }
 
+func ValidateTranslateNoteRequest(req *notespb.TranslateNoteRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.NoteId, validation.Required),
+		validation.Field(&req.Lang, validation.Required, validation.In(supportedLangs()...)),
+	)
+}
+
+// Maximum size of a file imported as a note.
+const maxImportedFileSize = 5 << 20
+
+func ValidateImportNoteRequest(req *notespb.ImportNoteRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		// A note imported from JSON keeps its title unless another is given.
+		validation.Field(&req.Title, validation.When(req.ImportFormat != notespb.NoteImportFormat_NOTE_IMPORT_FORMAT_JSON, validation.Required), validation.Length(1, 64)),
+		// An empty lang lets the service detect the note's language.
+		validation.Field(&req.Lang, validation.In(supportedLangs()...)),
+		validation.Field(&req.ImportFormat, validation.Required),
+		validation.Field(&req.File, validation.Required, validation.Length(1, maxImportedFileSize)),
+	)
+}
+
+func ValidateAskNotesRequest(req *notespb.AskNotesRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.FolderId, validation.When(req.NoteId != "", validation.Empty.Error("cannot be set with note_id"))),
+		validation.Field(&req.Question, validation.Required, validation.Length(1, 1000)),
+	)
+}
+
+// ValidateLang checks the language of a note which does not come from a
+// request, e.g. restored from a backup. An empty lang is detected later.
+func ValidateLang(lang string) error {
+	return validation.Validate(lang, validation.In(supportedLangs()...))
+}
+
+func supportedLangs() []interface{} {
+	codes := language.SupportedLocales()
+	langs := make([]interface{}, len(codes))
+	for i, code := range codes {
+		langs[i] = code
+	}
+	return langs
+}
+
 func ValidateGetNoteRequest(req *notespb.GetNoteRequest) error {
 	return validation.ValidateStruct(req,
 		validation.Field(&req.GroupId, validation.Required),
@@ -46,6 +96,9 @@ func ValidateUpdateNoteRequest(req *notespb.UpdateNoteRequest) error {
 		} else if path == "blocks" {
 			cptValideFieldMask++
 			err = validation.Validate(&req.Note.Blocks, validation.NotNil)
+			if err == nil {
+				err = validateBlocks(req.Note.Blocks)
+			}
 		} else {
 			// if update mask is not allowed, we remove it from the list
 			req.UpdateMask.Paths = append(req.UpdateMask.Paths[:i], req.UpdateMask.Paths[i+1:]...)
@@ -61,6 +114,23 @@ func ValidateUpdateNoteRequest(req *notespb.UpdateNoteRequest) error {
 	return err
 }
 
+func ValidateDuplicateNoteRequest(req *notespb.DuplicateNoteRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.NoteId, validation.Required),
+		// An empty title keeps the title of the note.
+		validation.Field(&req.Title, validation.Length(1, 64)),
+	)
+}
+
+func ValidateMoveNoteRequest(req *notespb.MoveNoteRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.NoteId, validation.Required),
+		validation.Field(&req.DestinationGroupId, validation.Required, validation.NotIn(req.GroupId).Error("must be another group")),
+	)
+}
+
 func ValidateDeleteNoteRequest(req *notespb.DeleteNoteRequest) error {
 	return validation.ValidateStruct(req,
 		validation.Field(&req.GroupId, validation.Required),
@@ -83,6 +153,21 @@ func ValidateExportNoteRequest(req *notespb.ExportNoteRequest) error {
 	)
 }
 
+func ValidateExportGroupRequest(req *notespb.ExportGroupRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.ExportFormat, validation.Required),
+	)
+}
+
+func ValidateExportQuizRequest(req *notespb.ExportQuizRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.NoteId, validation.Required),
+		validation.Field(&req.ExportFormat, validation.Required),
+	)
+}
+
 func ValidateGenerateQuizzRequest(req *notespb.GenerateQuizRequest) error {
 	return validation.ValidateStruct(req,
 		validation.Field(&req.GroupId, validation.Required),
@@ -138,3 +223,10 @@ func ValidateListQuizs(req *notespb.ListQuizsRequest) error {
 		validation.Field(&req.NoteId, validation.Required),
 	)
 }
+
+func ValidateGetSummaryRequest(req *notespb.GetSummaryRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.NoteId, validation.Required),
+	)
+}
@@ -0,0 +1,27 @@
+package validators
+
+import (
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func ValidateGetLeaderboardRequest(req *notesv1.GetLeaderboardRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.Window, validation.Required),
+	)
+}
+
+func ValidateListScoreEventsRequest(req *notesv1.ListScoreEventsRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.AccountId, validation.Required),
+	)
+}
+
+func ValidateResetGroupScoresRequest(req *notesv1.ResetGroupScoresRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+	)
+}
@@ -0,0 +1,43 @@
+package validators
+
+import (
+	notesv1 "notes-service/protorepo/noted/notes/v1"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func ValidateCreateTemplateRequest(req *notesv1.CreateTemplateRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.NoteId, validation.Required),
+		validation.Field(&req.Name, validation.Required, validation.Length(1, 64)),
+		validation.Field(&req.Description, validation.Length(0, 256)),
+		// Global templates are shipped with the service.
+		validation.Field(&req.Scope, validation.Required, validation.In(
+			notesv1.TemplateScope_TEMPLATE_SCOPE_GROUP,
+			notesv1.TemplateScope_TEMPLATE_SCOPE_ACCOUNT,
+		)),
+	)
+}
+
+func ValidateListTemplatesRequest(req *notesv1.ListTemplatesRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+	)
+}
+
+func ValidateDeleteTemplateRequest(req *notesv1.DeleteTemplateRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.TemplateId, validation.Required),
+	)
+}
+
+func ValidateCreateNoteFromTemplateRequest(req *notesv1.CreateNoteFromTemplateRequest) error {
+	return validation.ValidateStruct(req,
+		validation.Field(&req.GroupId, validation.Required),
+		validation.Field(&req.TemplateId, validation.Required),
+		validation.Field(&req.Title, validation.Required, validation.Length(1, 64)),
+		// An empty lang keeps the language of the template.
+		validation.Field(&req.Lang, validation.In(supportedLangs()...)),
+	)
+}